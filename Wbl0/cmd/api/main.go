@@ -4,17 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"syscall"
+	"slices"
+	"strconv"
 	"time"
 
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
 	"WbServis/Wbl0/internal/application/services"
+	"WbServis/Wbl0/internal/infrastructure/auth"
+	"WbServis/Wbl0/internal/infrastructure/cache"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
 	"WbServis/Wbl0/internal/infrastructure/consumers"
+	"WbServis/Wbl0/internal/infrastructure/encryption"
 	"WbServis/Wbl0/internal/infrastructure/repositories"
 	"WbServis/Wbl0/internal/presentation/controllers"
+	"WbServis/Wbl0/pkg/kafkaconfig"
+	"WbServis/Wbl0/pkg/schemaregistry"
+	"WbServis/Wbl0/pkg/schemas"
 
 	_ "github.com/lib/pq"
 )
@@ -30,11 +41,82 @@ func main() {
 	dbName := getEnv("DB_NAME", "orders_db")
 	dbSSLMode := getEnv("DB_SSLMODE", "disable")
 
-	kafkaBrokers := getEnv("KAFKA_BROKERS", "localhost:9092")
-	kafkaTopic := getEnv("KAFKA_TOPIC", "orders")
+	messageBroker := getEnv("MESSAGE_BROKER", "kafka")
+	consumerWorkers := getEnvInt("KAFKA_WORKERS", 4)
+	consumerQueueSize := getEnvInt("KAFKA_QUEUE_SIZE", 100)
+	consumerBatchSize := getEnvInt("KAFKA_BATCH_SIZE", 100)
+	consumerBatchLinger := getEnvDuration("KAFKA_BATCH_LINGER", 50*time.Millisecond)
+	consumerMaxAttempts := getEnvInt("CONSUMER_MAX_ATTEMPTS", 3)
+	consumerRetryBackoff := getEnvDuration("CONSUMER_RETRY_BACKOFF", 500*time.Millisecond)
+	consumerMaxRetryBackoff := getEnvDuration("CONSUMER_RETRY_MAX_BACKOFF", 30*time.Second)
+	consumerDeadLetterTopic := getEnv("CONSUMER_DEAD_LETTER_TOPIC", "")
+
+	kafkaBrokers := kafkaconfig.SplitList(getEnv("KAFKA_BROKERS", "localhost:9092"))
+	kafkaTopics := kafkaconfig.SplitList(getEnv("KAFKA_TOPIC", "orders"))
+	kafkaTopicPattern := getEnv("KAFKA_TOPIC_PATTERN", "")
+	kafkaTopicRefresh := getEnvDuration("KAFKA_TOPIC_REFRESH_INTERVAL", time.Minute)
+	kafkaTopicHandlers := getEnv("KAFKA_TOPIC_HANDLERS", "")
+	kafkaRebalanceStrategies := kafkaconfig.SplitList(getEnv("KAFKA_REBALANCE_STRATEGY", "roundrobin"))
 	kafkaGroupID := getEnv("KAFKA_GROUP_ID", "order-service-group")
+	kafkaInitialOffset := getEnv("KAFKA_INITIAL_OFFSET", "oldest")
+	kafkaCommitInterval := getEnvDuration("KAFKA_COMMIT_INTERVAL", time.Second)
+	kafkaLagInterval := getEnvDuration("KAFKA_LAG_INTERVAL", 30*time.Second)
+	kafkaLagWarnThreshold := getEnvInt("KAFKA_LAG_WARN_THRESHOLD", 1000)
+	healthMaxLag := getEnvInt("HEALTH_MAX_LAG", 0)
+	latencyWindow := getEnvInt("LATENCY_WINDOW", 10000)
+
+	natsURL := getEnv("NATS_URL", "nats://localhost:4222")
+	natsStream := getEnv("NATS_STREAM", "ORDERS")
+	natsSubjects := kafkaconfig.SplitList(getEnv("NATS_SUBJECT", "orders"))
+	natsDurable := getEnv("NATS_DURABLE", "order-service")
+	natsAckWait := getEnvDuration("NATS_ACK_WAIT", 30*time.Second)
+
+	schemaStrict := getEnvBool("SCHEMA_STRICT", false)
+	schemaRegistryURL := getEnv("SCHEMA_REGISTRY_URL", "")
+	messageFormat := getEnv("MESSAGE_FORMAT", codecs.FormatJSON)
+	topicFormats := getEnv("KAFKA_TOPIC_FORMATS", "")
 
 	httpPort := getEnv("HTTP_PORT", "8081")
+	piiMaskRules := getEnv("PII_MASK_RULES", "")
+	piiMasterKeyFile := getEnv("PII_MASTER_KEY_FILE", "")
+	corsAllowedOrigins := kafkaconfig.SplitList(getEnv("CORS_ALLOWED_ORIGINS", ""))
+	authDisabled := getEnvBool("AUTH_DISABLED", false)
+	authConfig := auth.Config{
+		APIKeysFile:   getEnv("AUTH_API_KEYS_FILE", ""),
+		JWTHMACSecret: getEnv("AUTH_JWT_HMAC_SECRET", ""),
+		JWKSFile:      getEnv("AUTH_JWKS_FILE", ""),
+		JWTIssuer:     getEnv("AUTH_JWT_ISSUER", ""),
+		JWTAudience:   getEnv("AUTH_JWT_AUDIENCE", ""),
+		JWTLeeway:     getEnvDuration("AUTH_JWT_LEEWAY", 30*time.Second),
+	}
+	instanceID := getEnv("INSTANCE_ID", defaultInstanceID())
+	cacheInvalidation := getEnvBool("CACHE_INVALIDATION", true)
+	cacheSize := getEnvInt("CACHE_SIZE", 100000)
+	cacheSerialization := getEnv("CACHE_SERIALIZATION", cache.SerializationJSON)
+	cacheSnapshotPath := getEnv("CACHE_SNAPSHOT_PATH", "")
+	cacheSnapshotInterval := getEnvDuration("CACHE_SNAPSHOT_INTERVAL", 5*time.Minute)
+	cacheSnapshotMaxAge := getEnvDuration("CACHE_SNAPSHOT_MAX_AGE", 24*time.Hour)
+	analyticsCacheTTL := getEnvDuration("ANALYTICS_CACHE_TTL", time.Minute)
+	rollupInterval := getEnvDuration("ROLLUP_INTERVAL", time.Minute)
+	redisAddr := getEnv("REDIS_ADDR", "")
+	redisPassword := getEnv("REDIS_PASSWORD", "")
+	redisDB := getEnvInt("REDIS_DB", 0)
+	redisTTL := getEnvDuration("REDIS_TTL", 24*time.Hour)
+	redisTimeout := getEnvDuration("REDIS_TIMEOUT", 200*time.Millisecond)
+
+	shutdownDrainTimeout := getEnvDuration("SHUTDOWN_DRAIN_TIMEOUT", 20*time.Second)
+	shutdownCommitTimeout := getEnvDuration("SHUTDOWN_COMMIT_TIMEOUT", 5*time.Second)
+	shutdownHTTPTimeout := getEnvDuration("SHUTDOWN_HTTP_TIMEOUT", 10*time.Second)
+	shutdownSnapshotTimeout := getEnvDuration("SHUTDOWN_SNAPSHOT_TIMEOUT", 10*time.Second)
+	shutdownDBTimeout := getEnvDuration("SHUTDOWN_DB_TIMEOUT", 5*time.Second)
+
+	// Телефоны и email маскируются во всех строках лога, включая ошибки
+	// валидации и базы, которые могут содержать значения полей
+	piiMasker, err := services.NewPIIMasker(piiMaskRules)
+	if err != nil {
+		log.Fatalf("Invalid PII_MASK_RULES: %v", err)
+	}
+	log.SetOutput(services.NewMaskingWriter(os.Stderr, piiMasker))
 
 	dbURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode)
@@ -50,36 +132,273 @@ func main() {
 	}
 	log.Println("Successfully connected to database")
 
-	orderRepository := repositories.NewOrderRepository(db)
-	orderService := services.NewOrderService(orderRepository)
+	// Без мастер-ключа данные получателя хранятся открытым текстом
+	var piiCipher interfaces.PIICipher
+	if piiMasterKeyFile != "" {
+		masterKey, err := encryption.LoadMasterKey(piiMasterKeyFile)
+		if err != nil {
+			log.Fatalf("Invalid PII_MASTER_KEY_FILE: %v", err)
+		}
+		piiCipher, err = encryption.NewKeyring(repositories.NewDataKeyRepository(db), masterKey)
+		if err != nil {
+			log.Fatalf("Failed to load data keys: %v", err)
+		}
+		// Строки, сохраненные до включения шифрования, должны быть зашифрованы
+		// командой encrypt migrate до запуска сервиса с ключом
+		plaintext, err := repositories.NewDeliveryEncryptor(db, piiCipher).CountPlaintext()
+		if err != nil {
+			log.Fatalf("Failed to check plaintext deliveries: %v", err)
+		}
+		if plaintext > 0 {
+			log.Fatalf("%d deliveries are stored in plaintext, run encrypt migrate before starting the service", plaintext)
+		}
+		log.Printf("Encrypting delivery PII with data key %d", piiCipher.ActiveKeyID())
+	}
+
+	orderRepository := repositories.NewOrderRepository(db, instanceID, piiCipher)
+	orderValidator, err := schemas.NewOrderValidator(schemaStrict)
+	if err != nil {
+		log.Fatalf("Failed to load order schemas: %v", err)
+	}
+
+	var schemaRegistry *schemaregistry.Client
+	if schemaRegistryURL != "" {
+		schemaRegistry = schemaregistry.NewClient(schemaRegistryURL)
+	}
+
+	topicFormatMap, err := codecs.ParseTopicFormats(topicFormats)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_TOPIC_FORMATS: %v", err)
+	}
 
-	if err := orderService.RestoreCache(); err != nil {
-		log.Printf("Warning: Failed to restore cache: %v", err)
+	orderDecoder, err := codecs.NewOrderDecoder(codecs.Config{
+		DefaultFormat: messageFormat,
+		TopicFormats:  topicFormatMap,
+		Validator:     orderValidator,
+		Registry:      schemaRegistry,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create order decoder: %v", err)
 	}
 
-	kafkaConsumer, err := consumers.NewKafkaConsumer(
-		[]string{kafkaBrokers},
-		kafkaGroupID,
-		[]string{kafkaTopic},
-		orderService,
+	orderCache := cache.NewLRUCache(cacheSize)
+	if redisAddr != "" {
+		serializer, err := cache.NewSerializer(cacheSerialization)
+		if err != nil {
+			log.Fatalf("Invalid CACHE_SERIALIZATION: %v", err)
+		}
+		if piiCipher != nil {
+			serializer = cache.NewSealedSerializer(serializer, piiCipher)
+		}
+		orderCache = cache.NewTieredCache(orderCache, cache.NewRedisCache(cache.RedisConfig{
+			Addr:       redisAddr,
+			Password:   redisPassword,
+			DB:         redisDB,
+			TTL:        redisTTL,
+			Timeout:    redisTimeout,
+			Serializer: serializer,
+		}))
+		log.Printf("Using redis cache tier at %s", redisAddr)
+	}
+
+	persistLatency := services.NewLatencyRecorder(latencyWindow)
+	orderService := services.NewOrderService(orderRepository, orderDecoder, orderCache, persistLatency)
+
+	// Подписываемся до восстановления кэша, чтобы не пропустить изменения,
+	// сделанные другими экземплярами во время загрузки
+	var orderChangeListener interfaces.OrderChangeListener
+	if cacheInvalidation {
+		orderChangeListener, err = repositories.NewOrderChangeListener(dbURL, instanceID)
+		if err != nil {
+			log.Fatalf("Failed to listen for order changes: %v", err)
+		}
+		orderChangeListener.Listen(orderService)
+		log.Printf("Listening for order changes as instance %s", instanceID)
+	}
+
+	var cacheSnapshotter interfaces.CacheSnapshotter
+	if cacheSnapshotPath != "" {
+		cacheSnapshotter = services.NewCacheSnapshotter(orderRepository, orderCache,
+			cache.NewFileSnapshotStore(cacheSnapshotPath, piiCipher), cacheSnapshotMaxAge)
+	}
+
+	cacheRestored := false
+	if cacheSnapshotter != nil {
+		if err := cacheSnapshotter.Restore(); err != nil {
+			log.Printf("Warning: Cache snapshot ignored: %v", err)
+		} else {
+			cacheRestored = true
+		}
+	}
+	if !cacheRestored {
+		if err := orderService.RestoreCache(); err != nil {
+			log.Printf("Warning: Failed to restore cache: %v", err)
+		}
+	}
+
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	defer stopSnapshots()
+	if cacheSnapshotter != nil {
+		go cacheSnapshotter.Run(snapshotCtx, cacheSnapshotInterval)
+	}
+
+	// Дневные сводки для аналитики пересчитываются по дням, отмеченным триггерами БД
+	rollupCtx, stopRollups := context.WithCancel(context.Background())
+	defer stopRollups()
+	if rollupInterval > 0 {
+		rollupService := services.NewRollupService(repositories.NewRollupRepository(db))
+		go rollupService.Run(rollupCtx, rollupInterval)
+		log.Printf("Rollups are refreshed every %s", rollupInterval)
+	} else {
+		log.Printf("Warning: Rollup refresh is disabled, rollup_dirty_days grows until 'rollup refresh' is run")
+	}
+
+	pool := consumers.WorkerPoolConfig{
+		Workers:         consumerWorkers,
+		QueueSize:       consumerQueueSize,
+		BatchSize:       consumerBatchSize,
+		BatchLinger:     consumerBatchLinger,
+		MaxAttempts:     consumerMaxAttempts,
+		RetryBackoff:    consumerRetryBackoff,
+		MaxRetryBackoff: consumerMaxRetryBackoff,
+		DeadLetterTopic: consumerDeadLetterTopic,
+		ShardKey:        services.NewOrderShardKey(orderDecoder),
+	}
+	messageHandlers := map[string]interfaces.MessageHandler{
+		"order": services.NewOrderMessageHandler(orderService),
+		"skip":  services.NewSkipMessageHandler(),
+	}
+	topicHandlerNames, err := services.ParseTopicHandlers(kafkaTopicHandlers)
+	if err != nil {
+		log.Fatalf("Invalid KAFKA_TOPIC_HANDLERS: %v", err)
+	}
+	topicRoutes := make(map[string]interfaces.MessageHandler, len(topicHandlerNames))
+	for topic, name := range topicHandlerNames {
+		handler, ok := messageHandlers[name]
+		if !ok {
+			log.Fatalf("Unknown handler %q for topic %s, expected order or skip", name, topic)
+		}
+		topicRoutes[topic] = handler
+	}
+	messageHandler := services.NewMessageRouter(topicRoutes, messageHandlers["order"])
+
+	var (
+		messageConsumer interfaces.MessageConsumer
+		memoryBroker    *consumers.MemoryBroker
+		consumerTopics  []string
 	)
+	// Без топика недоставленных сообщений неудачная пачка повторяется до остановки
+	var deadLetterPublisher interfaces.MessagePublisher
+	if consumerDeadLetterTopic != "" {
+		switch messageBroker {
+		case "kafka":
+			deadLetterPublisher, err = consumers.NewKafkaPublisher(kafkaBrokers, kafkaconfig.FromEnv())
+		case "nats":
+			deadLetterPublisher, err = consumers.NewNATSPublisher(natsURL, natsStream+"_DEAD_LETTER", consumerDeadLetterTopic)
+		case "memory":
+			memoryBroker = consumers.NewMemoryBroker(consumerQueueSize)
+			deadLetterPublisher = memoryBroker
+		}
+		if err != nil {
+			log.Fatalf("Failed to create dead letter publisher: %v", err)
+		}
+		pool.DeadLetter = deadLetterPublisher
+		log.Printf("Batches failing %d attempts are sent to %s", consumerMaxAttempts, consumerDeadLetterTopic)
+	}
+
+	switch messageBroker {
+	case "kafka":
+		consumerTopics = kafkaTopics
+		messageConsumer, err = consumers.NewKafkaConsumer(consumers.KafkaConfig{
+			Brokers:              kafkaBrokers,
+			GroupID:              kafkaGroupID,
+			Topics:               kafkaTopics,
+			TopicPattern:         kafkaTopicPattern,
+			TopicRefreshInterval: kafkaTopicRefresh,
+			RebalanceStrategies:  kafkaRebalanceStrategies,
+			InitialOffset:        kafkaInitialOffset,
+			CommitInterval:       kafkaCommitInterval,
+			LagInterval:          kafkaLagInterval,
+			LagWarnThreshold:     int64(kafkaLagWarnThreshold),
+			Client:               kafkaconfig.FromEnv(),
+			Pool:                 pool,
+		}, messageHandler)
+	case "nats":
+		consumerTopics = natsSubjects
+		messageConsumer, err = consumers.NewNATSConsumer(consumers.NATSConfig{
+			URL:      natsURL,
+			Stream:   natsStream,
+			Subjects: natsSubjects,
+			Durable:  natsDurable,
+			AckWait:  natsAckWait,
+			Pool:     pool,
+		}, messageHandler)
+	case "memory":
+		if len(kafkaTopics) == 0 {
+			log.Fatal("KAFKA_TOPIC is required for the memory broker")
+		}
+		consumerTopics = kafkaTopics
+		if memoryBroker == nil {
+			memoryBroker = consumers.NewMemoryBroker(consumerQueueSize)
+		}
+		messageConsumer = consumers.NewMemoryConsumer(memoryBroker, kafkaTopics, messageHandler, pool)
+	default:
+		log.Fatalf("Unknown MESSAGE_BROKER %q, expected kafka, nats or memory", messageBroker)
+	}
 	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %v", err)
+		log.Fatalf("Failed to create %s consumer: %v", messageBroker, err)
 	}
-	defer kafkaConsumer.Close()
 
-	if err := kafkaConsumer.Start(); err != nil {
-		log.Fatalf("Failed to start Kafka consumer: %v", err)
+	if err := messageConsumer.Start(); err != nil {
+		log.Fatalf("Failed to start %s consumer: %v", messageBroker, err)
 	}
-	log.Printf("Kafka consumer started for topic: %s", kafkaTopic)
+	log.Printf("Consumer started: broker=%s, topics=%v", messageBroker, consumerTopics)
+
+	orderController := controllers.NewOrderController(orderService, piiMasker)
+	analyticsRepository := repositories.NewAnalyticsRepository(db, rollupInterval > 0)
+	analyticsService := services.NewAnalyticsService(analyticsRepository, analyticsCacheTTL)
+	analyticsController := controllers.NewAnalyticsController(analyticsService)
+	exportController := controllers.NewExportController(services.NewOrderExporter(orderRepository), piiMasker)
+	schemaController := controllers.NewSchemaController(orderValidator)
 
-	orderController := controllers.NewOrderController(orderService)
+	lagReporter, _ := messageConsumer.(interfaces.LagReporter)
+	healthController := controllers.NewHealthController(lagReporter, persistLatency, int64(healthMaxLag))
+
+	// Аутентификация включена по умолчанию: без учетных данных сервис не запускается,
+	// открытое API требует явного AUTH_DISABLED=true и не разрешает любые источники
+	var authenticator interfaces.Authenticator
+	if !authDisabled {
+		authenticator, err = auth.NewAuthenticator(authConfig)
+		if err != nil {
+			log.Fatalf("Failed to configure authentication (set AUTH_DISABLED=true to run without it): %v", err)
+		}
+		log.Println("API authentication enabled")
+	} else {
+		if slices.Contains(corsAllowedOrigins, "*") {
+			log.Fatal("CORS_ALLOWED_ORIGINS=* is not allowed with AUTH_DISABLED=true, list the frontend origins")
+		}
+		log.Println("Warning: API authentication disabled by AUTH_DISABLED, all endpoints are open")
+	}
+	authMiddleware := controllers.NewAuthMiddleware(authenticator)
+	read := func(handler http.HandlerFunc) http.HandlerFunc {
+		return authMiddleware.Require(dto.ScopeOrdersRead, handler)
+	}
+	admin := func(handler http.HandlerFunc) http.HandlerFunc {
+		return authMiddleware.Require(dto.ScopeAdmin, handler)
+	}
 
 	corsMiddleware := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
+			// Разрешенный источник отражается в ответе: с учетными данными
+			// браузеры не принимают "*" вместе со списком источников
+			if slices.Contains(corsAllowedOrigins, "*") {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else if origin := r.Header.Get("Origin"); slices.Contains(corsAllowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Add("Vary", "Origin")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+controllers.APIKeyHeader)
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -91,8 +410,38 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/order/", orderController.GetOrderByID)
-	mux.HandleFunc("/health", orderController.HealthCheck)
+	mux.HandleFunc("/order/", read(orderController.GetOrderByID))
+	mux.HandleFunc("/orders/search", read(orderController.SearchOrders))
+	mux.HandleFunc("/orders/by-track/", read(orderController.FindOrders(dto.LookupTrackNumber, "/orders/by-track/")))
+	mux.HandleFunc("/orders/by-transaction/", read(orderController.FindOrders(dto.LookupTransaction, "/orders/by-transaction/")))
+	mux.HandleFunc("/orders/by-rid/", read(orderController.FindOrders(dto.LookupRid, "/orders/by-rid/")))
+	mux.HandleFunc("/orders/by-phone/", read(orderController.FindOrders(dto.LookupPhone, "/orders/by-phone/")))
+	mux.HandleFunc("/orders/by-email/", read(orderController.FindOrders(dto.LookupEmail, "/orders/by-email/")))
+	mux.HandleFunc("/customers/", read(orderController.GetCustomerOrders))
+	mux.HandleFunc("/analytics/revenue", read(analyticsController.Revenue))
+	mux.HandleFunc("/analytics/top/brands", read(analyticsController.TopBrands))
+	mux.HandleFunc("/analytics/top/products", read(analyticsController.TopProducts))
+	mux.HandleFunc("/analytics/basket", read(analyticsController.Basket))
+	mux.HandleFunc("/analytics/breakdown/", read(analyticsController.Breakdown))
+	mux.HandleFunc("/analytics/discount", read(analyticsController.Discount))
+	mux.HandleFunc("/export/orders", read(exportController.ExportOrders))
+	// Проверки здоровья и схема открыты: их опрашивают оркестратор и отправители
+	mux.HandleFunc("/health", healthController.HealthCheck)
+	mux.HandleFunc("/ready", healthController.ReadinessCheck)
+	mux.HandleFunc("/schema/order", schemaController.GetOrderSchema)
+
+	if consumerAdmin, ok := messageConsumer.(interfaces.ConsumerAdmin); ok {
+		adminController := controllers.NewAdminController(consumerAdmin)
+		mux.HandleFunc("/admin/consumer", admin(adminController.ConsumerStatus))
+		mux.HandleFunc("/admin/consumer/reset", admin(adminController.ResetOffsets))
+		mux.HandleFunc("/admin/consumer/pause", admin(adminController.PauseConsumer))
+		mux.HandleFunc("/admin/consumer/resume", admin(adminController.ResumeConsumer))
+	}
+
+	if memoryBroker != nil {
+		brokerController := controllers.NewBrokerController(memoryBroker, kafkaTopics[0])
+		mux.HandleFunc("/broker/publish", authMiddleware.Require(dto.ScopeOrdersWrite, brokerController.Publish))
+	}
 
 	handler := corsMiddleware(mux)
 
@@ -111,33 +460,104 @@ func main() {
 		}
 	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
 
-	log.Println("Shutting down server...")
+	shutdownOnSignal(ctx, stop, shutdownPhases(shutdownSteps{
+		drain: messageConsumer.Stop,
+		commit: func() error {
+			err := messageConsumer.Close()
+			if closer, ok := deadLetterPublisher.(io.Closer); ok {
+				if closeErr := closer.Close(); closeErr != nil {
+					log.Printf("Error closing dead letter publisher: %v", closeErr)
+				}
+			}
+			return err
+		},
+		stopHTTP: server.Shutdown,
+		snapshot: func() error {
+			stopSnapshots()
+			if cacheSnapshotter == nil {
+				return nil
+			}
+			return cacheSnapshotter.Save()
+		},
+		close: func() error {
+			stopRollups()
+			if orderChangeListener != nil {
+				if err := orderChangeListener.Close(); err != nil {
+					log.Printf("Error closing order change listener: %v", err)
+				}
+			}
+			if closer, ok := orderCache.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					log.Printf("Error closing cache: %v", err)
+				}
+			}
+			return orderService.Close()
+		},
+	}, shutdownTimeouts{
+		drain:    shutdownDrainTimeout,
+		commit:   shutdownCommitTimeout,
+		http:     shutdownHTTPTimeout,
+		snapshot: shutdownSnapshotTimeout,
+		close:    shutdownDBTimeout,
+	}))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	log.Println("Server stopped")
+}
 
-	if err := kafkaConsumer.Stop(); err != nil {
-		log.Printf("Error stopping Kafka consumer: %v", err)
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return defaultValue
+}
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Error shutting down HTTP server: %v", err)
+// defaultInstanceID возвращает идентификатор экземпляра из имени хоста и PID
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "order-service"
 	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
 
-	if err := orderService.Close(); err != nil {
-		log.Printf("Error closing order service: %v", err)
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
 	}
+	return parsed
+}
 
-	log.Println("Server stopped")
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
 	}
-	return defaultValue
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid value %q for %s, using default %t", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+	"time"
+)
+
+// shutdownSignals - сигналы, по которым сервис останавливается
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// shutdownPhase - шаг остановки сервиса со своим таймаутом
+type shutdownPhase struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// shutdownSteps - действия остановки компонентов сервиса
+type shutdownSteps struct {
+	// drain прекращает чтение сообщений и ждет обработки прочитанных
+	drain func(ctx context.Context) error
+	// commit коммитит офсеты и закрывает потребителя
+	commit func() error
+	// stopHTTP дожидается завершения HTTP-запросов
+	stopHTTP func(ctx context.Context) error
+	// snapshot сохраняет снимок кэша
+	snapshot func() error
+	// close закрывает кэш и базу
+	close func() error
+}
+
+// shutdownTimeouts - таймауты шагов остановки
+type shutdownTimeouts struct {
+	drain    time.Duration
+	commit   time.Duration
+	http     time.Duration
+	snapshot time.Duration
+	close    time.Duration
+}
+
+// shutdownPhases возвращает шаги остановки сервиса. Порядок важен: сообщения
+// дообрабатываются, пока база еще открыта, офсеты коммитятся только после их
+// обработки, а снимок сохраняется, когда кэш больше не меняется.
+func shutdownPhases(steps shutdownSteps, timeouts shutdownTimeouts) []shutdownPhase {
+	return []shutdownPhase{
+		{
+			name:    "stop fetching and drain in-flight messages",
+			timeout: timeouts.drain,
+			run:     steps.drain,
+		},
+		{
+			name:    "commit offsets and close consumer",
+			timeout: timeouts.commit,
+			run:     func(ctx context.Context) error { return steps.commit() },
+		},
+		{
+			name:    "drain HTTP connections",
+			timeout: timeouts.http,
+			run:     steps.stopHTTP,
+		},
+		{
+			name:    "save cache snapshot",
+			timeout: timeouts.snapshot,
+			run:     func(ctx context.Context) error { return steps.snapshot() },
+		},
+		{
+			name:    "close cache and database",
+			timeout: timeouts.close,
+			run:     func(ctx context.Context) error { return steps.close() },
+		},
+	}
+}
+
+// shutdownOnSignal ждет отмены ctx, созданного signal.NotifyContext, и выполняет
+// шаги остановки. stop сразу возвращает сигналам обработку по умолчанию:
+// повторный сигнал завершает зависшую остановку немедленно.
+func shutdownOnSignal(ctx context.Context, stop context.CancelFunc, phases []shutdownPhase) {
+	<-ctx.Done()
+	stop()
+
+	log.Println("Shutting down server...")
+	shutdown(phases)
+}
+
+// shutdown выполняет шаги строго по порядку. Шаг, не уложившийся в таймаут,
+// журналируется, и остановка переходит к следующему шагу, чтобы зависший
+// компонент не помешал освободить остальные ресурсы.
+func shutdown(phases []shutdownPhase) {
+	started := time.Now()
+
+	for i, phase := range phases {
+		log.Printf("Shutdown [%d/%d] %s (timeout %s)", i+1, len(phases), phase.name, phase.timeout)
+		phaseStarted := time.Now()
+
+		if err := runPhase(phase); err != nil {
+			log.Printf("Shutdown [%d/%d] %s failed after %s: %v",
+				i+1, len(phases), phase.name, time.Since(phaseStarted).Round(time.Millisecond), err)
+			continue
+		}
+		log.Printf("Shutdown [%d/%d] %s done in %s",
+			i+1, len(phases), phase.name, time.Since(phaseStarted).Round(time.Millisecond))
+	}
+
+	log.Printf("Shutdown completed in %s", time.Since(started).Round(time.Millisecond))
+}
+
+func runPhase(phase shutdownPhase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), phase.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- phase.run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/infrastructure/consumers"
+)
+
+// events записывает события остановки по порядку
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	e.list = append(e.list, event)
+	e.mu.Unlock()
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.list)
+}
+
+// blockingHandler обрабатывает пачку только после закрытия release
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+	events  *events
+	once    sync.Once
+}
+
+func (h *blockingHandler) HandleMessages(messages []*dto.Message) error {
+	h.once.Do(func() { close(h.started) })
+	<-h.release
+	h.events.add("batch")
+	return nil
+}
+
+// startBlockedConsumer запускает потребителя, чей обработчик занят пачкой
+func startBlockedConsumer(t *testing.T, recorded *events) (interfaces.MessageConsumer, *blockingHandler) {
+	t.Helper()
+
+	handler := &blockingHandler{started: make(chan struct{}), release: make(chan struct{}), events: recorded}
+	broker := consumers.NewMemoryBroker(10)
+	consumer := consumers.NewMemoryConsumer(broker, []string{"orders"}, handler, consumers.WorkerPoolConfig{
+		Workers:     1,
+		BatchSize:   1,
+		BatchLinger: time.Millisecond,
+	})
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Publish(&dto.Message{Topic: "orders", Key: []byte("a"), Value: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-handler.started:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not start")
+	}
+	return consumer, handler
+}
+
+func testShutdownSteps(consumer interfaces.MessageConsumer, recorded *events) shutdownSteps {
+	return shutdownSteps{
+		drain: consumer.Stop,
+		commit: func() error {
+			recorded.add("commit")
+			return consumer.Close()
+		},
+		stopHTTP: func(ctx context.Context) error {
+			recorded.add("http")
+			return nil
+		},
+		snapshot: func() error {
+			recorded.add("snapshot")
+			return nil
+		},
+		close: func() error {
+			recorded.add("close")
+			return nil
+		},
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	recorded := &events{}
+	consumer, handler := startBlockedConsumer(t, recorded)
+
+	done := make(chan struct{})
+	go func() {
+		shutdown(shutdownPhases(testShutdownSteps(consumer, recorded), shutdownTimeouts{
+			drain: 5 * time.Second, commit: time.Second, http: time.Second, snapshot: time.Second, close: time.Second,
+		}))
+		close(done)
+	}()
+
+	// Пока пачка обрабатывается, остановка не идет дальше дообработки
+	time.Sleep(100 * time.Millisecond)
+	if got := recorded.get(); len(got) != 0 {
+		t.Fatalf("phases ran before the batch finished: %v", got)
+	}
+
+	close(handler.release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish")
+	}
+
+	want := []string{"batch", "commit", "http", "snapshot", "close"}
+	if got := recorded.get(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %v, want %v", got, want)
+	}
+}
+
+func TestShutdownPhaseTimeout(t *testing.T) {
+	recorded := &events{}
+	consumer, handler := startBlockedConsumer(t, recorded)
+	defer close(handler.release)
+
+	steps := testShutdownSteps(consumer, recorded)
+	// Зависший HTTP-сервер не мешает сохранить снимок и закрыть базу
+	steps.stopHTTP = func(ctx context.Context) error {
+		recorded.add("http")
+		select {}
+	}
+
+	started := time.Now()
+	shutdown(shutdownPhases(steps, shutdownTimeouts{
+		drain: 50 * time.Millisecond, commit: 50 * time.Millisecond, http: 50 * time.Millisecond,
+		snapshot: time.Second, close: time.Second,
+	}))
+	elapsed := time.Since(started)
+
+	// Пачка не завершена, поэтому ее сообщения не подтверждены и будут доставлены повторно
+	want := []string{"commit", "http", "snapshot", "close"}
+	if got := recorded.get(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %v, want %v", got, want)
+	}
+	if elapsed > time.Second {
+		t.Errorf("shutdown took %s, timeouts were not applied", elapsed)
+	}
+}
+
+func TestShutdownOnSIGTERM(t *testing.T) {
+	recorded := &events{}
+	consumer, handler := startBlockedConsumer(t, recorded)
+
+	// HTTP-запрос, начатый до сигнала, должен получить ответ
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestStarted, requestRelease := make(chan struct{}), make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		<-requestRelease
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+
+	response := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("status %s", resp.Status)
+			}
+		}
+		response <- err
+	}()
+	<-requestStarted
+
+	steps := testShutdownSteps(consumer, recorded)
+	steps.stopHTTP = func(ctx context.Context) error {
+		recorded.add("http")
+		close(requestRelease)
+		return server.Shutdown(ctx)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		shutdownOnSignal(ctx, stop, shutdownPhases(steps, shutdownTimeouts{
+			drain: 5 * time.Second, commit: time.Second, http: time.Second, snapshot: time.Second, close: time.Second,
+		}))
+		close(done)
+	}()
+
+	// До сигнала остановка не начинается
+	time.Sleep(50 * time.Millisecond)
+	if got := recorded.get(); len(got) != 0 {
+		t.Fatalf("phases ran before the signal: %v", got)
+	}
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	// После сигнала остановка ждет пачку, которая уже обрабатывается
+	time.Sleep(50 * time.Millisecond)
+	if got := recorded.get(); len(got) != 0 {
+		t.Fatalf("phases ran before the batch finished: %v", got)
+	}
+	close(handler.release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish after SIGTERM")
+	}
+
+	want := []string{"batch", "commit", "http", "snapshot", "close"}
+	if got := recorded.get(); !slices.Equal(got, want) {
+		t.Errorf("shutdown order = %v, want %v", got, want)
+	}
+	if err := <-response; err != nil {
+		t.Errorf("in-flight request failed: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+const usage = `Usage: consumerctl [-addr URL] <command> [flags]
+
+Commands:
+  status    показать закоммиченные офсеты и high-water mark партиций
+  reset     сбросить офсеты группы (-to earliest|latest|offset|timestamp)
+  pause     приостановить потребление
+  resume    возобновить потребление
+`
+
+func main() {
+	log.SetFlags(0)
+
+	addr := flag.String("addr", getEnv("ORDER_SERVICE_ADDR", "http://localhost:8081"), "адрес HTTP API сервиса")
+	apiKey := flag.String("api-key", getEnv("ORDER_SERVICE_API_KEY", ""), "API-ключ с областью admin")
+	token := flag.String("token", getEnv("ORDER_SERVICE_TOKEN", ""), "JWT с областью admin")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := &adminClient{
+		baseURL: strings.TrimSuffix(*addr, "/"),
+		apiKey:  *apiKey,
+		token:   *token,
+		// Сброс ждет перезапуска сессии группы до 30 секунд
+		http: &http.Client{Timeout: time.Minute},
+	}
+
+	var err error
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "status":
+		err = client.status()
+	case "reset":
+		err = client.reset(args)
+	case "pause":
+		err = client.post("/admin/consumer/pause", nil, nil)
+	case "resume":
+		err = client.post("/admin/consumer/resume", nil, nil)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+type adminClient struct {
+	baseURL string
+	apiKey  string
+	token   string
+	http    *http.Client
+}
+
+func (c *adminClient) status() error {
+	var status dto.ConsumerStatusResponse
+	if err := c.do(http.MethodGet, "/admin/consumer", nil, &status); err != nil {
+		return err
+	}
+
+	fmt.Printf("paused: %v\n\n", status.Paused)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tCOMMITTED\tLOG START\tHIGH-WATER\tLAG")
+	for _, p := range status.Partitions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n",
+			p.Topic, p.Partition, p.Committed, p.LogStart, p.HighWaterMark, p.Lag)
+	}
+	return tw.Flush()
+}
+
+func (c *adminClient) reset(args []string) error {
+	fs := flag.NewFlagSet("reset", flag.ExitOnError)
+	topic := fs.String("topic", "", "топик (по умолчанию все топики потребителя)")
+	partitions := fs.String("partitions", "", "список партиций через запятую (по умолчанию все)")
+	to := fs.String("to", "", "earliest, latest, offset или timestamp")
+	offset := fs.Int64("offset", 0, "офсет для -to offset")
+	timestamp := fs.String("timestamp", "", "время в формате RFC3339 для -to timestamp")
+	fs.Parse(args)
+
+	request := dto.OffsetResetRequest{
+		Topic:  *topic,
+		To:     *to,
+		Offset: *offset,
+	}
+
+	if *partitions != "" {
+		for _, value := range strings.Split(*partitions, ",") {
+			partition, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid partition %q: %w", value, err)
+			}
+			request.Partitions = append(request.Partitions, int32(partition))
+		}
+	}
+
+	if *timestamp != "" {
+		parsed, err := time.Parse(time.RFC3339, *timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %w", err)
+		}
+		request.Timestamp = parsed
+	}
+
+	var targets []dto.OffsetTarget
+	if err := c.post("/admin/consumer/reset", request, &targets); err != nil {
+		return err
+	}
+
+	var skipped int
+	for _, target := range targets {
+		if target.Applied {
+			fmt.Printf("%s/%d -> %d\n", target.Topic, target.Partition, target.Offset)
+		} else {
+			fmt.Printf("%s/%d -> %d skipped: assigned to another instance\n", target.Topic, target.Partition, target.Offset)
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d partitions were not reset, repeat the command with -addr of the instances that own them", skipped)
+	}
+	return nil
+}
+
+func (c *adminClient) post(path string, request, response interface{}) error {
+	return c.do(http.MethodPost, path, request, response)
+}
+
+func (c *adminClient) do(method, path string, request, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errResponse dto.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil || errResponse.Error == "" {
+			return fmt.Errorf("request failed with status %s", resp.Status)
+		}
+		return fmt.Errorf("%s", errResponse.Error)
+	}
+
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
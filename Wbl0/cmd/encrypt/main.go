@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/infrastructure/encryption"
+	"WbServis/Wbl0/internal/infrastructure/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: encrypt <command> [flags]
+
+Commands:
+  generate-key  вывести новый мастер-ключ для файла PII_MASTER_KEY_FILE
+  migrate       зашифровать активным ключом данные получателя, сохраненные
+                открытым текстом или другим ключом (-batch)
+  rotate        создать новый ключ данных и перешифровать им все строки (-batch)
+  rewrap        перешифровать ключи данных мастер-ключом из PII_MASTER_KEY_FILE
+                после смены мастер-ключа (-old <файл прежнего ключа>)
+
+Мастер-ключ читается из файла PII_MASTER_KEY_FILE. Подключение к БД задается
+переменными DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE.
+`
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	if command == "generate-key" {
+		key, err := encryption.GenerateMasterKey()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	masterKeyFile := getEnv("PII_MASTER_KEY_FILE", "")
+	if masterKeyFile == "" {
+		log.Fatalf("Error: PII_MASTER_KEY_FILE is not set")
+	}
+	masterKey, err := encryption.LoadMasterKey(masterKeyFile)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	dbURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", "5432"), getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "password"), getEnv("DB_NAME", "orders_db"), getEnv("DB_SSLMODE", "disable"))
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	dataKeys := repositories.NewDataKeyRepository(db)
+
+	switch command {
+	case "migrate":
+		err = migrate(args, db, dataKeys, masterKey, false)
+	case "rotate":
+		err = migrate(args, db, dataKeys, masterKey, true)
+	case "rewrap":
+		err = rewrap(args, dataKeys, masterKey)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Println("Done")
+}
+
+// migrate перешифровывает строки deliveries активным ключом данных,
+// при rotate - предварительно создав новый ключ
+func migrate(args []string, db *sql.DB, dataKeys interfaces.DataKeyRepository, masterKey *encryption.MasterKey, rotate bool) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	batchSize := fs.Int("batch", 1000, "число строк в одной транзакции")
+	fs.Parse(args)
+
+	if *batchSize < 1 {
+		return fmt.Errorf("-batch must be at least 1")
+	}
+
+	cipher, err := encryption.NewKeyring(dataKeys, masterKey)
+	if err != nil {
+		return err
+	}
+
+	if rotate {
+		keyID, err := cipher.Rotate()
+		if err != nil {
+			return err
+		}
+		log.Printf("Created data key %d", keyID)
+	}
+
+	log.Printf("Encrypting deliveries with data key %d", cipher.ActiveKeyID())
+	count, err := repositories.NewDeliveryEncryptor(db, cipher).EncryptPending(*batchSize)
+	log.Printf("Encrypted %d deliveries", count)
+	return err
+}
+
+// rewrap перешифровывает ключи данных с прежнего мастер-ключа на текущий
+func rewrap(args []string, dataKeys interfaces.DataKeyRepository, masterKey *encryption.MasterKey) error {
+	fs := flag.NewFlagSet("rewrap", flag.ExitOnError)
+	oldKeyFile := fs.String("old", "", "файл прежнего мастер-ключа")
+	fs.Parse(args)
+
+	if *oldKeyFile == "" {
+		return fmt.Errorf("-old is required")
+	}
+	oldKey, err := encryption.LoadMasterKey(*oldKeyFile)
+	if err != nil {
+		return err
+	}
+
+	count, err := encryption.RewrapKeys(dataKeys, oldKey, masterKey)
+	if err != nil {
+		return err
+	}
+	log.Printf("Rewrapped %d data keys from master key %s to %s", count, oldKey.ID(), masterKey.ID())
+	return nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
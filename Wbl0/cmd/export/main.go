@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/application/services"
+	"WbServis/Wbl0/internal/infrastructure/encryption"
+	"WbServis/Wbl0/internal/infrastructure/exporters"
+	"WbServis/Wbl0/internal/infrastructure/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: export [flags]
+
+Выгружает заказы из БД в CSV (строка на товар), NDJSON (заказ на строку) или Parquet.
+Данные получателя маскируются по правилам PII_MASK_RULES, как в HTTP API; -pii выгружает их как есть.
+Зашифрованные данные получателя расшифровываются мастер-ключом из файла PII_MASTER_KEY_FILE.
+Подключение к БД задается переменными DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE.
+
+Flags:
+`
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	format := flag.String("format", exporters.FormatCSV, "формат: "+strings.Join(exporters.Formats, ", "))
+	output := flag.String("o", "-", "файл выгрузки, - для stdout")
+	from := flag.String("from", "", "начало периода по date_created (YYYY-MM-DD или RFC 3339)")
+	to := flag.String("to", "", "конец периода, не включается (YYYY-MM-DD или RFC 3339)")
+	customerID := flag.String("customer", "", "только заказы покупателя")
+	deliveryService := flag.String("delivery-service", "", "только заказы службы доставки")
+	pii := flag.Bool("pii", false, "выгрузить данные получателя без маскирования")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	masker, err := services.NewPIIMasker(getEnv("PII_MASK_RULES", ""))
+	if err != nil {
+		log.Fatalf("Invalid PII_MASK_RULES: %v", err)
+	}
+
+	filter := dto.ExportFilter{
+		CustomerID:      *customerID,
+		DeliveryService: *deliveryService,
+	}
+	if filter.From, err = parseTime(*from); err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	dbURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", "5432"), getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "password"), getEnv("DB_NAME", "orders_db"), getEnv("DB_SSLMODE", "disable"))
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	var piiCipher interfaces.PIICipher
+	if path := getEnv("PII_MASTER_KEY_FILE", ""); path != "" {
+		masterKey, err := encryption.LoadMasterKey(path)
+		if err != nil {
+			log.Fatalf("Invalid PII_MASTER_KEY_FILE: %v", err)
+		}
+		if piiCipher, err = encryption.NewKeyring(repositories.NewDataKeyRepository(db), masterKey); err != nil {
+			log.Fatalf("Failed to load data keys: %v", err)
+		}
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+	}
+	buffered := bufio.NewWriter(out)
+
+	writer, err := exporters.NewOrderWriter(*format, buffered)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if !*pii {
+		writer = exporters.NewMaskingWriter(writer, masker)
+	}
+
+	exporter := services.NewOrderExporter(repositories.NewOrderRepository(db, "", piiCipher))
+	exported, err := exporter.Export(filter, writer)
+	if err != nil {
+		log.Fatalf("Error after %d orders: %v", exported, err)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		log.Fatalf("Failed to write output: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatalf("Failed to close output: %v", err)
+	}
+
+	log.Printf("Exported %d orders", exported)
+}
+
+// parseTime разбирает дату (2006-01-02) или время в RFC 3339; пустая строка - нулевое время
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/application/services"
+	"WbServis/Wbl0/internal/infrastructure/cache"
+	"WbServis/Wbl0/internal/infrastructure/encryption"
+	"WbServis/Wbl0/internal/infrastructure/importers"
+	"WbServis/Wbl0/internal/infrastructure/repositories"
+	"WbServis/Wbl0/pkg/schemas"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: import [flags] [file]
+
+Загружает заказы в БД в обход брокера. Файл - NDJSON (заказ на строку),
+JSON-массив заказов или CSV в формате выгрузки; без файла или "-" читается stdin.
+Записи проверяются по JSON Schema заказа и сохраняются пачками.
+Данные получателя шифруются мастер-ключом из файла PII_MASTER_KEY_FILE, как в сервисе.
+При заданном REDIS_ADDR импортированные заказы удаляются из общего кэша.
+Подключение к БД задается переменными DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE.
+
+Flags:
+`
+
+// importInstanceID помечает уведомления об изменении заказов: запущенные
+// экземпляры сервиса вытеснят импортированные заказы из своих кэшей
+const importInstanceID = "import"
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	format := flag.String("format", "", "формат: "+strings.Join(importers.Formats, ", ")+" (по умолчанию - по расширению файла, для stdin - ndjson)")
+	batchSize := flag.Int("batch", 500, "число заказов в одной транзакции")
+	dryRun := flag.Bool("dry-run", false, "только проверить записи, ничего не сохраняя")
+	skip := flag.Int("skip", 0, "пропустить записи до этой строки включительно (продолжение прерванного импорта)")
+	rejectsPath := flag.String("rejects", "", "файл для отклоненных записей (NDJSON: line, error, record)")
+	progressInterval := flag.Duration("progress", 5*time.Second, "период вывода прогресса")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	path := flag.Arg(0)
+	input := io.Reader(os.Stdin)
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+		input = file
+
+		if *format == "" {
+			*format = importers.DetectFormat(path)
+		}
+	}
+	if *format == "" {
+		*format = importers.FormatNDJSON
+	}
+
+	reader, err := importers.NewOrderReader(*format, input)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Ошибки проверки могут содержать значения полей - маскируем их в логе,
+	// файл -rejects хранит исходные записи как есть
+	masker, err := services.NewPIIMasker(getEnv("PII_MASK_RULES", ""))
+	if err != nil {
+		log.Fatalf("Invalid PII_MASK_RULES: %v", err)
+	}
+	log.SetOutput(services.NewMaskingWriter(os.Stderr, masker))
+
+	validator, err := schemas.NewOrderValidator(true)
+	if err != nil {
+		log.Fatalf("Failed to load order schemas: %v", err)
+	}
+
+	dbURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", "5432"), getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "password"), getEnv("DB_NAME", "orders_db"), getEnv("DB_SSLMODE", "disable"))
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// При -dry-run база не нужна: заказы не сохраняются
+	var piiCipher interfaces.PIICipher
+	if !*dryRun {
+		if err := db.Ping(); err != nil {
+			log.Fatalf("Failed to ping database: %v", err)
+		}
+
+		if path := getEnv("PII_MASTER_KEY_FILE", ""); path != "" {
+			masterKey, err := encryption.LoadMasterKey(path)
+			if err != nil {
+				log.Fatalf("Invalid PII_MASTER_KEY_FILE: %v", err)
+			}
+			if piiCipher, err = encryption.NewKeyring(repositories.NewDataKeyRepository(db), masterKey); err != nil {
+				log.Fatalf("Failed to load data keys: %v", err)
+			}
+		}
+	}
+
+	options := dto.ImportOptions{
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+		SkipLines: *skip,
+	}
+
+	if *rejectsPath != "" {
+		rejects, err := os.Create(*rejectsPath)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *rejectsPath, err)
+		}
+		defer rejects.Close()
+
+		encoder := json.NewEncoder(rejects)
+		options.OnReject = func(record *dto.ImportRecord, err error) {
+			encoder.Encode(rejectedRecord{Line: record.Line, Error: err.Error(), Record: string(record.Raw)})
+		}
+	} else {
+		options.OnReject = func(record *dto.ImportRecord, err error) {
+			log.Printf("Rejected record at line %d: %v", record.Line, err)
+		}
+	}
+
+	lastProgress := time.Now()
+	options.OnProgress = func(stats dto.ImportStats) {
+		if time.Since(lastProgress) < *progressInterval {
+			return
+		}
+		lastProgress = time.Now()
+		printStats("Progress", stats)
+	}
+
+	// Экземпляры сервиса по уведомлениям вытесняют только локальные кэши,
+	// поэтому общий кэш обновляет тот, кто сохранил заказы
+	var sharedCache interfaces.OrderCache
+	if redisAddr := getEnv("REDIS_ADDR", ""); redisAddr != "" && !*dryRun {
+		redisDB, err := strconv.Atoi(getEnv("REDIS_DB", "0"))
+		if err != nil {
+			log.Fatalf("Invalid REDIS_DB: %v", err)
+		}
+		sharedCache = cache.NewRedisCache(cache.RedisConfig{
+			Addr:     redisAddr,
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       redisDB,
+		})
+	}
+
+	importer := services.NewOrderImporter(repositories.NewOrderRepository(db, importInstanceID, piiCipher), validator, sharedCache)
+	stats, err := importer.Import(reader, options)
+	printStats("Finished", *stats)
+	if err != nil {
+		log.Fatalf("Import stopped: %v (continue with -skip %d)", err, stats.CommittedLine)
+	}
+}
+
+// rejectedRecord - строка файла отклоненных записей
+type rejectedRecord struct {
+	Line   int    `json:"line"`
+	Error  string `json:"error"`
+	Record string `json:"record"`
+}
+
+func printStats(title string, stats dto.ImportStats) {
+	rate := 0.0
+	if seconds := stats.Elapsed.Seconds(); seconds > 0 {
+		rate = float64(stats.Imported) / seconds
+	}
+	log.Printf("%s: read=%d skipped=%d imported=%d rejected=%d committed_line=%d (%.0f orders/s)",
+		title, stats.Read, stats.Skipped, stats.Imported, stats.Rejected, stats.CommittedLine, rate)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/services"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
+	"WbServis/Wbl0/internal/infrastructure/generators"
+	"WbServis/Wbl0/pkg/kafkaconfig"
+
+	"github.com/IBM/sarama"
+)
+
+const usage = `Usage: latency [flags]
+
+Измеряет сквозную задержку: отправляет в Kafka сгенерированные заказы с заголовком
+produced-at и опрашивает GET /order/{order_uid}, пока заказ не станет доступен.
+Выводит перцентили задержки и заказы, не появившиеся за -timeout (потери).
+Задержка включает до одного периода -poll, поэтому -poll должен быть заметно
+меньше ожидаемой задержки. Если все -pollers заняты ожиданием, отправка
+притормаживает, поэтому -pollers должен покрывать -rate, умноженный на задержку.
+
+Flags:
+`
+
+// pending - отправленный заказ, ожидающий появления в API
+type pending struct {
+	orderUID   string
+	producedAt time.Time
+}
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	brokers := flag.String("brokers", getEnv("KAFKA_BROKERS", "localhost:9092"), "адреса брокеров через запятую")
+	topic := flag.String("topic", getEnv("KAFKA_TOPIC", "orders"), "топик заказов")
+	format := flag.String("format", codecs.FormatJSON, "формат сообщений: json, protobuf, avro")
+	apiURL := flag.String("api", "http://localhost:8081", "адрес HTTP API сервиса")
+	apiKey := flag.String("api-key", getEnv("ORDER_SERVICE_API_KEY", ""), "API-ключ с областью orders:read")
+	seed := flag.Uint64("seed", 0, "seed генератора заказов (0 - случайный; повтор seed дает уже сохраненные заказы)")
+	count := flag.Int("count", 100, "число заказов")
+	rate := flag.Float64("rate", 10, "число заказов в секунду (0 - без ограничения)")
+	timeout := flag.Duration("timeout", 30*time.Second, "время ожидания заказа, после которого он считается потерянным")
+	pollInterval := flag.Duration("poll", 50*time.Millisecond, "период опроса API")
+	pollers := flag.Int("pollers", 32, "число параллельно ожидаемых заказов")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *count < 1 || *pollers < 1 {
+		log.Fatalf("Error: -count and -pollers must be at least 1")
+	}
+
+	var codec codecs.Codec
+	var err error
+	switch *format {
+	case codecs.FormatJSON:
+		codec = codecs.NewJSONCodec(nil)
+	case codecs.FormatProtobuf:
+		codec = codecs.NewProtobufCodec()
+	case codecs.FormatAvro:
+		codec, err = codecs.NewAvroCodec(nil)
+	default:
+		err = fmt.Errorf("unknown message format %q", *format)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
+	log.Printf("Using seed %d", *seed)
+
+	config, err := kafkaconfig.NewSaramaConfig(kafkaconfig.FromEnv())
+	if err != nil {
+		log.Fatalf("Invalid Kafka configuration: %v", err)
+	}
+	config.ClientID = "order-latency"
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	producer, err := sarama.NewSyncProducer(kafkaconfig.SplitList(*brokers), config)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka producer: %v", err)
+	}
+	defer producer.Close()
+
+	// Прерывание останавливает отправку; уже отправленные заказы ожидаются до -timeout
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var sent, produceErrors, lost atomic.Int64
+	latency := services.NewLatencyRecorder(*count)
+	client := &http.Client{Timeout: *timeout}
+	base := strings.TrimRight(*apiURL, "/")
+
+	queue := make(chan pending, *pollers)
+	go func() {
+		defer close(queue)
+
+		gen := generators.NewOrderGenerator(*seed)
+		var interval time.Duration
+		if *rate > 0 {
+			interval = time.Duration(float64(time.Second) / *rate)
+		}
+		start := time.Now()
+
+		for i := 0; i < *count; i++ {
+			if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			order := gen.Order()
+			payload, err := codec.Encode(order)
+			if err != nil {
+				log.Fatalf("Failed to encode order %s: %v", order.OrderUID, err)
+			}
+
+			producedAt := time.Now()
+			_, _, err = producer.SendMessage(&sarama.ProducerMessage{
+				Topic: *topic,
+				Key:   sarama.StringEncoder(order.OrderUID),
+				Value: sarama.ByteEncoder(payload),
+				Headers: []sarama.RecordHeader{
+					{Key: []byte(codecs.ContentTypeHeader), Value: []byte(codec.ContentType())},
+					{Key: []byte(dto.ProducedAtHeader), Value: []byte(producedAt.Format(time.RFC3339Nano))},
+				},
+			})
+			if err != nil {
+				produceErrors.Add(1)
+				log.Printf("Failed to produce order %s: %v", order.OrderUID, err)
+				continue
+			}
+			sent.Add(1)
+
+			queue <- pending{orderUID: order.OrderUID, producedAt: producedAt}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range *pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				seenAt, err := waitForOrder(client, base, *apiKey, p, *timeout, *pollInterval)
+				if err != nil {
+					lost.Add(1)
+					log.Printf("Order %s lost: %v", p.orderUID, err)
+					continue
+				}
+				latency.Observe(seenAt.Sub(p.producedAt))
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := latency.Stats()
+	lossRate := 0.0
+	if n := sent.Load(); n > 0 {
+		lossRate = float64(lost.Load()) / float64(n) * 100
+	}
+	log.Printf("Sent %d orders (produce errors: %d), seen %d, lost %d (%.2f%%)",
+		sent.Load(), produceErrors.Load(), stats.Count, lost.Load(), lossRate)
+	log.Printf("End-to-end latency: p50=%.1fms p95=%.1fms p99=%.1fms max=%.1fms",
+		stats.P50, stats.P95, stats.P99, stats.Max)
+
+	if lost.Load() > 0 || produceErrors.Load() > 0 {
+		os.Exit(1)
+	}
+}
+
+// waitForOrder опрашивает API, пока заказ не станет доступен, и возвращает
+// время первого успешного ответа
+func waitForOrder(client *http.Client, base, apiKey string, p pending, timeout, pollInterval time.Duration) (time.Time, error) {
+	orderURL := base + "/order/" + url.PathEscape(p.orderUID)
+	deadline := p.producedAt.Add(timeout)
+
+	var lastErr error
+	for {
+		found, err := fetchOrder(client, orderURL, apiKey)
+		if found {
+			return time.Now(), nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+
+		if time.Now().Add(pollInterval).After(deadline) {
+			if lastErr != nil {
+				return time.Time{}, fmt.Errorf("not found after %s, last error: %w", timeout, lastErr)
+			}
+			return time.Time{}, fmt.Errorf("not found after %s", timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// fetchOrder сообщает, отдает ли API заказ. 404 - заказ еще не сохранен,
+// остальные ответы считаются ошибкой
+func fetchOrder(client *http.Client, orderURL, apiKey string) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, orderURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to request order: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// orderAPI отдает 404, пока не получит notFound запросов, затем status
+func orderAPI(t *testing.T, notFound int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/order/order%201" {
+			t.Errorf("requested %s", r.URL.EscapedPath())
+		}
+		if requests.Add(1) <= notFound {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWaitForOrderPollsUntilFound(t *testing.T) {
+	server, requests := orderAPI(t, 3, http.StatusOK)
+	p := pending{orderUID: "order 1", producedAt: time.Now()}
+
+	seenAt, err := waitForOrder(server.Client(), server.URL, "secret", p, 5*time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("waitForOrder() error = %v", err)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("requests = %d, want 4", requests.Load())
+	}
+	if !seenAt.After(p.producedAt) {
+		t.Errorf("seen at %s, before produced at %s", seenAt, p.producedAt)
+	}
+}
+
+func TestWaitForOrderLost(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		apiKey  string
+		wantErr string
+	}{
+		{name: "never saved", status: http.StatusNotFound, apiKey: "secret", wantErr: "not found after 50ms"},
+		{name: "server error", status: http.StatusInternalServerError, apiKey: "secret", wantErr: "unexpected status 500"},
+		{name: "wrong api key", status: http.StatusOK, apiKey: "wrong", wantErr: "unexpected status 401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := orderAPI(t, 0, tt.status)
+			p := pending{orderUID: "order 1", producedAt: time.Now()}
+
+			// Заказ считается потерянным по истечении timeout от отправки
+			start := time.Now()
+			_, err := waitForOrder(server.Client(), server.URL, tt.apiKey, p, 50*time.Millisecond, 5*time.Millisecond)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("waitForOrder() error = %v, want %q", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("waited %s after the timeout", elapsed)
+			}
+		})
+	}
+}
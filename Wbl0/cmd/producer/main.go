@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
+	"WbServis/Wbl0/internal/infrastructure/generators"
+	"WbServis/Wbl0/internal/infrastructure/importers"
+	"WbServis/Wbl0/pkg/kafkaconfig"
+
+	"github.com/IBM/sarama"
+)
+
+const usage = `Usage: producer [flags] [fixture files...]
+
+Отправляет заказы в Kafka. Без файлов генерирует случайные заказы с корректными
+суммами (одинаковый -seed дает одинаковую последовательность), с файлами -
+отправляет заказы из них по кругу. Файлы - NDJSON, JSON-массив или CSV в формате выгрузки.
+Без -count и -duration отправляется 10 заказов или каждый заказ из файлов по одному разу.
+Настройки TLS/SASL берутся из переменных KAFKA_* так же, как у сервиса.
+
+Flags:
+`
+
+// message - подготовленное к отправке сообщение
+type message struct {
+	key     string
+	value   []byte
+	invalid string
+}
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	brokers := flag.String("brokers", getEnv("KAFKA_BROKERS", "localhost:9092"), "адреса брокеров через запятую")
+	topic := flag.String("topic", getEnv("KAFKA_TOPIC", "orders"), "топик заказов")
+	format := flag.String("format", codecs.FormatJSON, "формат сообщений: json, protobuf, avro")
+	seed := flag.Uint64("seed", 0, "seed генератора заказов (0 - случайный)")
+	count := flag.Int("count", 0, "число сообщений (0 - без ограничения, если задан -duration)")
+	duration := flag.Duration("duration", 0, "длительность отправки (0 - без ограничения, если задан -count)")
+	rate := flag.Float64("rate", 0, "целевое число сообщений в секунду (0 - без ограничения)")
+	concurrency := flag.Int("concurrency", 1, "число параллельных отправителей")
+	invalidRatio := flag.Float64("invalid", 0, "доля невалидных сообщений от 0 до 1")
+	reportInterval := flag.Duration("report", 5*time.Second, "период вывода промежуточной статистики (0 - только итог)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *concurrency < 1 {
+		log.Fatalf("Error: -concurrency must be at least 1")
+	}
+	if *invalidRatio < 0 || *invalidRatio > 1 {
+		log.Fatalf("Error: -invalid must be between 0 and 1")
+	}
+
+	codec, err := newCodec(*format)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	var fixtures []*entities.Order
+	for _, path := range flag.Args() {
+		orders, err := loadFixtures(path)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		fixtures = append(fixtures, orders...)
+	}
+	if flag.NArg() > 0 && len(fixtures) == 0 {
+		log.Fatalf("Error: no orders in fixture files")
+	}
+
+	if *count == 0 && *duration == 0 {
+		*count = 10
+		if len(fixtures) > 0 {
+			*count = len(fixtures)
+		}
+	}
+
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
+	log.Printf("Using seed %d", *seed)
+
+	config, err := kafkaconfig.NewSaramaConfig(kafkaconfig.FromEnv())
+	if err != nil {
+		log.Fatalf("Invalid Kafka configuration: %v", err)
+	}
+	config.ClientID = "order-producer"
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	producer, err := sarama.NewSyncProducer(kafkaconfig.SplitList(*brokers), config)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka producer: %v", err)
+	}
+	defer producer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	gen := generators.NewOrderGenerator(*seed)
+	messages := make(chan message, *concurrency)
+	go func() {
+		defer close(messages)
+
+		var interval time.Duration
+		if *rate > 0 {
+			interval = time.Duration(float64(time.Second) / *rate)
+		}
+		start := time.Now()
+
+		for i := 0; *count == 0 || i < *count; i++ {
+			if interval > 0 {
+				// Отправка по расписанию от старта, а не от предыдущего сообщения,
+				// чтобы задержки отдельных отправок не снижали итоговый темп
+				if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			msg, err := nextMessage(gen, codec, fixtures, i, *invalidRatio)
+			if err != nil {
+				log.Printf("Failed to encode order: %v", err)
+				continue
+			}
+
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	stats := newProduceStats()
+	var wg sync.WaitGroup
+	for range *concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range messages {
+				_, _, err := producer.SendMessage(&sarama.ProducerMessage{
+					Topic: *topic,
+					Key:   sarama.StringEncoder(msg.key),
+					Value: sarama.ByteEncoder(msg.value),
+					Headers: []sarama.RecordHeader{
+						{Key: []byte(codecs.ContentTypeHeader), Value: []byte(codec.ContentType())},
+						{Key: []byte(dto.ProducedAtHeader), Value: []byte(time.Now().Format(time.RFC3339Nano))},
+					},
+				})
+				stats.record(msg, err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if *reportInterval > 0 {
+		ticker := time.NewTicker(*reportInterval)
+		defer ticker.Stop()
+	report:
+		for {
+			select {
+			case <-ticker.C:
+				stats.print("Progress")
+			case <-done:
+				break report
+			}
+		}
+	}
+	<-done
+
+	stats.print("Finished")
+	stats.printErrors()
+	if stats.failed.Load() > 0 {
+		os.Exit(1)
+	}
+}
+
+// nextMessage возвращает i-е сообщение: невалидное с вероятностью invalidRatio,
+// иначе заказ из фикстур по кругу или сгенерированный заказ
+func nextMessage(gen *generators.OrderGenerator, codec codecs.Codec, fixtures []*entities.Order, i int, invalidRatio float64) (message, error) {
+	if invalidRatio > 0 && gen.Chance(invalidRatio) {
+		kind, payload := gen.Invalid()
+		return message{key: gen.ID(), value: payload, invalid: kind}, nil
+	}
+
+	var order *entities.Order
+	if len(fixtures) > 0 {
+		order = fixtures[i%len(fixtures)]
+	} else {
+		order = gen.Order()
+	}
+
+	payload, err := codec.Encode(order)
+	if err != nil {
+		return message{}, fmt.Errorf("order %s: %w", order.OrderUID, err)
+	}
+	return message{key: order.OrderUID, value: payload}, nil
+}
+
+// newCodec возвращает кодек для отправки сообщений в формате format
+func newCodec(format string) (codecs.Codec, error) {
+	switch format {
+	case codecs.FormatJSON:
+		return codecs.NewJSONCodec(nil), nil
+	case codecs.FormatProtobuf:
+		return codecs.NewProtobufCodec(), nil
+	case codecs.FormatAvro:
+		return codecs.NewAvroCodec(nil)
+	default:
+		return nil, fmt.Errorf("unknown message format %q", format)
+	}
+}
+
+// loadFixtures читает заказы из файла в любом формате импорта
+func loadFixtures(path string) ([]*entities.Order, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader, err := importers.NewOrderReader(importers.DetectFormat(path), file)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*entities.Order
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return orders, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if record.Err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, record.Line, record.Err)
+		}
+
+		var order entities.Order
+		if err := json.Unmarshal(record.Payload, &order); err != nil {
+			return nil, fmt.Errorf("%s:%d: failed to unmarshal order: %w", path, record.Line, err)
+		}
+		orders = append(orders, &order)
+	}
+}
+
+// produceStats считает результаты отправки
+type produceStats struct {
+	start   time.Time
+	sent    atomic.Int64
+	invalid atomic.Int64
+	failed  atomic.Int64
+
+	mu     sync.Mutex
+	errors map[string]int
+}
+
+func newProduceStats() *produceStats {
+	return &produceStats{
+		start:  time.Now(),
+		errors: make(map[string]int),
+	}
+}
+
+func (s *produceStats) record(msg message, err error) {
+	if err != nil {
+		s.failed.Add(1)
+		s.mu.Lock()
+		s.errors[err.Error()]++
+		s.mu.Unlock()
+		return
+	}
+
+	s.sent.Add(1)
+	if msg.invalid != "" {
+		s.invalid.Add(1)
+	}
+}
+
+func (s *produceStats) print(title string) {
+	elapsed := time.Since(s.start)
+	sent := s.sent.Load()
+	rate := 0.0
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		rate = float64(sent) / seconds
+	}
+	log.Printf("%s: sent=%d (invalid=%d) failed=%d elapsed=%s (%.0f msg/s)",
+		title, sent, s.invalid.Load(), s.failed.Load(), elapsed.Round(time.Millisecond), rate)
+}
+
+// printErrors выводит ошибки отправки, сгруппированные по тексту
+func (s *produceStats) printErrors() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]string, 0, len(s.errors))
+	for message := range s.errors {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return s.errors[messages[i]] > s.errors[messages[j]]
+	})
+
+	for _, message := range messages {
+		log.Printf("Produce error (%d times): %s", s.errors[message], message)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
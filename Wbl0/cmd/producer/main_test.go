@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
+	"WbServis/Wbl0/internal/infrastructure/generators"
+)
+
+func TestNextMessageCyclesFixtures(t *testing.T) {
+	fixtures := []*entities.Order{{OrderUID: "a"}, {OrderUID: "b"}}
+	gen := generators.NewOrderGenerator(1)
+	codec := codecs.NewJSONCodec(nil)
+
+	var keys []string
+	for i := range 5 {
+		msg, err := nextMessage(gen, codec, fixtures, i, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var order entities.Order
+		if err := json.Unmarshal(msg.value, &order); err != nil || order.OrderUID != msg.key {
+			t.Fatalf("message %d: key %s, value %s", i, msg.key, msg.value)
+		}
+		keys = append(keys, msg.key)
+	}
+	if got := strings.Join(keys, ","); got != "a,b,a,b,a" {
+		t.Errorf("keys = %s, want fixtures in a loop", got)
+	}
+}
+
+func TestNextMessageGeneratesOrders(t *testing.T) {
+	codec := codecs.NewJSONCodec(nil)
+	first, second := generators.NewOrderGenerator(5), generators.NewOrderGenerator(5)
+
+	// Одинаковый seed дает одинаковую последовательность ключей
+	for i := range 10 {
+		a, err := nextMessage(first, codec, nil, i, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := nextMessage(second, codec, nil, i, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.key != b.key || a.invalid != "" {
+			t.Fatalf("message %d: keys %s and %s for the same seed", i, a.key, b.key)
+		}
+	}
+
+	// С долей 1 все сообщения невалидные и помечены видом
+	for i := range 10 {
+		msg, err := nextMessage(first, codec, nil, i, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.invalid == "" || msg.key == "" {
+			t.Errorf("message %d = %+v, want invalid", i, msg)
+		}
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "orders.ndjson")
+	content := `{"order_uid":"a","items":[]}` + "\n\n" + `{"order_uid":"b","items":[]}` + "\n"
+	if err := os.WriteFile(valid, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orders, err := loadFixtures(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].OrderUID != "a" || orders[1].OrderUID != "b" {
+		t.Errorf("loadFixtures() = %+v", orders)
+	}
+
+	// Ошибка указывает файл и строку записи
+	broken := filepath.Join(dir, "broken.ndjson")
+	if err := os.WriteFile(broken, []byte(`{"order_uid":"a"}`+"\n"+`not json`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFixtures(broken); err == nil || !strings.Contains(err.Error(), "broken.ndjson:2") {
+		t.Errorf("loadFixtures() error = %v, want file and line", err)
+	}
+
+	unknown := filepath.Join(dir, "orders.xml")
+	if err := os.WriteFile(unknown, []byte("<orders/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFixtures(unknown); err == nil {
+		t.Error("fixtures with unknown format accepted")
+	}
+}
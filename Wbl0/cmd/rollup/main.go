@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"WbServis/Wbl0/internal/application/services"
+	"WbServis/Wbl0/internal/infrastructure/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: rollup <command> [flags]
+
+Commands:
+  rebuild   пересчитать дневные сводки по исходным таблицам (-from, -to)
+  refresh   пересчитать дни, отмеченные после изменения заказов
+
+Подключение к БД задается переменными DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE.
+`
+
+func main() {
+	log.SetFlags(log.LstdFlags)
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dbURL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("DB_HOST", "localhost"), getEnv("DB_PORT", "5432"), getEnv("DB_USER", "postgres"),
+		getEnv("DB_PASSWORD", "password"), getEnv("DB_NAME", "orders_db"), getEnv("DB_SSLMODE", "disable"))
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	rollupService := services.NewRollupService(repositories.NewRollupRepository(db))
+
+	switch command, args := flag.Arg(0), flag.Args()[1:]; command {
+	case "rebuild":
+		err = rebuild(args, rollupService.Rebuild)
+	case "refresh":
+		err = rollupService.Refresh()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Println("Done")
+}
+
+func rebuild(args []string, run func(from, to time.Time) error) error {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	fromFlag := fs.String("from", "", "первый день периода (YYYY-MM-DD), по умолчанию - без ограничения")
+	toFlag := fs.String("to", "", "день после конца периода (YYYY-MM-DD), по умолчанию - без ограничения")
+	fs.Parse(args)
+
+	var from, to time.Time
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse(time.DateOnly, *fromFlag); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse(time.DateOnly, *toFlag); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	return run(from, to)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,12 @@
+-- Полнотекстовый и нечеткий поиск заказов
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Выражения индексов должны совпадать с выражениями в запросе поиска
+CREATE INDEX IF NOT EXISTS idx_deliveries_search ON deliveries
+    USING GIN (to_tsvector('simple', name || ' ' || address || ' ' || city));
+
+CREATE INDEX IF NOT EXISTS idx_items_search ON items
+    USING GIN (to_tsvector('simple', name || ' ' || brand));
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone_trgm ON deliveries USING GIN (phone gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_trgm ON deliveries USING GIN (email gin_trgm_ops);
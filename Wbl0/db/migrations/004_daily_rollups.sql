@@ -0,0 +1,111 @@
+-- Дневные сводки для аналитики. Пересчитываются фоновым заданием сервиса
+-- по дням, отмеченным в rollup_dirty_days триггерами таблиц заказов.
+
+CREATE TABLE IF NOT EXISTS rollup_daily_orders (
+    day DATE NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    orders INTEGER NOT NULL,
+    items INTEGER NOT NULL,
+    revenue BIGINT NOT NULL,
+    goods_total BIGINT NOT NULL,
+    PRIMARY KEY (day, currency)
+);
+
+CREATE TABLE IF NOT EXISTS rollup_daily_brands (
+    day DATE NOT NULL,
+    brand VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    quantity INTEGER NOT NULL,
+    revenue BIGINT NOT NULL,
+    PRIMARY KEY (day, brand, currency)
+);
+
+CREATE TABLE IF NOT EXISTS rollup_daily_delivery_services (
+    day DATE NOT NULL,
+    delivery_service VARCHAR(100) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    orders INTEGER NOT NULL,
+    revenue BIGINT NOT NULL,
+    PRIMARY KEY (day, delivery_service, currency)
+);
+
+-- Дни, сводки которых нужно пересчитать. Отметки не уникальны: отметка,
+-- добавленная во время пересчета, обрабатывается следующим проходом.
+CREATE TABLE IF NOT EXISTS rollup_dirty_days (
+    id BIGSERIAL PRIMARY KEY,
+    day DATE NOT NULL
+);
+
+-- Отмечает дни измененных заказов (старую и новую дату создания)
+CREATE OR REPLACE FUNCTION rollup_mark_orders()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO rollup_dirty_days (day)
+        SELECT DISTINCT date_created::date FROM old_rows;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO rollup_dirty_days (day)
+        SELECT DISTINCT date_created::date FROM new_rows;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+-- Отмечает дни заказов, у которых изменились оплата или товары
+CREATE OR REPLACE FUNCTION rollup_mark_order_parts()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO rollup_dirty_days (day)
+        SELECT DISTINCT o.date_created::date FROM old_rows r JOIN orders o ON o.order_uid = r.order_uid;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO rollup_dirty_days (day)
+        SELECT DISTINCT o.date_created::date FROM new_rows r JOIN orders o ON o.order_uid = r.order_uid;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+-- Триггеры уровня оператора с таблицами переходов: одна отметка на день
+-- для всей пачки, а не на каждую строку
+DROP TRIGGER IF EXISTS orders_rollup_insert ON orders;
+CREATE TRIGGER orders_rollup_insert AFTER INSERT ON orders
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_orders();
+
+DROP TRIGGER IF EXISTS orders_rollup_update ON orders;
+CREATE TRIGGER orders_rollup_update AFTER UPDATE ON orders
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_orders();
+
+DROP TRIGGER IF EXISTS orders_rollup_delete ON orders;
+CREATE TRIGGER orders_rollup_delete AFTER DELETE ON orders
+    REFERENCING OLD TABLE AS old_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_orders();
+
+DROP TRIGGER IF EXISTS payments_rollup_insert ON payments;
+CREATE TRIGGER payments_rollup_insert AFTER INSERT ON payments
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_order_parts();
+
+DROP TRIGGER IF EXISTS payments_rollup_update ON payments;
+CREATE TRIGGER payments_rollup_update AFTER UPDATE ON payments
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_order_parts();
+
+DROP TRIGGER IF EXISTS items_rollup_insert ON items;
+CREATE TRIGGER items_rollup_insert AFTER INSERT ON items
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_order_parts();
+
+DROP TRIGGER IF EXISTS items_rollup_update ON items;
+CREATE TRIGGER items_rollup_update AFTER UPDATE ON items
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_order_parts();
+
+DROP TRIGGER IF EXISTS items_rollup_delete ON items;
+CREATE TRIGGER items_rollup_delete AFTER DELETE ON items
+    REFERENCING OLD TABLE AS old_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION rollup_mark_order_parts();
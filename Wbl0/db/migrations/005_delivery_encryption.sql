@@ -0,0 +1,33 @@
+-- Шифрование имени, телефона, адреса и email получателя на уровне приложения.
+-- Существующие строки остаются открытым текстом (key_id IS NULL), пока их не
+-- зашифрует команда `encrypt migrate`.
+
+-- Ключи данных, зашифрованные мастер-ключом из файла PII_MASTER_KEY_FILE
+CREATE TABLE IF NOT EXISTS data_keys (
+    id SERIAL PRIMARY KEY,
+    purpose VARCHAR(10) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Ключ слепого индекса один: с новым ключом пришлось бы пересчитать все хэши
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_keys_index ON data_keys(purpose) WHERE purpose = 'index';
+
+-- Шифртекст в base64 длиннее исходных значений
+ALTER TABLE deliveries
+    ALTER COLUMN name TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN email TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS key_id INTEGER REFERENCES data_keys(id),
+    ADD COLUMN IF NOT EXISTS phone_hash VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS email_hash VARCHAR(64);
+
+-- Точный поиск по слепому индексу и выбор строк для перешифрования
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone_hash ON deliveries(phone_hash);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_hash ON deliveries(email_hash);
+CREATE INDEX IF NOT EXISTS idx_deliveries_key_id ON deliveries(key_id);
+
+-- У зашифрованных строк полнотекстово ищется только город
+CREATE INDEX IF NOT EXISTS idx_deliveries_city_search ON deliveries
+    USING GIN (to_tsvector('simple', city));
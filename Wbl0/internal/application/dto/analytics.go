@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+)
+
+// RevenueInterval - шаг группировки выручки по времени
+type RevenueInterval string
+
+const (
+	RevenueByDay   RevenueInterval = "day"
+	RevenueByWeek  RevenueInterval = "week"
+	RevenueByMonth RevenueInterval = "month"
+)
+
+// TopMetric - показатель, по которому строится рейтинг брендов и товаров
+type TopMetric string
+
+const (
+	TopByQuantity TopMetric = "quantity"
+	TopByRevenue  TopMetric = "revenue"
+)
+
+// BreakdownDimension - поле заказа, по которому строится разбивка
+type BreakdownDimension string
+
+const (
+	BreakdownDeliveryService BreakdownDimension = "delivery_service"
+	BreakdownRegion          BreakdownDimension = "region"
+	BreakdownProvider        BreakdownDimension = "provider"
+	BreakdownBank            BreakdownDimension = "bank"
+)
+
+// AnalyticsFilter задает период [From, To) и необязательные фильтры аналитических запросов
+type AnalyticsFilter struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	Currency        string    `json:"currency,omitempty"`
+	DeliveryService string    `json:"delivery_service,omitempty"`
+	Region          string    `json:"region,omitempty"`
+	Provider        string    `json:"provider,omitempty"`
+	Bank            string    `json:"bank,omitempty"`
+}
+
+// Key возвращает строку, однозначно описывающую фильтр (для кэширования результатов)
+func (f AnalyticsFilter) Key() string {
+	return fmt.Sprintf("%d|%d|%q|%q|%q|%q|%q", f.From.UnixNano(), f.To.UnixNano(),
+		f.Currency, f.DeliveryService, f.Region, f.Provider, f.Bank)
+}
+
+// RevenuePoint представляет выручку за период в одной валюте
+type RevenuePoint struct {
+	Period   time.Time `json:"period"`
+	Currency string    `json:"currency"`
+	Orders   int       `json:"orders"`
+	Revenue  int64     `json:"revenue"`
+}
+
+// TopEntry представляет позицию рейтинга брендов или товаров.
+// Выручка между валютами не пересчитывается, поэтому позиция - пара ключ и валюта.
+type TopEntry struct {
+	Brand    string `json:"brand,omitempty"`
+	NmID     int    `json:"nm_id,omitempty"`
+	Currency string `json:"currency"`
+	Quantity int    `json:"quantity"`
+	Revenue  int64  `json:"revenue"`
+}
+
+// BasketStats представляет средний размер корзины в одной валюте
+type BasketStats struct {
+	Currency      string  `json:"currency"`
+	Orders        int     `json:"orders"`
+	AvgItems      float64 `json:"avg_items"`
+	AvgAmount     float64 `json:"avg_amount"`
+	AvgGoodsTotal float64 `json:"avg_goods_total"`
+}
+
+// BreakdownEntry представляет число заказов и выручку для одного значения поля
+type BreakdownEntry struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+	Orders   int    `json:"orders"`
+	Revenue  int64  `json:"revenue"`
+}
+
+// DiscountStats представляет статистику скидок (sale) по товарам
+type DiscountStats struct {
+	Items           int     `json:"items"`
+	DiscountedItems int     `json:"discounted_items"`
+	AvgSale         float64 `json:"avg_sale"`
+	AvgSaleDiscount float64 `json:"avg_sale_discounted"`
+	MaxSale         int     `json:"max_sale"`
+}
+
+// AnalyticsResponse представляет результат аналитического запроса вместе с примененным фильтром
+type AnalyticsResponse struct {
+	Filter AnalyticsFilter `json:"filter"`
+	Result interface{}     `json:"result"`
+}
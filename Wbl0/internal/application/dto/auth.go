@@ -0,0 +1,32 @@
+package dto
+
+import "slices"
+
+// Области доступа HTTP API
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+	ScopeAdmin       = "admin"
+	// ScopeOrdersPII открывает персональные данные получателя без маскирования
+	ScopeOrdersPII = "orders:pii"
+)
+
+// Способы аутентификации клиента
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// Principal представляет аутентифицированного клиента API
+type Principal struct {
+	// Subject - имя API-ключа или sub из JWT
+	Subject string
+	Method  string
+	Scopes  []string
+}
+
+// HasScope сообщает, выдана ли клиенту область доступа. Области не наследуются:
+// admin не дает доступа к заказам.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
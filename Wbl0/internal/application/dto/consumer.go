@@ -0,0 +1,68 @@
+package dto
+
+import "time"
+
+// Позиции для сброса офсетов группы потребителя
+const (
+	OffsetResetEarliest  = "earliest"
+	OffsetResetLatest    = "latest"
+	OffsetResetOffset    = "offset"
+	OffsetResetTimestamp = "timestamp"
+)
+
+// PartitionOffsets описывает офсеты группы потребителя в партиции
+type PartitionOffsets struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	Committed     int64  `json:"committed"`
+	LogStart      int64  `json:"log_start"`
+	HighWaterMark int64  `json:"high_water_mark"`
+	Lag           int64  `json:"lag"`
+}
+
+// OffsetResetRequest представляет запрос на сброс офсетов группы потребителя
+type OffsetResetRequest struct {
+	// Topic - топик для сброса, пустое значение означает все топики потребителя
+	Topic string `json:"topic,omitempty"`
+	// Partitions - партиции для сброса, пустой список означает все партиции топика
+	Partitions []int32 `json:"partitions,omitempty"`
+	// To - целевая позиция: earliest, latest, offset или timestamp
+	To        string    `json:"to"`
+	Offset    int64     `json:"offset,omitempty"`
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// OffsetTarget описывает офсет, на который переводится партиция
+type OffsetTarget struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	// Applied - офсет переведен. false - партиция назначена другому экземпляру
+	// группы, сброс нужно повторить на нем.
+	Applied bool `json:"applied"`
+}
+
+// ConsumerStatusResponse представляет состояние потребителя
+type ConsumerStatusResponse struct {
+	Paused     bool               `json:"paused"`
+	Partitions []PartitionOffsets `json:"partitions"`
+}
+
+// ConsumerLag представляет последний замер отставания потребителя
+type ConsumerLag struct {
+	// Total - суммарное отставание по всем партициям
+	Total      int64              `json:"total"`
+	Partitions []PartitionOffsets `json:"partitions"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	// Error - ошибка последнего замера; Partitions при этом содержат предыдущий успешный замер
+	Error string `json:"error,omitempty"`
+}
+
+// HealthResponse представляет состояние сервиса
+type HealthResponse struct {
+	Status   string       `json:"status"`
+	Service  string       `json:"service"`
+	Consumer *ConsumerLag `json:"consumer,omitempty"`
+	// PersistLatency - задержка от отправки сообщения до сохранения заказа
+	PersistLatency *LatencyStats `json:"persist_latency,omitempty"`
+}
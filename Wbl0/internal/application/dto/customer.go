@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// CustomerOrderSummary представляет краткие сведения о заказе покупателя
+type CustomerOrderSummary struct {
+	OrderUID     string    `json:"order_uid"`
+	DateCreated  time.Time `json:"date_created"`
+	Amount       int       `json:"amount"`
+	Currency     string    `json:"currency"`
+	ItemCount    int       `json:"item_count"`
+	DeliveryCity string    `json:"delivery_city"`
+}
+
+// CurrencyTotal представляет сумму заказов покупателя в одной валюте
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+	Orders   int    `json:"orders"`
+}
+
+// CustomerOrderStats представляет сводку по всем заказам покупателя
+type CustomerOrderStats struct {
+	OrderCount int             `json:"order_count"`
+	FirstOrder *time.Time      `json:"first_order,omitempty"`
+	LastOrder  *time.Time      `json:"last_order,omitempty"`
+	TotalSpent []CurrencyTotal `json:"total_spent"`
+}
+
+// CustomerOrdersResponse представляет страницу заказов покупателя со сводкой
+type CustomerOrdersResponse struct {
+	CustomerID string                 `json:"customer_id"`
+	Stats      CustomerOrderStats     `json:"stats"`
+	Limit      int                    `json:"limit"`
+	Offset     int                    `json:"offset"`
+	Orders     []CustomerOrderSummary `json:"orders"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// Назначения ключей в таблице data_keys
+const (
+	// DataKeyPurposeData - ключ шифрования полей; новые строки шифруются последним
+	DataKeyPurposeData = "data"
+	// DataKeyPurposeIndex - ключ слепого индекса телефона и email, он один на базу
+	DataKeyPurposeIndex = "index"
+)
+
+// DataKey - ключ из таблицы data_keys, зашифрованный мастер-ключом
+type DataKey struct {
+	ID      int
+	Purpose string
+	// WrappedKey - nonce и шифртекст ключа AES-256-GCM мастер-ключа
+	WrappedKey []byte
+	// MasterKeyID - отпечаток мастер-ключа, которым зашифрован ключ
+	MasterKeyID string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+// ExportFilter задает отбор заказов для выгрузки. Нулевые и пустые поля не ограничивают выборку.
+type ExportFilter struct {
+	From            time.Time
+	To              time.Time
+	CustomerID      string
+	DeliveryService string
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+// ImportRecord представляет одну запись файла импорта
+type ImportRecord struct {
+	// Line - номер строки (для JSON-массива - номер элемента), с которой начинается запись
+	Line int
+	// Raw - исходный текст записи для файла отклоненных
+	Raw []byte
+	// Payload - заказ в JSON для проверки по схеме
+	Payload []byte
+	// Err - ошибка разбора записи; такая запись отклоняется
+	Err error
+}
+
+// ImportOptions задает параметры импорта заказов
+type ImportOptions struct {
+	// BatchSize - число заказов, сохраняемых одной транзакцией
+	BatchSize int
+	// DryRun - только проверить записи, ничего не сохраняя
+	DryRun bool
+	// SkipLines - пропустить записи, начинающиеся не дальше этой строки (продолжение импорта)
+	SkipLines int
+	// OnReject вызывается для каждой отклоненной записи
+	OnReject func(record *ImportRecord, err error)
+	// OnProgress вызывается после сохранения каждой пачки
+	OnProgress func(stats ImportStats)
+}
+
+// ImportStats представляет ход импорта
+type ImportStats struct {
+	Read     int `json:"read"`
+	Skipped  int `json:"skipped"`
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+	// CommittedLine - строка, до которой включительно все записи обработаны;
+	// с нее можно продолжить прерванный импорт
+	CommittedLine int           `json:"committed_line"`
+	Elapsed       time.Duration `json:"elapsed"`
+}
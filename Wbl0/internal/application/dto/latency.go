@@ -0,0 +1,13 @@
+package dto
+
+// LatencyStats представляет распределение задержки по последним замерам, в миллисекундах
+type LatencyStats struct {
+	// Count - число замеров с момента запуска
+	Count int64 `json:"count"`
+	// Samples - число последних замеров, по которым посчитаны перцентили
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
@@ -0,0 +1,44 @@
+package dto
+
+import "WbServis/Wbl0/internal/domain/entities"
+
+// LookupField - поле заказа, по которому ищется заказ помимо order_uid
+type LookupField string
+
+// Поля поиска заказов
+const (
+	LookupTrackNumber LookupField = "track_number"
+	LookupTransaction LookupField = "transaction"
+	LookupRid         LookupField = "rid"
+	LookupPhone       LookupField = "phone"
+	LookupEmail       LookupField = "email"
+)
+
+// LookupFields перечисляет все поля поиска
+var LookupFields = []LookupField{LookupTrackNumber, LookupTransaction, LookupRid, LookupPhone, LookupEmail}
+
+// LookupValues возвращает значения поля в заказе. У RID их столько же, сколько товаров.
+func LookupValues(order *entities.Order, field LookupField) []string {
+	switch field {
+	case LookupTrackNumber:
+		return []string{order.TrackNumber}
+	case LookupTransaction:
+		return []string{order.Payment.Transaction}
+	case LookupRid:
+		values := make([]string, 0, len(order.Items))
+		for _, item := range order.Items {
+			values = append(values, item.Rid)
+		}
+		return values
+	case LookupPhone:
+		return []string{order.Delivery.Phone}
+	case LookupEmail:
+		return []string{order.Delivery.Email}
+	}
+	return nil
+}
+
+// OrdersResponse представляет ответ API со списком заказов
+type OrdersResponse struct {
+	Orders []*entities.Order `json:"orders"`
+}
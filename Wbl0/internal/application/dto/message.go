@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// Message представляет сообщение брокера вместе с метаданными
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// ProducedAtHeader - заголовок с временем отправки сообщения в формате RFC 3339
+// с наносекундами. Выставляется отправителем для замера задержки обработки.
+const ProducedAtHeader = "produced-at"
+
+// ProducedAt возвращает время отправки сообщения из заголовка produced-at,
+// а без него - время сообщения в брокере. false - время неизвестно: заголовка
+// нет, а брокер время не передал (сообщения Kafka до 0.10 приходят без него
+// или с -1), и задержку по такому сообщению замерять нельзя.
+func (m *Message) ProducedAt() (time.Time, bool) {
+	if value := m.Headers[ProducedAtHeader]; value != "" {
+		if producedAt, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return producedAt, true
+		}
+	}
+	if m.Timestamp.UnixMilli() <= 0 {
+		return time.Time{}, false
+	}
+	return m.Timestamp, true
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "WbServis/Wbl0/internal/domain/entities"
+import (
+	"time"
+
+	"WbServis/Wbl0/internal/domain/entities"
+)
 
 // OrderResponse представляет ответ API для заказа
 type OrderResponse struct {
@@ -16,3 +20,11 @@ type ErrorResponse struct {
 type OrderRequest struct {
 	Order *entities.Order `json:"order"`
 }
+
+// CacheSnapshot представляет снимок кэша заказов
+type CacheSnapshot struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Watermark - время изменения по часам БД, до которого снимок содержит все изменения
+	Watermark time.Time         `json:"watermark"`
+	Orders    []*entities.Order `json:"orders"`
+}
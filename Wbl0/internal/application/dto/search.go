@@ -0,0 +1,29 @@
+package dto
+
+import "WbServis/Wbl0/internal/domain/entities"
+
+// OrderSearchResult представляет найденный заказ с его релевантностью
+type OrderSearchResult struct {
+	Rank  float64         `json:"rank"`
+	Order *entities.Order `json:"order"`
+}
+
+// OrderSearchResponse представляет страницу результатов поиска заказов
+type OrderSearchResponse struct {
+	Query   string              `json:"query"`
+	Total   int                 `json:"total"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+	Results []OrderSearchResult `json:"results"`
+	// EncryptedOrders перечисляет поля, по которым зашифрованные заказы
+	// не находятся или находятся только при точном совпадении
+	EncryptedOrders *SearchCoverage `json:"encrypted_orders,omitempty"`
+}
+
+// SearchCoverage описывает ограничения поиска по зашифрованным данным получателя
+type SearchCoverage struct {
+	// NotSearched - поля, которые у зашифрованных заказов не ищутся
+	NotSearched []string `json:"not_searched"`
+	// ExactOnly - поля, которые у зашифрованных заказов совпадают только точно
+	ExactOnly []string `json:"exact_only"`
+}
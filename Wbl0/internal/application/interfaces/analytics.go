@@ -0,0 +1,40 @@
+package interfaces
+
+import "WbServis/Wbl0/internal/application/dto"
+
+// AnalyticsRepository определяет аналитические запросы к заказам.
+// Все методы учитывают только заказы, попадающие под фильтр.
+type AnalyticsRepository interface {
+	// Revenue возвращает выручку (payment.amount) по периодам и валютам
+	Revenue(filter dto.AnalyticsFilter, interval dto.RevenueInterval) ([]dto.RevenuePoint, error)
+
+	// TopBrands возвращает limit брендов с наибольшим количеством проданных товаров или выручкой
+	TopBrands(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) ([]dto.TopEntry, error)
+
+	// TopProducts возвращает limit товаров (nm_id) с наибольшим количеством или выручкой
+	TopProducts(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) ([]dto.TopEntry, error)
+
+	// Basket возвращает средний размер корзины по валютам
+	Basket(filter dto.AnalyticsFilter) ([]dto.BasketStats, error)
+
+	// Breakdown возвращает число заказов и выручку по значениям поля
+	Breakdown(filter dto.AnalyticsFilter, dimension dto.BreakdownDimension) ([]dto.BreakdownEntry, error)
+
+	// Discount возвращает статистику скидок по товарам
+	Discount(filter dto.AnalyticsFilter) (*dto.DiscountStats, error)
+}
+
+// AnalyticsService определяет интерфейс аналитики заказов с кэшированием результатов
+type AnalyticsService interface {
+	Revenue(filter dto.AnalyticsFilter, interval dto.RevenueInterval) (*dto.AnalyticsResponse, error)
+
+	TopBrands(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) (*dto.AnalyticsResponse, error)
+
+	TopProducts(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) (*dto.AnalyticsResponse, error)
+
+	Basket(filter dto.AnalyticsFilter) (*dto.AnalyticsResponse, error)
+
+	Breakdown(filter dto.AnalyticsFilter, dimension dto.BreakdownDimension) (*dto.AnalyticsResponse, error)
+
+	Discount(filter dto.AnalyticsFilter) (*dto.AnalyticsResponse, error)
+}
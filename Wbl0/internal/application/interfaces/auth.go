@@ -0,0 +1,12 @@
+package interfaces
+
+import "WbServis/Wbl0/internal/application/dto"
+
+// Authenticator определяет проверку учетных данных клиентов HTTP API
+type Authenticator interface {
+	// AuthenticateAPIKey проверяет статический API-ключ
+	AuthenticateAPIKey(key string) (*dto.Principal, error)
+
+	// AuthenticateToken проверяет подпись и срок действия JWT
+	AuthenticateToken(token string) (*dto.Principal, error)
+}
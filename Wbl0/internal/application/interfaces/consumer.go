@@ -1,10 +1,82 @@
 package interfaces
 
-// MessageConsumer определяет интерфейс для потребления сообщений из Kafka
+import (
+	"context"
+	"fmt"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// MessageConsumer определяет интерфейс для потребления сообщений из брокера
 type MessageConsumer interface {
 	Start() error
 
-	Stop() error
+	// Stop прекращает получение сообщений и дожидается обработки уже полученных.
+	// По истечении ctx необработанные сообщения бросаются и будут доставлены повторно.
+	Stop(ctx context.Context) error
 
+	// Close фиксирует подтвержденные сообщения в брокере и освобождает соединения
 	Close() error
 }
+
+// MessageHandler определяет интерфейс обработчика сообщений брокера.
+// Пачка подтверждается только после успешного возврата или, если настроен
+// топик недоставленных сообщений, после публикации в него.
+type MessageHandler interface {
+	HandleMessages(messages []*dto.Message) error
+}
+
+// InvalidMessagesError возвращается обработчиком, если часть сообщений пачки
+// невалидна (не декодируется, нет order_uid). Остальные сообщения обработаны,
+// а невалидные повтор не исправит: их нужно отправить в топик недоставленных
+// сообщений, не повторяя пачку.
+type InvalidMessagesError struct {
+	Messages []*dto.Message
+	// Errors - причина для каждого сообщения из Messages
+	Errors []error
+}
+
+// Add добавляет невалидное сообщение и причину
+func (e *InvalidMessagesError) Add(message *dto.Message, err error) {
+	e.Messages = append(e.Messages, message)
+	e.Errors = append(e.Errors, err)
+}
+
+func (e *InvalidMessagesError) Error() string {
+	if len(e.Messages) == 1 {
+		return fmt.Sprintf("invalid message: %v", e.Errors[0])
+	}
+	return fmt.Sprintf("%d invalid messages, first: %v", len(e.Messages), e.Errors[0])
+}
+
+// MessagePublisher определяет интерфейс публикации сообщений в брокер
+type MessagePublisher interface {
+	Publish(message *dto.Message) error
+}
+
+// ConsumerAdmin определяет операции управления потреблением и офсетами группы.
+// Реализуется потребителями, которые поддерживают такие операции.
+type ConsumerAdmin interface {
+	// Offsets возвращает закоммиченные офсеты группы и границы партиций
+	Offsets() ([]dto.PartitionOffsets, error)
+
+	// ResetOffsets переводит офсеты группы на заданную позицию и сообщает,
+	// к каким партициям сброс применен
+	ResetOffsets(request dto.OffsetResetRequest) ([]dto.OffsetTarget, error)
+
+	// Pause приостанавливает получение сообщений
+	Pause()
+
+	// Resume возобновляет получение сообщений
+	Resume()
+
+	// Paused сообщает, приостановлен ли потребитель
+	Paused() bool
+}
+
+// LagReporter определяет получение отставания потребителя от конца партиций.
+// Реализуется потребителями, которые отслеживают отставание.
+type LagReporter interface {
+	// Lag возвращает последний замер отставания
+	Lag() dto.ConsumerLag
+}
@@ -0,0 +1,51 @@
+package interfaces
+
+import "WbServis/Wbl0/internal/application/dto"
+
+// DataKeyRepository определяет хранение ключей данных, зашифрованных мастер-ключом
+type DataKeyRepository interface {
+	// GetAll возвращает все ключи по порядку ID
+	GetAll() ([]dto.DataKey, error)
+
+	// GetByID возвращает ключ или nil, если его нет
+	GetByID(id int) (*dto.DataKey, error)
+
+	// Create сохраняет ключ и возвращает его ID. Ключ слепого индекса может быть
+	// только один: если его уже создал другой экземпляр, возвращается 0.
+	Create(key dto.DataKey) (int, error)
+
+	// UpdateWrapped заменяет зашифрованные ключи и отпечатки мастер-ключа одной транзакцией
+	UpdateWrapped(keys []dto.DataKey) error
+}
+
+// PIICipher определяет шифрование данных получателя для хранения в БД
+// ключами данных из DataKeyRepository
+type PIICipher interface {
+	// ActiveKeyID возвращает ID ключа данных, которым шифруются новые записи
+	ActiveKeyID() int
+
+	// Encrypt шифрует значение ключом keyID. aad привязывает шифртекст к заказу
+	// и полю: перенесенный в другую строку или столбец, он не расшифруется.
+	Encrypt(keyID int, plaintext, aad string) (string, error)
+
+	// Decrypt расшифровывает значение. Ключи, созданные после запуска
+	// (например, при ротации другим процессом), загружаются из БД.
+	Decrypt(keyID int, ciphertext, aad string) (string, error)
+
+	// BlindIndex возвращает HMAC нормализованного значения поля phone или email
+	// для точного поиска или пустую строку, если значение пустое
+	BlindIndex(field, value string) string
+
+	// Rotate создает новый ключ данных и делает его активным
+	Rotate() (int, error)
+}
+
+// DeliveryEncryptor определяет перешифрование уже сохраненных данных получателя
+type DeliveryEncryptor interface {
+	// EncryptPending шифрует активным ключом строки, сохраненные открытым текстом
+	// или другим ключом, пачками по batchSize и возвращает число перешифрованных строк
+	EncryptPending(batchSize int) (int, error)
+
+	// CountPlaintext возвращает число строк, сохраненных открытым текстом
+	CountPlaintext() (int, error)
+}
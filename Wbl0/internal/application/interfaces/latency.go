@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// LatencyRecorder определяет накопление замеров задержки
+type LatencyRecorder interface {
+	Observe(latency time.Duration)
+
+	// Stats возвращает перцентили по последним замерам
+	Stats() dto.LatencyStats
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+// MessageValidator определяет интерфейс проверки сообщений по версионированной схеме
+type MessageValidator interface {
+	// Validate проверяет сообщение по версии схемы и возвращает найденные расхождения
+	Validate(version string, payload []byte) ([]string, error)
+
+	// Schema возвращает опубликованную схему указанной версии
+	Schema(version string) ([]byte, error)
+}
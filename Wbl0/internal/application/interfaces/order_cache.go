@@ -0,0 +1,67 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// OrderCache определяет кэш заказов. Реализации не возвращают ошибок:
+// недоступный кэш ведет себя как промах, и заказ читается из БД.
+type OrderCache interface {
+	// Get возвращает заказ из кэша
+	Get(orderUID string) (*entities.Order, bool)
+
+	// Lookup возвращает закэшированные заказы с указанным значением поля.
+	// false означает, что кэш не знает полного набора и заказы нужно искать в БД.
+	Lookup(field dto.LookupField, value string) ([]*entities.Order, bool)
+
+	// SetLookup сохраняет заказы, найденные в БД по значению поля, как полный
+	// набор для Lookup. Набор перестает считаться полным при вытеснении или
+	// удалении любого из его заказов.
+	SetLookup(field dto.LookupField, value string, orders []*entities.Order)
+
+	// Set добавляет или обновляет заказы
+	Set(orders ...*entities.Order)
+
+	// Delete удаляет заказы из кэша
+	Delete(orderUIDs ...string)
+
+	// DeleteLocal удаляет заказы только из кэша в памяти процесса. Общий кэш
+	// обновляет тот, кто изменил заказ, поэтому другие экземпляры, узнавшие
+	// об изменении, его не трогают.
+	DeleteLocal(orderUIDs ...string)
+
+	// Clear очищает кэш
+	Clear()
+
+	// Len возвращает количество заказов в кэше
+	Len() int
+
+	// Orders возвращает заказы локального кэша. Общие кэши возвращают nil.
+	Orders() []*entities.Order
+}
+
+// CacheSnapshotStore определяет хранилище снимка кэша
+type CacheSnapshotStore interface {
+	// Save атомарно записывает снимок
+	Save(snapshot *dto.CacheSnapshot) error
+
+	// Load читает снимок. Поврежденный снимок возвращает ошибку.
+	Load() (*dto.CacheSnapshot, error)
+}
+
+// CacheSnapshotter определяет сохранение кэша в снимок и быстрое восстановление из него
+type CacheSnapshotter interface {
+	// Restore загружает снимок и догружает заказы, измененные после него.
+	// Возвращает ошибку, если снимок отсутствует, поврежден или устарел.
+	Restore() error
+
+	// Save сохраняет снимок текущего кэша
+	Save() error
+
+	// Run периодически сохраняет снимок до отмены ctx
+	Run(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+// OrderCacheInvalidator определяет вытеснение заказов из кэша
+type OrderCacheInvalidator interface {
+	// EvictOrders удаляет заказы из кэша, следующее чтение загрузит их из БД
+	EvictOrders(orderUIDs []string)
+
+	// EvictAll очищает кэш целиком
+	EvictAll()
+}
+
+// OrderChangeListener определяет подписку на изменения заказов другими экземплярами сервиса
+type OrderChangeListener interface {
+	// Listen начинает вытеснять измененные заказы из кэша
+	Listen(cache OrderCacheInvalidator)
+
+	Close() error
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// OrderDecoder определяет интерфейс разбора заказа из сообщения брокера
+type OrderDecoder interface {
+	Decode(message *dto.Message) (*entities.Order, error)
+}
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// OrderWriter записывает заказы в поток в одном из форматов выгрузки
+type OrderWriter interface {
+	Write(order *entities.Order) error
+
+	// Close дописывает окончание файла (например, футер Parquet). Сам поток не закрывает.
+	Close() error
+}
+
+// OrderExporter определяет выгрузку заказов
+type OrderExporter interface {
+	// Export передает отобранные заказы в writer по порядку даты создания
+	// и возвращает число выгруженных заказов. Память не зависит от размера выборки.
+	Export(filter dto.ExportFilter, writer OrderWriter) (int, error)
+}
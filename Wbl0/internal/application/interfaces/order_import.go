@@ -0,0 +1,17 @@
+package interfaces
+
+import "WbServis/Wbl0/internal/application/dto"
+
+// OrderRecordReader читает записи заказов из файла импорта
+type OrderRecordReader interface {
+	// Next возвращает следующую запись или io.EOF в конце файла.
+	// Ошибка разбора отдельной записи возвращается в ImportRecord.Err,
+	// ошибка результата означает, что дальше читать нельзя.
+	Next() (*dto.ImportRecord, error)
+}
+
+// OrderImporter определяет загрузку заказов в обход брокера сообщений
+type OrderImporter interface {
+	// Import проверяет записи по схеме заказа и сохраняет валидные пачками
+	Import(reader OrderRecordReader, options dto.ImportOptions) (*dto.ImportStats, error)
+}
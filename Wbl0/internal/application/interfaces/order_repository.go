@@ -1,14 +1,56 @@
 package interfaces
 
-import "WbServis/Wbl0/internal/domain/entities"
+import (
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
 
 // OrderRepository определяет интерфейс для работы с заказами в базе данных
 type OrderRepository interface {
 	Save(order *entities.Order) error
 
+	SaveBatch(orders []*entities.Order) error
+
 	GetByID(orderUID string) (*entities.Order, error)
 
 	GetAll() ([]*entities.Order, error)
 
+	// GetUpdatedSince возвращает заказы, измененные позже since
+	GetUpdatedSince(since time.Time) ([]*entities.Order, error)
+
+	// FindBy возвращает заказы с указанным значением поля
+	FindBy(field dto.LookupField, value string) ([]*entities.Order, error)
+
+	// Search ищет заказы по имени, адресу и городу получателя, названию и бренду
+	// товаров (полнотекстово), а также по телефону и email (нечетко). У строк
+	// с зашифрованными данными получателя ищутся только город и точные телефон и email.
+	// Возвращает страницу результатов по убыванию релевантности и общее число найденных.
+	Search(query string, limit, offset int) ([]dto.OrderSearchResult, int, error)
+
+	// SearchCoverage возвращает поля, которые Search не охватывает у зашифрованных
+	// заказов, или nil, если шифрование не настроено
+	SearchCoverage() *dto.SearchCoverage
+
+	// GetCustomerOrders возвращает страницу кратких сведений о заказах покупателя,
+	// от новых к старым, без загрузки заказов целиком
+	GetCustomerOrders(customerID string, limit, offset int) ([]dto.CustomerOrderSummary, error)
+
+	// GetCustomerStats возвращает сводку по всем заказам покупателя
+	GetCustomerStats(customerID string) (*dto.CustomerOrderStats, error)
+
+	// Stream передает заказы, отобранные фильтром, в fn порциями по мере чтения из БД,
+	// по порядку даты создания. Ошибка fn прерывает чтение.
+	Stream(filter dto.ExportFilter, fn func(order *entities.Order) error) error
+
+	// LatestUpdate возвращает время последнего изменения заказов по часам БД
+	LatestUpdate() (time.Time, error)
+
+	// OldestOpenTransaction возвращает время начала самой старой открытой транзакции
+	// БД по часам БД: изменения, еще не видимые другим сессиям, получат updated_at
+	// не раньше этого времени
+	OldestOpenTransaction() (time.Time, error)
+
 	Close() error
 }
@@ -1,20 +1,48 @@
 package interfaces
 
-import "WbServis/Wbl0/internal/domain/entities"
+import (
+	"errors"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// ErrOrderNotFound возвращается, если заказа нет ни в кэше, ни в БД
+var ErrOrderNotFound = errors.New("order not found")
 
 // OrderService определяет интерфейс для бизнес-логики работы с заказами
 type OrderService interface {
 	// ProcessOrder обрабатывает новый заказ (сохраняет в БД и кэш)
 	ProcessOrder(order *entities.Order) error
 
-	// GetOrderByID получает заказ по ID (сначала из кэша, затем из БД)
+	// GetOrderByID получает заказ по ID (сначала из кэша, затем из БД).
+	// Для неизвестного ID возвращает ErrOrderNotFound.
 	GetOrderByID(orderUID string) (*entities.Order, error)
 
+	// FindOrders ищет заказы по трек-номеру, транзакции или RID товара (сначала в кэше, затем в БД)
+	FindOrders(field dto.LookupField, value string) ([]*entities.Order, error)
+
+	// GetCustomerOrders возвращает страницу заказов покупателя со сводкой по всем его заказам
+	GetCustomerOrders(customerID string, limit, offset int) (*dto.CustomerOrdersResponse, error)
+
+	// SearchOrders ищет заказы по данным получателя и товаров
+	SearchOrders(query string, limit, offset int) (*dto.OrderSearchResponse, error)
+
 	// RestoreCache восстанавливает кэш из базы данных при запуске
 	RestoreCache() error
 
+	// ProcessOrders сохраняет пачку заказов одной транзакцией и обновляет кэш
+	ProcessOrders(orders []*entities.Order) error
+
 	// ProcessMessage обрабатывает сообщение из Kafka
-	ProcessMessage(message []byte) error
+	ProcessMessage(message *dto.Message) error
+
+	// ProcessMessageBatch обрабатывает пачку сообщений из Kafka. Если есть невалидные
+	// сообщения, валидные сохраняются, а невалидные возвращаются в *InvalidMessagesError.
+	ProcessMessageBatch(messages []*dto.Message) error
+
+	// OrderCacheInvalidator вытесняет заказы, измененные другими экземплярами
+	OrderCacheInvalidator
 
 	// Close закрывает сервис
 	Close() error
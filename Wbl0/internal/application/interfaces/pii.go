@@ -0,0 +1,16 @@
+package interfaces
+
+import "WbServis/Wbl0/internal/domain/entities"
+
+// PIIMasker определяет маскирование персональных данных получателя
+type PIIMasker interface {
+	// MaskOrder возвращает копию заказа с замаскированными полями доставки,
+	// исходный заказ (например, из кэша) не меняется
+	MaskOrder(order *entities.Order) *entities.Order
+
+	// MaskField маскирует значение поля доставки по имени поля в JSON
+	MaskField(field, value string) string
+
+	// MaskText маскирует телефоны и email в произвольном тексте, например в строке лога
+	MaskText(text string) string
+}
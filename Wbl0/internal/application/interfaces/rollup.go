@@ -0,0 +1,30 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// RollupRepository определяет работу с дневными сводками заказов.
+// Дни, требующие пересчета, отмечаются триггерами БД при любых изменениях заказов.
+type RollupRepository interface {
+	// RefreshDirty пересчитывает сводки не более чем по limit отметкам
+	// и возвращает число обработанных отметок
+	RefreshDirty(limit int) (int, error)
+
+	// MarkDays отмечает для пересчета дни периода [from, to), в которых есть заказы
+	// или сводки. Нулевая граница означает отсутствие ограничения.
+	MarkDays(from, to time.Time) (int, error)
+}
+
+// RollupService определяет обновление дневных сводок для аналитики
+type RollupService interface {
+	// Refresh пересчитывает все отмеченные дни
+	Refresh() error
+
+	// Rebuild пересчитывает сводки за период [from, to) по исходным таблицам
+	Rebuild(from, to time.Time) error
+
+	// Run периодически вызывает Refresh до отмены ctx
+	Run(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// maxAnalyticsCacheEntries ограничивает число закэшированных результатов:
+// фильтры приходят от пользователей, и их сочетаний может быть много
+const maxAnalyticsCacheEntries = 1000
+
+type analyticsEntry struct {
+	response  *dto.AnalyticsResponse
+	expiresAt time.Time
+}
+
+type analyticsService struct {
+	repository interfaces.AnalyticsRepository
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	results map[string]analyticsEntry
+}
+
+// NewAnalyticsService создает сервис аналитики. Результаты запросов кэшируются на ttl,
+// ttl <= 0 отключает кэширование.
+func NewAnalyticsService(repository interfaces.AnalyticsRepository, ttl time.Duration) interfaces.AnalyticsService {
+	return &analyticsService{
+		repository: repository,
+		ttl:        ttl,
+		results:    make(map[string]analyticsEntry),
+	}
+}
+
+func (s *analyticsService) Revenue(filter dto.AnalyticsFilter, interval dto.RevenueInterval) (*dto.AnalyticsResponse, error) {
+	return s.cached(fmt.Sprintf("revenue|%s", interval), filter, func() (interface{}, error) {
+		return s.repository.Revenue(filter, interval)
+	})
+}
+
+func (s *analyticsService) TopBrands(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) (*dto.AnalyticsResponse, error) {
+	return s.cached(fmt.Sprintf("brands|%s|%d", metric, limit), filter, func() (interface{}, error) {
+		return s.repository.TopBrands(filter, metric, limit)
+	})
+}
+
+func (s *analyticsService) TopProducts(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) (*dto.AnalyticsResponse, error) {
+	return s.cached(fmt.Sprintf("products|%s|%d", metric, limit), filter, func() (interface{}, error) {
+		return s.repository.TopProducts(filter, metric, limit)
+	})
+}
+
+func (s *analyticsService) Basket(filter dto.AnalyticsFilter) (*dto.AnalyticsResponse, error) {
+	return s.cached("basket", filter, func() (interface{}, error) {
+		return s.repository.Basket(filter)
+	})
+}
+
+func (s *analyticsService) Breakdown(filter dto.AnalyticsFilter, dimension dto.BreakdownDimension) (*dto.AnalyticsResponse, error) {
+	return s.cached(fmt.Sprintf("breakdown|%s", dimension), filter, func() (interface{}, error) {
+		return s.repository.Breakdown(filter, dimension)
+	})
+}
+
+func (s *analyticsService) Discount(filter dto.AnalyticsFilter) (*dto.AnalyticsResponse, error) {
+	return s.cached("discount", filter, func() (interface{}, error) {
+		return s.repository.Discount(filter)
+	})
+}
+
+// cached возвращает результат запроса из кэша или выполняет query и кэширует его на ttl
+func (s *analyticsService) cached(name string, filter dto.AnalyticsFilter, query func() (interface{}, error)) (*dto.AnalyticsResponse, error) {
+	key := name + "|" + filter.Key()
+	now := time.Now()
+
+	if s.ttl > 0 {
+		s.mu.Lock()
+		entry, ok := s.results[key]
+		s.mu.Unlock()
+		if ok && now.Before(entry.expiresAt) {
+			return entry.response, nil
+		}
+	}
+
+	result, err := query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute %s analytics: %w", name, err)
+	}
+	response := &dto.AnalyticsResponse{Filter: filter, Result: result}
+
+	if s.ttl > 0 {
+		s.mu.Lock()
+		if len(s.results) >= maxAnalyticsCacheEntries {
+			s.evictExpired(now)
+		}
+		if len(s.results) < maxAnalyticsCacheEntries {
+			s.results[key] = analyticsEntry{response: response, expiresAt: now.Add(s.ttl)}
+		}
+		s.mu.Unlock()
+	}
+
+	return response, nil
+}
+
+// evictExpired удаляет устаревшие результаты. Вызывается под s.mu.
+func (s *analyticsService) evictExpired(now time.Time) {
+	for key, entry := range s.results {
+		if !now.Before(entry.expiresAt) {
+			delete(s.results, key)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// snapshotWatermarkMargin сдвигает отметку снимка назад на время между коммитом
+// заказа и его записью в кэш
+const snapshotWatermarkMargin = time.Minute
+
+type cacheSnapshotter struct {
+	repository interfaces.OrderRepository
+	cache      interfaces.OrderCache
+	store      interfaces.CacheSnapshotStore
+	maxAge     time.Duration
+}
+
+// NewCacheSnapshotter создает компонент снимков кэша. Снимок старше maxAge
+// считается устаревшим; 0 отключает проверку возраста.
+func NewCacheSnapshotter(repository interfaces.OrderRepository, cache interfaces.OrderCache, store interfaces.CacheSnapshotStore, maxAge time.Duration) interfaces.CacheSnapshotter {
+	return &cacheSnapshotter{
+		repository: repository,
+		cache:      cache,
+		store:      store,
+		maxAge:     maxAge,
+	}
+}
+
+func (s *cacheSnapshotter) Restore() error {
+	started := time.Now()
+
+	snapshot, err := s.store.Load()
+	if err != nil {
+		return err
+	}
+
+	if s.maxAge > 0 && time.Since(snapshot.CreatedAt) > s.maxAge {
+		return fmt.Errorf("snapshot is stale: created at %s", snapshot.CreatedAt.Format(time.RFC3339))
+	}
+
+	latest, err := s.repository.LatestUpdate()
+	if err != nil {
+		return err
+	}
+	// База старее снимка - например, восстановлена из резервной копии
+	if snapshot.Watermark.After(latest) {
+		return fmt.Errorf("snapshot watermark %s is ahead of database %s",
+			snapshot.Watermark.Format(time.RFC3339), latest.Format(time.RFC3339))
+	}
+
+	updated, err := s.repository.GetUpdatedSince(snapshot.Watermark)
+	if err != nil {
+		return fmt.Errorf("failed to catch up after snapshot: %w", err)
+	}
+
+	s.cache.Clear()
+	s.cache.Set(snapshot.Orders...)
+	s.cache.Set(updated...)
+
+	log.Printf("Cache restored from snapshot with %d orders and %d updated since %s in %s",
+		len(snapshot.Orders), len(updated), snapshot.Watermark.Format(time.RFC3339),
+		time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+func (s *cacheSnapshotter) Save() error {
+	// Отметку снимаем до чтения кэша: все, что изменится позже, догрузится по updated_at.
+	// updated_at равен времени начала транзакции, поэтому отметка не позже начала
+	// самой старой открытой транзакции, сколько бы она ни длилась, и не позже
+	// последнего изменения, чтобы при загрузке отметка не оказалась новее базы.
+	latest, err := s.repository.LatestUpdate()
+	if err != nil {
+		return err
+	}
+	oldest, err := s.repository.OldestOpenTransaction()
+	if err != nil {
+		return err
+	}
+	watermark := latest
+	if oldest.Before(watermark) {
+		watermark = oldest
+	}
+
+	orders := s.cache.Orders()
+	err = s.store.Save(&dto.CacheSnapshot{
+		CreatedAt: time.Now(),
+		Watermark: watermark.Add(-snapshotWatermarkMargin),
+		Orders:    orders,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Cache snapshot saved with %d orders", len(orders))
+	return nil
+}
+
+func (s *cacheSnapshotter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				log.Printf("Failed to save cache snapshot: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/cache"
+)
+
+// snapshotRepository отдает заданные часы БД и заказы, измененные после отметки
+type snapshotRepository struct {
+	interfaces.OrderRepository
+	latest  time.Time
+	oldest  time.Time
+	updated []*entities.Order
+	since   time.Time
+}
+
+func (r *snapshotRepository) LatestUpdate() (time.Time, error) {
+	return r.latest, nil
+}
+
+func (r *snapshotRepository) OldestOpenTransaction() (time.Time, error) {
+	return r.oldest, nil
+}
+
+func (r *snapshotRepository) GetUpdatedSince(since time.Time) ([]*entities.Order, error) {
+	r.since = since
+	return r.updated, nil
+}
+
+// memorySnapshotStore хранит последний снимок в памяти
+type memorySnapshotStore struct {
+	snapshot *dto.CacheSnapshot
+}
+
+func (s *memorySnapshotStore) Save(snapshot *dto.CacheSnapshot) error {
+	s.snapshot = snapshot
+	return nil
+}
+
+func (s *memorySnapshotStore) Load() (*dto.CacheSnapshot, error) {
+	if s.snapshot == nil {
+		return nil, cache.ErrNoSnapshot
+	}
+	return s.snapshot, nil
+}
+
+func TestSnapshotWatermark(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		latest time.Time
+		oldest time.Time
+		want   time.Time
+	}{
+		// Транзакция, начатая 10 минут назад, еще не закоммичена
+		{"long transaction", now, now.Add(-10 * time.Minute), now.Add(-11 * time.Minute)},
+		{"no open transactions", now.Add(-time.Hour), now, now.Add(-time.Hour - time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memorySnapshotStore{}
+			repository := &snapshotRepository{latest: tt.latest, oldest: tt.oldest}
+			snapshotter := NewCacheSnapshotter(repository, cache.NewLRUCache(10), store, 0)
+
+			if err := snapshotter.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if !store.snapshot.Watermark.Equal(tt.want) {
+				t.Errorf("watermark = %s, want %s", store.snapshot.Watermark, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotRestoreCatchesUp(t *testing.T) {
+	now := time.Now().UTC()
+	orderCache := cache.NewLRUCache(10)
+	orderCache.Set(&entities.Order{OrderUID: "a", TrackNumber: "old"})
+	store := &memorySnapshotStore{}
+	repository := &snapshotRepository{latest: now, oldest: now}
+
+	if err := NewCacheSnapshotter(repository, orderCache, store, time.Hour).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// После снимка заказ a изменился, появился заказ b
+	repository.updated = []*entities.Order{{OrderUID: "a", TrackNumber: "new"}, {OrderUID: "b"}}
+	restored := cache.NewLRUCache(10)
+	if err := NewCacheSnapshotter(repository, restored, store, time.Hour).Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if !repository.since.Equal(store.snapshot.Watermark) {
+		t.Errorf("caught up since %s, want watermark %s", repository.since, store.snapshot.Watermark)
+	}
+	if order, ok := restored.Get("a"); !ok || order.TrackNumber != "new" {
+		t.Errorf("Get(a) = %+v, %v, want updated order", order, ok)
+	}
+	if _, ok := restored.Get("b"); !ok {
+		t.Error("order created after the snapshot was not loaded")
+	}
+}
+
+func TestSnapshotRestoreRejects(t *testing.T) {
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name     string
+		snapshot *dto.CacheSnapshot
+		latest   time.Time
+		wantErr  string
+	}{
+		{
+			name:     "older than max age",
+			snapshot: &dto.CacheSnapshot{CreatedAt: now.Add(-2 * time.Hour), Watermark: now.Add(-2 * time.Hour)},
+			latest:   now,
+			wantErr:  "stale",
+		},
+		{
+			// База восстановлена из резервной копии старше снимка
+			name:     "watermark ahead of database",
+			snapshot: &dto.CacheSnapshot{CreatedAt: now, Watermark: now},
+			latest:   now.Add(-time.Hour),
+			wantErr:  "ahead of database",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.snapshot.Orders = []*entities.Order{{OrderUID: "a"}}
+			orderCache := cache.NewLRUCache(10)
+			snapshotter := NewCacheSnapshotter(
+				&snapshotRepository{latest: tt.latest, oldest: tt.latest},
+				orderCache,
+				&memorySnapshotStore{snapshot: tt.snapshot},
+				time.Hour,
+			)
+
+			err := snapshotter.Restore()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Restore() error = %v, want %q", err, tt.wantErr)
+			}
+			if orderCache.Len() != 0 {
+				t.Errorf("cache has %d orders from a rejected snapshot", orderCache.Len())
+			}
+		})
+	}
+}
+
+func TestSnapshotRestoreIgnoresCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	store := cache.NewFileSnapshotStore(path, nil)
+	now := time.Now().UTC()
+	repository := &snapshotRepository{latest: now, oldest: now}
+
+	orderCache := cache.NewLRUCache(10)
+	orderCache.Set(&entities.Order{OrderUID: "a"})
+	if err := NewCacheSnapshotter(repository, orderCache, store, time.Hour).Save(); err != nil {
+		t.Fatal(err)
+	}
+	// Порча последнего байта сжатых данных
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := cache.NewLRUCache(10)
+	if err := NewCacheSnapshotter(repository, restored, store, time.Hour).Restore(); err == nil {
+		t.Fatal("corrupt snapshot was restored")
+	}
+	if restored.Len() != 0 {
+		t.Errorf("cache has %d orders from a corrupt snapshot", restored.Len())
+	}
+}
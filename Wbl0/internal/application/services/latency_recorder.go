@@ -0,0 +1,72 @@
+package services
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+type latencyRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	count   int64
+}
+
+// NewLatencyRecorder создает накопитель задержек, считающий перцентили
+// по последним window замерам
+func NewLatencyRecorder(window int) interfaces.LatencyRecorder {
+	if window <= 0 {
+		window = 1
+	}
+	return &latencyRecorder{samples: make([]time.Duration, 0, window)}
+}
+
+func (r *latencyRecorder) Observe(latency time.Duration) {
+	// При расхождении часов отправителя и сервиса задержка может оказаться отрицательной
+	latency = max(latency, 0)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count++
+	if len(r.samples) < cap(r.samples) {
+		r.samples = append(r.samples, latency)
+		return
+	}
+	r.samples[r.next] = latency
+	r.next = (r.next + 1) % len(r.samples)
+}
+
+func (r *latencyRecorder) Stats() dto.LatencyStats {
+	r.mu.Lock()
+	samples := slices.Clone(r.samples)
+	count := r.count
+	r.mu.Unlock()
+
+	stats := dto.LatencyStats{Count: count, Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	slices.Sort(samples)
+	stats.P50 = milliseconds(percentile(samples, 0.50))
+	stats.P95 = milliseconds(percentile(samples, 0.95))
+	stats.P99 = milliseconds(percentile(samples, 0.99))
+	stats.Max = milliseconds(samples[len(samples)-1])
+	return stats
+}
+
+// percentile возвращает перцентиль p отсортированных замеров методом ближайшего ранга
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
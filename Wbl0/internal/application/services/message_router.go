@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// messageRouter направляет сообщения обработчикам по топику
+type messageRouter struct {
+	routes   map[string]interfaces.MessageHandler
+	fallback interfaces.MessageHandler
+}
+
+// NewMessageRouter создает обработчик, передающий сообщения обработчику их топика.
+// Сообщения топиков без маршрута передаются fallback.
+func NewMessageRouter(routes map[string]interfaces.MessageHandler, fallback interfaces.MessageHandler) interfaces.MessageHandler {
+	return &messageRouter{
+		routes:   routes,
+		fallback: fallback,
+	}
+}
+
+func (r *messageRouter) HandleMessages(messages []*dto.Message) error {
+	// Пачка обычно состоит из сообщений одной партиции - проверяем это без аллокаций
+	if topic := messages[0].Topic; sameTopic(messages, topic) {
+		return r.handlerFor(topic).HandleMessages(messages)
+	}
+
+	var (
+		topics  []string
+		byTopic = make(map[string][]*dto.Message)
+	)
+	for _, message := range messages {
+		if _, ok := byTopic[message.Topic]; !ok {
+			topics = append(topics, message.Topic)
+		}
+		byTopic[message.Topic] = append(byTopic[message.Topic], message)
+	}
+
+	var (
+		errs    []error
+		invalid interfaces.InvalidMessagesError
+	)
+	for _, topic := range topics {
+		err := r.handlerFor(topic).HandleMessages(byTopic[topic])
+		// Невалидные сообщения разных топиков собираются в одну ошибку,
+		// чтобы процессор отправил в DeadLetter их все
+		var topicInvalid *interfaces.InvalidMessagesError
+		if errors.As(err, &topicInvalid) {
+			invalid.Messages = append(invalid.Messages, topicInvalid.Messages...)
+			invalid.Errors = append(invalid.Errors, topicInvalid.Errors...)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("topic %s: %w", topic, err))
+		}
+	}
+
+	// Пачка с ошибками обработки повторяется целиком, невалидные сообщения
+	// найдутся при повторе снова
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if len(invalid.Messages) > 0 {
+		return &invalid
+	}
+	return nil
+}
+
+func (r *messageRouter) handlerFor(topic string) interfaces.MessageHandler {
+	if handler, ok := r.routes[topic]; ok {
+		return handler
+	}
+	return r.fallback
+}
+
+func sameTopic(messages []*dto.Message, topic string) bool {
+	for _, message := range messages {
+		if message.Topic != topic {
+			return false
+		}
+	}
+	return true
+}
+
+// skipMessageHandler подтверждает сообщения без обработки
+type skipMessageHandler struct{}
+
+// NewSkipMessageHandler создает обработчик, который только журналирует и пропускает сообщения.
+// Нужен для топиков, попадающих под шаблон подписки, но не требующих обработки.
+func NewSkipMessageHandler() interfaces.MessageHandler {
+	return skipMessageHandler{}
+}
+
+func (skipMessageHandler) HandleMessages(messages []*dto.Message) error {
+	log.Printf("Skipping %d messages from %s", len(messages), messages[0].Topic)
+	return nil
+}
+
+// ParseTopicHandlers разбирает список вида "orders=order,order-status=skip"
+// в соответствие топика имени обработчика
+func ParseTopicHandlers(spec string) (map[string]string, error) {
+	handlers := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		topic, handler, ok := strings.Cut(pair, "=")
+		if !ok || topic == "" || handler == "" {
+			return nil, fmt.Errorf("invalid topic handler %q, expected topic=handler", pair)
+		}
+		handlers[strings.TrimSpace(topic)] = strings.TrimSpace(handler)
+	}
+	return handlers, nil
+}
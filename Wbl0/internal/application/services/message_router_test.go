@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// recordingHandler запоминает полученные сообщения и возвращает err
+type recordingHandler struct {
+	received []*dto.Message
+	err      func(messages []*dto.Message) error
+}
+
+func (h *recordingHandler) HandleMessages(messages []*dto.Message) error {
+	h.received = append(h.received, messages...)
+	if h.err != nil {
+		return h.err(messages)
+	}
+	return nil
+}
+
+// invalidFirst объявляет невалидным первое сообщение пачки
+func invalidFirst(messages []*dto.Message) error {
+	var invalid interfaces.InvalidMessagesError
+	invalid.Add(messages[0], errors.New("order_uid is required"))
+	return &invalid
+}
+
+// keys возвращает ключи сообщений
+func keys(messages []*dto.Message) []string {
+	var result []string
+	for _, message := range messages {
+		result = append(result, string(message.Key))
+	}
+	return result
+}
+
+func TestMessageRouterRoutesByTopic(t *testing.T) {
+	orders := &recordingHandler{}
+	status := &recordingHandler{}
+	fallback := &recordingHandler{}
+	router := NewMessageRouter(map[string]interfaces.MessageHandler{
+		"orders":       orders,
+		"order-status": status,
+	}, fallback)
+
+	// Пачка одного топика передается целиком
+	if err := router.HandleMessages([]*dto.Message{{Topic: "orders", Key: []byte("o1")}, {Topic: "orders", Key: []byte("o2")}}); err != nil {
+		t.Fatal(err)
+	}
+	// Смешанная пачка делится по топикам с сохранением порядка
+	err := router.HandleMessages([]*dto.Message{
+		{Topic: "order-status", Key: []byte("s1")},
+		{Topic: "orders", Key: []byte("o3")},
+		{Topic: "orders-eu", Key: []byte("e1")},
+		{Topic: "order-status", Key: []byte("s2")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range map[string]struct {
+		handler *recordingHandler
+		want    []string
+	}{
+		"orders":   {orders, []string{"o1", "o2", "o3"}},
+		"status":   {status, []string{"s1", "s2"}},
+		"fallback": {fallback, []string{"e1"}},
+	} {
+		if got := keys(tt.handler.received); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s handler received %v, want %v", name, got, tt.want)
+		}
+	}
+}
+
+func TestMessageRouterReportsFailedTopics(t *testing.T) {
+	failing := &recordingHandler{err: func([]*dto.Message) error { return errors.New("database is unavailable") }}
+	orders := &recordingHandler{}
+	router := NewMessageRouter(map[string]interfaces.MessageHandler{"orders": orders, "order-status": failing}, NewSkipMessageHandler())
+
+	err := router.HandleMessages([]*dto.Message{
+		{Topic: "orders", Key: []byte("o1")},
+		{Topic: "order-status", Key: []byte("s1")},
+		{Topic: "unrouted", Key: []byte("u1")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "topic order-status: database is unavailable") {
+		t.Errorf("HandleMessages() error = %v, want error of topic order-status", err)
+	}
+	// Остальные топики пачки обработаны
+	if len(orders.received) != 1 {
+		t.Errorf("orders handler received %d messages, want 1", len(orders.received))
+	}
+}
+
+func TestParseTopicHandlers(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{spec: "", want: map[string]string{}},
+		{spec: "orders=order", want: map[string]string{"orders": "order"}},
+		{spec: " orders = order , order-status=skip, ", want: map[string]string{"orders": "order", "order-status": "skip"}},
+		{spec: "orders", wantErr: true},
+		{spec: "=order", wantErr: true},
+		{spec: "orders=", wantErr: true},
+		{spec: "orders=order,status", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseTopicHandlers(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTopicHandlers(%q) = %v, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTopicHandlers(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageRouterMergesInvalidMessages(t *testing.T) {
+	orders := &recordingHandler{err: invalidFirst}
+	archive := &recordingHandler{err: invalidFirst}
+	router := NewMessageRouter(map[string]interfaces.MessageHandler{"orders": orders}, archive)
+
+	messages := []*dto.Message{
+		{Topic: "orders", Key: []byte("o1")},
+		{Topic: "orders-archive", Key: []byte("a1")},
+		{Topic: "orders", Key: []byte("o2")},
+	}
+	err := router.HandleMessages(messages)
+
+	var invalid *interfaces.InvalidMessagesError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("HandleMessages() error = %v, want InvalidMessagesError", err)
+	}
+	if len(invalid.Messages) != 2 || invalid.Messages[0] != messages[0] || invalid.Messages[1] != messages[1] {
+		t.Errorf("invalid messages = %v, want o1 and a1", invalid.Messages)
+	}
+
+	// Ошибка обработки важнее невалидных сообщений: пачка повторяется целиком
+	archive.err = func([]*dto.Message) error { return errors.New("database is unavailable") }
+	err = router.HandleMessages(messages)
+	if err == nil || errors.As(err, &invalid) {
+		t.Errorf("HandleMessages() error = %v, want processing error", err)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"fmt"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+type orderExporter struct {
+	repository interfaces.OrderRepository
+}
+
+// NewOrderExporter создает выгрузку заказов. Заказы читаются из БД в обход кэша.
+func NewOrderExporter(repository interfaces.OrderRepository) interfaces.OrderExporter {
+	return &orderExporter{repository: repository}
+}
+
+func (e *orderExporter) Export(filter dto.ExportFilter, writer interfaces.OrderWriter) (int, error) {
+	exported := 0
+	err := e.repository.Stream(filter, func(order *entities.Order) error {
+		if err := writer.Write(order); err != nil {
+			return fmt.Errorf("failed to write order %s: %w", order.OrderUID, err)
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("failed to export orders: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return exported, fmt.Errorf("failed to finish export: %w", err)
+	}
+	return exported, nil
+}
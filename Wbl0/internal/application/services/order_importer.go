@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+type orderImporter struct {
+	repository interfaces.OrderRepository
+	validator  interfaces.MessageValidator
+	cache      interfaces.OrderCache
+}
+
+// NewOrderImporter создает импорт заказов. Записи проверяются validator (он должен
+// быть строгим, чтобы расхождения со схемой считались ошибкой) и сохраняются
+// тем же пакетным путем, что и сообщения из брокера. Сохраненные заказы удаляются
+// из общего кэша sharedCache (nil - общего кэша нет): экземпляры сервиса
+// вытесняют по уведомлениям только свои локальные кэши.
+func NewOrderImporter(repository interfaces.OrderRepository, validator interfaces.MessageValidator, sharedCache interfaces.OrderCache) interfaces.OrderImporter {
+	return &orderImporter{
+		repository: repository,
+		validator:  validator,
+		cache:      sharedCache,
+	}
+}
+
+// importBatch - записи, ожидающие сохранения одной транзакцией
+type importBatch struct {
+	records []*dto.ImportRecord
+	orders  []*entities.Order
+}
+
+func (i *orderImporter) Import(reader interfaces.OrderRecordReader, options dto.ImportOptions) (*dto.ImportStats, error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+
+	started := time.Now()
+	stats := &dto.ImportStats{}
+	reject := func(record *dto.ImportRecord, err error) {
+		stats.Rejected++
+		if options.OnReject != nil {
+			options.OnReject(record, err)
+		}
+	}
+
+	var (
+		batch    importBatch
+		lastLine int
+	)
+	// flush сохраняет пачку; после нее все прочитанные записи обработаны
+	flush := func() {
+		i.saveBatch(batch, options.DryRun, stats, reject)
+		batch = importBatch{}
+
+		stats.CommittedLine = lastLine
+		stats.Elapsed = time.Since(started)
+		if options.OnProgress != nil {
+			options.OnProgress(*stats)
+		}
+	}
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			flush()
+			return stats, fmt.Errorf("failed to read records: %w", err)
+		}
+
+		stats.Read++
+		lastLine = record.Line
+		if record.Line <= options.SkipLines {
+			stats.Skipped++
+			continue
+		}
+
+		order, err := i.decode(record)
+		if err != nil {
+			reject(record, err)
+			continue
+		}
+
+		batch.records = append(batch.records, record)
+		batch.orders = append(batch.orders, order)
+		if len(batch.orders) >= options.BatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return stats, nil
+}
+
+// decode проверяет запись по схеме и разбирает заказ
+func (i *orderImporter) decode(record *dto.ImportRecord) (*entities.Order, error) {
+	if record.Err != nil {
+		return nil, record.Err
+	}
+
+	if _, err := i.validator.Validate("", record.Payload); err != nil {
+		return nil, err
+	}
+
+	var order entities.Order
+	if err := json.Unmarshal(record.Payload, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order: %w", err)
+	}
+	if order.OrderUID == "" {
+		return nil, fmt.Errorf("order_uid is required")
+	}
+
+	return &order, nil
+}
+
+// saveBatch сохраняет пачку одной транзакцией. Если это не удалось, заказы сохраняются
+// по одному, и в отклоненные попадают только те, которые не сохранились.
+func (i *orderImporter) saveBatch(batch importBatch, dryRun bool, stats *dto.ImportStats, reject func(*dto.ImportRecord, error)) {
+	if len(batch.orders) == 0 {
+		return
+	}
+	if dryRun {
+		stats.Imported += len(batch.orders)
+		return
+	}
+
+	if err := i.repository.SaveBatch(batch.orders); err == nil {
+		stats.Imported += len(batch.orders)
+		i.evict(batch.orders...)
+		return
+	}
+
+	for n, order := range batch.orders {
+		if err := i.repository.Save(order); err != nil {
+			reject(batch.records[n], fmt.Errorf("failed to save order: %w", err))
+			continue
+		}
+		stats.Imported++
+		i.evict(order)
+	}
+}
+
+// evict удаляет сохраненные заказы из общего кэша
+func (i *orderImporter) evict(orders ...*entities.Order) {
+	if i.cache == nil {
+		return
+	}
+	orderUIDs := make([]string, len(orders))
+	for n, order := range orders {
+		orderUIDs[n] = order.OrderUID
+	}
+	i.cache.Delete(orderUIDs...)
+}
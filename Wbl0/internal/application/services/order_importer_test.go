@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/cache"
+	"WbServis/Wbl0/internal/infrastructure/generators"
+	"WbServis/Wbl0/internal/infrastructure/importers"
+	"WbServis/Wbl0/pkg/schemas"
+)
+
+// importRepository запоминает сохраненные заказы. Сохранение заказов из failing
+// падает, и пачка с таким заказом откатывается целиком.
+type importRepository struct {
+	interfaces.OrderRepository
+	failing map[string]bool
+	batches int
+	saved   []string
+}
+
+func (r *importRepository) SaveBatch(orders []*entities.Order) error {
+	r.batches++
+	for _, order := range orders {
+		if r.failing[order.OrderUID] {
+			return errors.New("foreign key violation")
+		}
+	}
+	for _, order := range orders {
+		r.saved = append(r.saved, order.OrderUID)
+	}
+	return nil
+}
+
+func (r *importRepository) Save(order *entities.Order) error {
+	if r.failing[order.OrderUID] {
+		return errors.New("foreign key violation")
+	}
+	r.saved = append(r.saved, order.OrderUID)
+	return nil
+}
+
+// importFile возвращает NDJSON из сгенерированных заказов по строке на заказ
+// и их order_uid
+func importFile(t *testing.T, count int) (string, []string) {
+	t.Helper()
+
+	gen := generators.NewOrderGenerator(1)
+	var (
+		lines     []string
+		orderUIDs []string
+	)
+	for range count {
+		order := gen.Order()
+		payload, err := json.Marshal(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(payload))
+		orderUIDs = append(orderUIDs, order.OrderUID)
+	}
+	return strings.Join(lines, "\n"), orderUIDs
+}
+
+func runImport(t *testing.T, repository interfaces.OrderRepository, sharedCache interfaces.OrderCache, input string, options dto.ImportOptions) *dto.ImportStats {
+	t.Helper()
+
+	validator, err := schemas.NewOrderValidator(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := importers.NewOrderReader(importers.FormatNDJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := NewOrderImporter(repository, validator, sharedCache).Import(reader, options)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	return stats
+}
+
+func TestImportDryRun(t *testing.T) {
+	input, _ := importFile(t, 3)
+	// Запись с расхождением со схемой отклоняется и без сохранения
+	input += "\n" + `{"order_uid":"broken"}`
+
+	repository := &importRepository{}
+	var rejected []int
+	stats := runImport(t, repository, nil, input, dto.ImportOptions{
+		BatchSize: 2,
+		DryRun:    true,
+		OnReject:  func(record *dto.ImportRecord, err error) { rejected = append(rejected, record.Line) },
+	})
+
+	if stats.Read != 4 || stats.Imported != 3 || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want 3 imported and 1 rejected", stats)
+	}
+	if !slices.Equal(rejected, []int{4}) {
+		t.Errorf("rejected lines %v, want [4]", rejected)
+	}
+	if repository.batches != 0 || len(repository.saved) != 0 {
+		t.Errorf("dry run saved %v", repository.saved)
+	}
+}
+
+func TestImportResumesAfterSkipLines(t *testing.T) {
+	input, orderUIDs := importFile(t, 5)
+
+	repository := &importRepository{}
+	var committed []int
+	stats := runImport(t, repository, nil, input, dto.ImportOptions{
+		BatchSize:  2,
+		SkipLines:  3,
+		OnProgress: func(stats dto.ImportStats) { committed = append(committed, stats.CommittedLine) },
+	})
+
+	// Записи до SkipLines включительно уже были импортированы прерванным запуском
+	if stats.Read != 5 || stats.Skipped != 3 || stats.Imported != 2 || stats.CommittedLine != 5 {
+		t.Errorf("stats = %+v, want 3 skipped, 2 imported up to line 5", stats)
+	}
+	if !slices.Equal(repository.saved, orderUIDs[3:]) {
+		t.Errorf("saved %v, want %v", repository.saved, orderUIDs[3:])
+	}
+	if len(committed) == 0 || committed[len(committed)-1] != 5 || !slices.IsSorted(committed) {
+		t.Errorf("committed lines %v, want growing up to 5", committed)
+	}
+}
+
+func TestImportFallsBackToSingleSaves(t *testing.T) {
+	input, orderUIDs := importFile(t, 5)
+
+	repository := &importRepository{failing: map[string]bool{orderUIDs[1]: true}}
+	orderCache := cache.NewLRUCache(10)
+	for _, orderUID := range orderUIDs {
+		orderCache.Set(&entities.Order{OrderUID: orderUID})
+	}
+
+	var rejected []string
+	stats := runImport(t, repository, orderCache, input, dto.ImportOptions{
+		BatchSize: 3,
+		OnReject: func(record *dto.ImportRecord, err error) {
+			rejected = append(rejected, fmt.Sprintf("%d: %v", record.Line, err))
+		},
+	})
+
+	// Пачка с заказом, который не сохраняется, откатывается и сохраняется по одному:
+	// отклоняется только этот заказ, остальные заказы пачки импортированы
+	if stats.Imported != 4 || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want 4 imported and 1 rejected", stats)
+	}
+	if len(rejected) != 1 || !strings.HasPrefix(rejected[0], "2: failed to save order") {
+		t.Errorf("rejected %v, want line 2", rejected)
+	}
+	want := []string{orderUIDs[0], orderUIDs[2], orderUIDs[3], orderUIDs[4]}
+	if !slices.Equal(repository.saved, want) {
+		t.Errorf("saved %v, want %v", repository.saved, want)
+	}
+
+	// Из общего кэша удаляются только сохраненные заказы
+	for i, orderUID := range orderUIDs {
+		if _, cached := orderCache.Get(orderUID); cached != (i == 1) {
+			t.Errorf("order %d cached = %v", i, cached)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// orderMessageHandler передает сообщения брокера в сервис заказов
+type orderMessageHandler struct {
+	orderService interfaces.OrderService
+}
+
+// NewOrderMessageHandler создает обработчик сообщений о заказах
+func NewOrderMessageHandler(orderService interfaces.OrderService) interfaces.MessageHandler {
+	return &orderMessageHandler{
+		orderService: orderService,
+	}
+}
+
+func (h *orderMessageHandler) HandleMessages(messages []*dto.Message) error {
+	return h.orderService.ProcessMessageBatch(messages)
+}
+
+// NewOrderShardKey возвращает ключ шардирования сообщений по order_uid: сообщения
+// одного заказа попадают в один воркер, даже если у них разные ключи брокера или
+// ключа нет. Сообщение декодируется лишний раз; для сообщений, которые не удалось
+// декодировать, возвращается nil, и они шардируются по ключу брокера.
+func NewOrderShardKey(decoder interfaces.OrderDecoder) func(message *dto.Message) []byte {
+	return func(message *dto.Message) []byte {
+		order, err := decoder.Decode(message)
+		if err != nil || order.OrderUID == "" {
+			return nil
+		}
+		return []byte(order.OrderUID)
+	}
+}
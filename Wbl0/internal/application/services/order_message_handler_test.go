@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
+)
+
+func TestOrderShardKey(t *testing.T) {
+	decoder, err := codecs.NewOrderDecoder(codecs.Config{DefaultFormat: codecs.FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardKey := NewOrderShardKey(decoder)
+
+	tests := []struct {
+		name    string
+		message *dto.Message
+		want    string
+	}{
+		{"order_uid instead of key", &dto.Message{Key: []byte("other"), Value: []byte(`{"order_uid":"a"}`)}, "a"},
+		{"keyless message", &dto.Message{Value: []byte(`{"order_uid":"b"}`)}, "b"},
+		{"undecodable message", &dto.Message{Key: []byte("c"), Value: []byte(`not json`)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shardKey(tt.message); string(got) != tt.want {
+				t.Errorf("shardKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,25 +1,32 @@
 package services
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"time"
 
+	"WbServis/Wbl0/internal/application/dto"
 	"WbServis/Wbl0/internal/application/interfaces"
 	"WbServis/Wbl0/internal/domain/entities"
 )
 
 type orderService struct {
 	repository interfaces.OrderRepository
-	cache      map[string]*entities.Order
-	mutex      sync.RWMutex
+	decoder    interfaces.OrderDecoder
+	cache      interfaces.OrderCache
+	latency    interfaces.LatencyRecorder
 }
 
-func NewOrderService(repository interfaces.OrderRepository) interfaces.OrderService {
+// NewOrderService создает сервис заказов. decoder разбирает заказы из сообщений брокера,
+// cache хранит прочитанные и сохраненные заказы, latency - задержку от отправки
+// сообщения до сохранения заказа.
+func NewOrderService(repository interfaces.OrderRepository, decoder interfaces.OrderDecoder, cache interfaces.OrderCache, latency interfaces.LatencyRecorder) interfaces.OrderService {
 	return &orderService{
 		repository: repository,
-		cache:      make(map[string]*entities.Order),
+		decoder:    decoder,
+		cache:      cache,
+		latency:    latency,
 	}
 }
 
@@ -29,36 +36,114 @@ func (s *orderService) ProcessOrder(order *entities.Order) error {
 		return fmt.Errorf("failed to save order to database: %w", err)
 	}
 
-	s.mutex.Lock()
-	s.cache[order.OrderUID] = order
-	s.mutex.Unlock()
+	s.cache.Set(order)
 
 	log.Printf("Order %s processed successfully", order.OrderUID)
 	return nil
 }
 
+func (s *orderService) ProcessOrders(orders []*entities.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	err := s.repository.SaveBatch(orders)
+	if err != nil {
+		// Одна ошибочная запись не должна блокировать всю пачку -
+		// сохраняем заказы по одному
+		log.Printf("Failed to save batch of %d orders, falling back to single saves: %v", len(orders), err)
+
+		var errs []error
+		for _, order := range orders {
+			if err := s.ProcessOrder(order); err != nil {
+				errs = append(errs, fmt.Errorf("order %s: %w", order.OrderUID, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	s.cache.Set(orders...)
+
+	log.Printf("Batch of %d orders processed successfully", len(orders))
+	return nil
+}
+
 func (s *orderService) GetOrderByID(orderUID string) (*entities.Order, error) {
-	s.mutex.RLock()
-	if order, exists := s.cache[orderUID]; exists {
-		s.mutex.RUnlock()
+	if order, exists := s.cache.Get(orderUID); exists {
 		log.Printf("Order %s found in cache", orderUID)
 		return order, nil
 	}
-	s.mutex.RUnlock()
 
 	order, err := s.repository.GetByID(orderUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get order from database: %w", err)
 	}
+	if order == nil {
+		return nil, interfaces.ErrOrderNotFound
+	}
 
-	s.mutex.Lock()
-	s.cache[orderUID] = order
-	s.mutex.Unlock()
+	s.cache.Set(order)
 
 	log.Printf("Order %s loaded from database and cached", orderUID)
 	return order, nil
 }
 
+func (s *orderService) FindOrders(field dto.LookupField, value string) ([]*entities.Order, error) {
+	if orders, ok := s.cache.Lookup(field, value); ok {
+		log.Printf("Orders with %s %s found in cache", field, value)
+		return orders, nil
+	}
+
+	orders, err := s.repository.FindBy(field, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orders in database: %w", err)
+	}
+	s.cache.SetLookup(field, value, orders)
+
+	return orders, nil
+}
+
+func (s *orderService) SearchOrders(query string, limit, offset int) (*dto.OrderSearchResponse, error) {
+	results, total, err := s.repository.Search(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
+
+	return &dto.OrderSearchResponse{
+		Query:   query,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		Results: results,
+		// Ограничения поиска передаются клиенту, чтобы пустой результат по имени
+		// или адресу не выдавался за отсутствие заказов
+		EncryptedOrders: s.repository.SearchCoverage(),
+	}, nil
+}
+
+func (s *orderService) GetCustomerOrders(customerID string, limit, offset int) (*dto.CustomerOrdersResponse, error) {
+	stats, err := s.repository.GetCustomerStats(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+
+	orders := []dto.CustomerOrderSummary{}
+	if stats.OrderCount > offset {
+		orders, err = s.repository.GetCustomerOrders(customerID, limit, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get customer orders: %w", err)
+		}
+	}
+
+	return &dto.CustomerOrdersResponse{
+		CustomerID: customerID,
+		Stats:      *stats,
+		Limit:      limit,
+		Offset:     offset,
+		Orders:     orders,
+	}, nil
+}
+
 func (s *orderService) RestoreCache() error {
 	log.Println("Restoring cache from database...")
 
@@ -67,33 +152,92 @@ func (s *orderService) RestoreCache() error {
 		return fmt.Errorf("failed to get all orders: %w", err)
 	}
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	s.cache = make(map[string]*entities.Order)
-
-	for _, order := range orders {
-		s.cache[order.OrderUID] = order
-	}
+	s.cache.Clear()
+	s.cache.Set(orders...)
 
 	log.Printf("Cache restored with %d orders", len(orders))
 	return nil
 }
 
+// EvictOrders вытесняет заказы, измененные другим экземпляром, только из
+// локального кэша: общий кэш тот экземпляр уже обновил сам
+func (s *orderService) EvictOrders(orderUIDs []string) {
+	s.cache.DeleteLocal(orderUIDs...)
+
+	log.Printf("Evicted %d changed orders from cache", len(orderUIDs))
+}
+
+func (s *orderService) EvictAll() {
+	s.cache.Clear()
+
+	log.Println("Cache evicted")
+}
+
 func (s *orderService) Close() error {
 	return s.repository.Close()
 }
 
-func (s *orderService) ProcessMessage(message []byte) error {
-	var order entities.Order
-	err := json.Unmarshal(message, &order)
+func (s *orderService) ProcessMessage(message *dto.Message) error {
+	order, err := s.decodeMessage(message)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal order: %w", err)
+		return err
+	}
+
+	if err := s.ProcessOrder(order); err != nil {
+		return err
+	}
+
+	if producedAt, ok := message.ProducedAt(); ok {
+		s.latency.Observe(time.Since(producedAt))
+	}
+	return nil
+}
+
+func (s *orderService) ProcessMessageBatch(messages []*dto.Message) error {
+	orders := make([]*entities.Order, 0, len(messages))
+	producedAt := make([]time.Time, 0, len(messages))
+	var invalid interfaces.InvalidMessagesError
+	for _, message := range messages {
+		order, err := s.decodeMessage(message)
+		if err != nil {
+			invalid.Add(message, err)
+			continue
+		}
+		orders = append(orders, order)
+		if t, ok := message.ProducedAt(); ok {
+			producedAt = append(producedAt, t)
+		}
+	}
+
+	if err := s.ProcessOrders(orders); err != nil {
+		return err
+	}
+
+	// Задержка замеряется только для сохраненной пачки: неудачная будет
+	// повторена, и замер учтет время повторов
+	persisted := time.Now()
+	for _, t := range producedAt {
+		s.latency.Observe(persisted.Sub(t))
+	}
+
+	// Невалидные сообщения не подтверждаются молча: процессор отправит их
+	// в топик недоставленных сообщений
+	if len(invalid.Messages) > 0 {
+		return &invalid
+	}
+	return nil
+}
+
+// decodeMessage разбирает заказ из сообщения в формате, выбранном декодером
+func (s *orderService) decodeMessage(message *dto.Message) (*entities.Order, error) {
+	order, err := s.decoder.Decode(message)
+	if err != nil {
+		return nil, err
 	}
 
 	if order.OrderUID == "" {
-		return fmt.Errorf("order_uid is required")
+		return nil, fmt.Errorf("order_uid is required")
 	}
 
-	return s.ProcessOrder(&order)
+	return order, nil
 }
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/cache"
+	"WbServis/Wbl0/internal/infrastructure/codecs"
+)
+
+// fakeOrderRepository хранит заказы в памяти. Неиспользуемые методы
+// интерфейса не реализованы и паникуют при вызове.
+type fakeOrderRepository struct {
+	interfaces.OrderRepository
+	orders  map[string]*entities.Order
+	lookups int
+}
+
+func (r *fakeOrderRepository) GetByID(orderUID string) (*entities.Order, error) {
+	return r.orders[orderUID], nil
+}
+
+func (r *fakeOrderRepository) FindBy(field dto.LookupField, value string) ([]*entities.Order, error) {
+	r.lookups++
+	var orders []*entities.Order
+	for _, order := range r.orders {
+		if slices.Contains(dto.LookupValues(order, field), value) {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeOrderRepository) Save(order *entities.Order) error {
+	return r.SaveBatch([]*entities.Order{order})
+}
+
+func (r *fakeOrderRepository) SaveBatch(orders []*entities.Order) error {
+	if r.orders == nil {
+		r.orders = make(map[string]*entities.Order)
+	}
+	for _, order := range orders {
+		r.orders[order.OrderUID] = order
+	}
+	return nil
+}
+
+// searchRepository возвращает заданные результаты поиска и ограничения
+type searchRepository struct {
+	fakeOrderRepository
+	coverage *dto.SearchCoverage
+}
+
+func (r *searchRepository) Search(query string, limit, offset int) ([]dto.OrderSearchResult, int, error) {
+	return nil, 3, nil
+}
+
+func (r *searchRepository) SearchCoverage() *dto.SearchCoverage {
+	return r.coverage
+}
+
+func TestGetOrderByIDUnknown(t *testing.T) {
+	orderCache := cache.NewLRUCache(10)
+	service := NewOrderService(&fakeOrderRepository{}, nil, orderCache, NewLatencyRecorder(10))
+
+	order, err := service.GetOrderByID("unknown")
+	if !errors.Is(err, interfaces.ErrOrderNotFound) {
+		t.Fatalf("GetOrderByID() error = %v, want ErrOrderNotFound", err)
+	}
+	if order != nil {
+		t.Errorf("GetOrderByID() = %+v, want nil", order)
+	}
+	if orderCache.Len() != 0 {
+		t.Errorf("cache has %d orders, want 0", orderCache.Len())
+	}
+}
+
+func TestGetOrderByIDCachesLoadedOrder(t *testing.T) {
+	repository := &fakeOrderRepository{orders: map[string]*entities.Order{
+		"known": {OrderUID: "known"},
+	}}
+	orderCache := cache.NewLRUCache(10)
+	service := NewOrderService(repository, nil, orderCache, NewLatencyRecorder(10))
+
+	order, err := service.GetOrderByID("known")
+	if err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+	if order.OrderUID != "known" {
+		t.Errorf("GetOrderByID() = %s, want known", order.OrderUID)
+	}
+	if _, ok := orderCache.Get("known"); !ok {
+		t.Error("loaded order is not cached")
+	}
+}
+
+func TestFindOrdersIgnoresPartialCache(t *testing.T) {
+	repository := &fakeOrderRepository{orders: map[string]*entities.Order{
+		"a": {OrderUID: "a", TrackNumber: "T1"},
+		"b": {OrderUID: "b", TrackNumber: "T1"},
+	}}
+	orderCache := cache.NewLRUCache(10)
+	service := NewOrderService(repository, nil, orderCache, NewLatencyRecorder(10))
+
+	// В кэше только один из двух заказов с трек-номером T1
+	if _, err := service.GetOrderByID("a"); err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		orders, err := service.FindOrders(dto.LookupTrackNumber, "T1")
+		if err != nil {
+			t.Fatalf("FindOrders() error = %v", err)
+		}
+		if len(orders) != 2 {
+			t.Fatalf("attempt %d: FindOrders() returned %d orders, want 2", attempt, len(orders))
+		}
+	}
+	// Второй запрос обслужен кэшем, где теперь полный набор
+	if repository.lookups != 1 {
+		t.Errorf("repository lookups = %d, want 1", repository.lookups)
+	}
+}
+
+func TestProcessMessageBatchReturnsInvalidMessages(t *testing.T) {
+	decoder, err := codecs.NewOrderDecoder(codecs.Config{DefaultFormat: codecs.FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := &fakeOrderRepository{}
+	service := NewOrderService(repository, decoder, cache.NewLRUCache(10), NewLatencyRecorder(10))
+
+	messages := []*dto.Message{
+		{Topic: "orders", Value: []byte(`{"order_uid":"a"}`)},
+		{Topic: "orders", Value: []byte(`not json`)},
+		{Topic: "orders", Value: []byte(`{"track_number":"T1"}`)},
+		{Topic: "orders", Value: []byte(`{"order_uid":"b"}`)},
+	}
+
+	err = service.ProcessMessageBatch(messages)
+	var invalid *interfaces.InvalidMessagesError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("ProcessMessageBatch() error = %v, want InvalidMessagesError", err)
+	}
+	if len(invalid.Messages) != 2 || invalid.Messages[0] != messages[1] || invalid.Messages[1] != messages[2] {
+		t.Errorf("invalid messages = %v, want messages 1 and 2", invalid.Messages)
+	}
+	if len(invalid.Errors) != 2 || invalid.Errors[1].Error() != "order_uid is required" {
+		t.Errorf("invalid errors = %v", invalid.Errors)
+	}
+
+	// Валидные сообщения пачки сохранены, несмотря на невалидные
+	if repository.orders["a"] == nil || repository.orders["b"] == nil {
+		t.Errorf("saved orders = %v, want a and b", repository.orders)
+	}
+
+	if err := service.ProcessMessageBatch(messages[3:]); err != nil {
+		t.Errorf("ProcessMessageBatch() of valid messages error = %v", err)
+	}
+}
+
+func TestProcessMessageObservesLatencyOnlyWithProduceTime(t *testing.T) {
+	decoder, err := codecs.NewOrderDecoder(codecs.Config{DefaultFormat: codecs.FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		timestamp time.Time
+		wantCount int64
+	}{
+		{name: "header", headers: map[string]string{dto.ProducedAtHeader: sent.Format(time.RFC3339Nano)}, wantCount: 1},
+		{name: "broker timestamp", timestamp: sent, wantCount: 1},
+		{name: "invalid header falls back to broker timestamp", headers: map[string]string{dto.ProducedAtHeader: "yesterday"}, timestamp: sent, wantCount: 1},
+		// Без времени отправки задержка была бы отсчитана от 0001 или 1970 года
+		{name: "no produce time", wantCount: 0},
+		{name: "kafka timestamp -1", timestamp: time.UnixMilli(-1), wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := func(orderUID string) *dto.Message {
+				return &dto.Message{
+					Topic:     "orders",
+					Value:     []byte(`{"order_uid":"` + orderUID + `"}`),
+					Headers:   tt.headers,
+					Timestamp: tt.timestamp,
+				}
+			}
+
+			latency := NewLatencyRecorder(10)
+			service := NewOrderService(&fakeOrderRepository{}, decoder, cache.NewLRUCache(10), latency)
+			if err := service.ProcessMessage(message("a")); err != nil {
+				t.Fatal(err)
+			}
+			if err := service.ProcessMessageBatch([]*dto.Message{message("b"), message("c")}); err != nil {
+				t.Fatal(err)
+			}
+
+			stats := latency.Stats()
+			if stats.Count != 3*tt.wantCount {
+				t.Fatalf("observed %d latencies, want %d", stats.Count, 3*tt.wantCount)
+			}
+			// Задержка отсчитывается от времени отправки, а не от нулевого времени
+			if stats.Count > 0 && (stats.Max < 1000 || stats.Max > 60000) {
+				t.Errorf("max latency = %.0fms, want about 1s", stats.Max)
+			}
+		})
+	}
+}
+
+func TestSearchOrdersReportsEncryptedCoverage(t *testing.T) {
+	coverage := &dto.SearchCoverage{NotSearched: []string{"delivery.name"}, ExactOnly: []string{"delivery.phone"}}
+
+	for _, tt := range []struct {
+		name     string
+		coverage *dto.SearchCoverage
+	}{
+		{"without encryption", nil},
+		{"with encryption", coverage},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewOrderService(&searchRepository{coverage: tt.coverage}, nil, cache.NewLRUCache(10), NewLatencyRecorder(10))
+
+			response, err := service.SearchOrders("Testov", 2, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.Total != 3 || response.EncryptedOrders != tt.coverage {
+				t.Errorf("SearchOrders() = total %d, encrypted orders %+v", response.Total, response.EncryptedOrders)
+			}
+		})
+	}
+}
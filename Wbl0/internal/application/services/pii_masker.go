@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// Правила маскирования полей доставки
+const (
+	// MaskKeep оставляет значение как есть
+	MaskKeep = "keep"
+	// MaskRedact заменяет значение на ***
+	MaskRedact = "redact"
+	// MaskPartial оставляет первую букву каждого слова: "Test Testov" -> "T*** T***"
+	MaskPartial = "partial"
+	// MaskPhone оставляет первые 4 и последние 2 символа: "+9720000000" -> "+972*****00"
+	MaskPhone = "phone"
+	// MaskEmail оставляет первую букву имени и домен: "test@gmail.com" -> "t***@gmail.com"
+	MaskEmail = "email"
+)
+
+// DefaultMaskRules - правила по умолчанию; город, регион и индекс нужны
+// для разбора доставки и не маскируются
+var DefaultMaskRules = map[string]string{
+	"name":    MaskPartial,
+	"phone":   MaskPhone,
+	"zip":     MaskKeep,
+	"city":    MaskKeep,
+	"address": MaskRedact,
+	"region":  MaskKeep,
+	"email":   MaskEmail,
+}
+
+var maskFuncs = map[string]func(string) string{
+	MaskKeep:    func(value string) string { return value },
+	MaskRedact:  func(string) string { return "***" },
+	MaskPartial: maskPartial,
+	MaskPhone:   maskPhone,
+	MaskEmail:   maskEmail,
+}
+
+var (
+	// phonePattern находит телефоны с разделителями (пробел, дефис, точка, скобки):
+	// международные с + от 7 до 15 цифр и национальные от 10 до 15 цифр с префиксом
+	// 8 или 0 либо с кодом в скобках (8 (916) 123-45-67, (916) 123-45-67).
+	// Префикс нужен, чтобы не маскировать даты и идентификаторы.
+	phonePattern = regexp.MustCompile(`\+\d(?:[ \-.()]{0,2}\d){6,14}\b|(?:\b[08]|\(\d)(?:[ \-.()]{0,2}\d){9,14}\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+type piiMasker struct {
+	rules map[string]func(string) string
+	text  []func(string) string
+}
+
+// NewPIIMasker создает маскирование по правилам DefaultMaskRules, переопределенным
+// спецификацией вида "name=redact,zip=partial". Телефоны и email в тексте
+// маскируются, если правило поля phone или email не keep.
+func NewPIIMasker(spec string) (interfaces.PIIMasker, error) {
+	rules := make(map[string]string, len(DefaultMaskRules))
+	for field, rule := range DefaultMaskRules {
+		rules[field] = rule
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, rule, ok := strings.Cut(pair, "=")
+		field, rule = strings.TrimSpace(field), strings.TrimSpace(rule)
+		if !ok {
+			return nil, fmt.Errorf("invalid mask rule %q, expected field=rule", pair)
+		}
+		if _, known := DefaultMaskRules[field]; !known {
+			return nil, fmt.Errorf("unknown delivery field %q in mask rules", field)
+		}
+		if _, known := maskFuncs[rule]; !known {
+			return nil, fmt.Errorf("unknown mask rule %q for field %s", rule, field)
+		}
+		rules[field] = rule
+	}
+
+	m := &piiMasker{rules: make(map[string]func(string) string, len(rules))}
+	for field, rule := range rules {
+		m.rules[field] = maskFuncs[rule]
+	}
+	if rules["phone"] != MaskKeep {
+		m.text = append(m.text, func(text string) string {
+			return phonePattern.ReplaceAllStringFunc(text, m.rules["phone"])
+		})
+	}
+	if rules["email"] != MaskKeep {
+		m.text = append(m.text, func(text string) string {
+			return emailPattern.ReplaceAllStringFunc(text, m.rules["email"])
+		})
+	}
+	return m, nil
+}
+
+func (m *piiMasker) MaskOrder(order *entities.Order) *entities.Order {
+	if order == nil {
+		return nil
+	}
+
+	masked := *order
+	masked.Delivery = entities.Delivery{
+		Name:    m.MaskField("name", order.Delivery.Name),
+		Phone:   m.MaskField("phone", order.Delivery.Phone),
+		Zip:     m.MaskField("zip", order.Delivery.Zip),
+		City:    m.MaskField("city", order.Delivery.City),
+		Address: m.MaskField("address", order.Delivery.Address),
+		Region:  m.MaskField("region", order.Delivery.Region),
+		Email:   m.MaskField("email", order.Delivery.Email),
+	}
+	return &masked
+}
+
+func (m *piiMasker) MaskField(field, value string) string {
+	mask, ok := m.rules[field]
+	if !ok || value == "" {
+		return value
+	}
+	return mask(value)
+}
+
+func (m *piiMasker) MaskText(text string) string {
+	for _, mask := range m.text {
+		text = mask(text)
+	}
+	return text
+}
+
+func maskPartial(value string) string {
+	words := strings.Fields(value)
+	for i, word := range words {
+		words[i] = string([]rune(word)[0]) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+func maskPhone(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 6 {
+		return "***"
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-6) + string(runes[len(runes)-2:])
+}
+
+func maskEmail(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	return string([]rune(local)[0]) + "***@" + domain
+}
+
+// maskingWriter маскирует персональные данные в каждой записи перед передачей дальше
+type maskingWriter struct {
+	next   io.Writer
+	masker interfaces.PIIMasker
+}
+
+// NewMaskingWriter оборачивает вывод лога: log пишет строку за один вызов Write,
+// поэтому телефон или email не разрываются между вызовами
+func NewMaskingWriter(next io.Writer, masker interfaces.PIIMasker) io.Writer {
+	return &maskingWriter{next: next, masker: masker}
+}
+
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.next, w.masker.MaskText(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import "testing"
+
+func TestMaskTextPhones(t *testing.T) {
+	masker, err := NewPIIMasker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"international", "phone +79161234567 is invalid", "phone +791******67 is invalid"},
+		{"international with spaces", "phone +7 916 123 45 67 is invalid", "phone +7 9**********67 is invalid"},
+		{"international with brackets", "call +7 (916) 123-45-67", "call +7 (************67"},
+		{"short international", "phone: +9720000000", "phone: +972*****00"},
+		{"national", "phone 89161234567 is invalid", "phone 8916*****67 is invalid"},
+		{"national with brackets", "phone 8 (916) 123-45-67 is invalid", "phone 8 (9***********67 is invalid"},
+		{"national with dots", "tel=8.916.123.45.67", "tel=8.91*********67"},
+		{"area code in brackets", "phone (916) 123-45-67", "phone (916*********67"},
+		{"several phones", "+79161234567, 89161234567", "+791******67, 8916*****67"},
+		{"date", "created 2024-01-15 12:00:00", "created 2024-01-15 12:00:00"},
+		{"short numbers", "order 1817 of 9934930 items", "order 1817 of 9934930 items"},
+		{"timestamp", "payment_dt 1637907727", "payment_dt 1637907727"},
+		{"too long", "rid 1234567890123456789", "rid 1234567890123456789"},
+		{"email", "user test@gmail.com", "user t***@gmail.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := masker.MaskText(tt.text); got != tt.want {
+				t.Errorf("MaskText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskTextKeepsPhonesWithKeepRule(t *testing.T) {
+	masker, err := NewPIIMasker("phone=keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "phone 8 (916) 123-45-67"
+	if got := masker.MaskText(text); got != text {
+		t.Errorf("MaskText(%q) = %q", text, got)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// rollupBatchSize - число отметок, пересчитываемых одной транзакцией
+const rollupBatchSize = 100
+
+type rollupService struct {
+	repository interfaces.RollupRepository
+}
+
+// NewRollupService создает сервис обновления дневных сводок
+func NewRollupService(repository interfaces.RollupRepository) interfaces.RollupService {
+	return &rollupService{repository: repository}
+}
+
+func (s *rollupService) Refresh() error {
+	total := 0
+	for {
+		processed, err := s.repository.RefreshDirty(rollupBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to refresh rollups: %w", err)
+		}
+		total += processed
+
+		if processed < rollupBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Rollups refreshed for %d changed days", total)
+	}
+	return nil
+}
+
+func (s *rollupService) Rebuild(from, to time.Time) error {
+	marked, err := s.repository.MarkDays(from, to)
+	if err != nil {
+		return fmt.Errorf("failed to mark days for rebuild: %w", err)
+	}
+	log.Printf("Rebuilding rollups for %d days...", marked)
+
+	return s.Refresh()
+}
+
+func (s *rollupService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				log.Printf("Failed to refresh rollups: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// apiKeyEntry - запись файла API-ключей. Сам ключ не хранится, только его SHA-256.
+type apiKeyEntry struct {
+	Name   string   `json:"name"`
+	SHA256 string   `json:"sha256"`
+	Scopes []string `json:"scopes"`
+}
+
+type apiKey struct {
+	name   string
+	hash   []byte
+	scopes []string
+}
+
+// apiKeyStore проверяет статические API-ключи по их хешам
+type apiKeyStore struct {
+	keys []apiKey
+}
+
+// loadAPIKeys читает файл ключей: JSON-массив записей {name, sha256, scopes}
+func loadAPIKeys(path string) (*apiKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys file: %w", err)
+	}
+
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys file: %w", err)
+	}
+
+	store := &apiKeyStore{keys: make([]apiKey, 0, len(entries))}
+	for i, entry := range entries {
+		hash, err := hex.DecodeString(entry.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %d (%s): sha256 must be %d hex characters", i, entry.Name, sha256.Size*2)
+		}
+		if entry.Name == "" {
+			return nil, fmt.Errorf("api key %d: name is required", i)
+		}
+		store.keys = append(store.keys, apiKey{name: entry.Name, hash: hash, scopes: entry.Scopes})
+	}
+	return store, nil
+}
+
+func (s *apiKeyStore) authenticate(key string) (*dto.Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+
+	// Сравниваем со всеми ключами без раннего выхода, чтобы время ответа
+	// не зависело от позиции ключа
+	var found *apiKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].hash) == 1 {
+			found = &s.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &dto.Principal{
+		Subject: found.name,
+		Method:  dto.AuthMethodAPIKey,
+		Scopes:  found.scopes,
+	}, nil
+}
@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAPIKey     = "orders-reader-key"
+	testHMACSecret = "hmac-secret-for-tests"
+	testKeyID      = "test-key"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeAPIKeys создает файл ключей с хешем testAPIKey
+func writeAPIKeys(t *testing.T) string {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(testAPIKey))
+	data, err := json.Marshal([]apiKeyEntry{{
+		Name:   "reader",
+		SHA256: hex.EncodeToString(sum[:]),
+		Scopes: []string{dto.ScopeOrdersRead},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "api_keys.json", data)
+}
+
+// writeJWKS создает JWKS-файл с открытым ключом key
+func writeJWKS(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, "jwks.json", data)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "dashboard",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": dto.ScopeOrdersRead + " " + dto.ScopeOrdersPII,
+	}
+}
+
+func TestNewAuthenticatorRequiresCredentials(t *testing.T) {
+	if _, err := NewAuthenticator(Config{}); err == nil {
+		t.Error("authenticator without credentials was created")
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	authenticator, err := NewAuthenticator(Config{APIKeysFile: writeAPIKeys(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := authenticator.AuthenticateAPIKey(testAPIKey)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey() error = %v", err)
+	}
+	if principal.Subject != "reader" || principal.Method != dto.AuthMethodAPIKey || !principal.HasScope(dto.ScopeOrdersRead) {
+		t.Errorf("AuthenticateAPIKey() = %+v", principal)
+	}
+
+	for _, key := range []string{"unknown-key", testAPIKey + " ", hex.EncodeToString([]byte(testAPIKey))} {
+		if _, err := authenticator.AuthenticateAPIKey(key); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("AuthenticateAPIKey(%q) error = %v, want ErrInvalidCredentials", key, err)
+		}
+	}
+
+	// JWT не настроен
+	if _, err := authenticator.AuthenticateToken(sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), validClaims())); err == nil {
+		t.Error("token accepted without jwt configuration")
+	}
+}
+
+func TestLoadAPIKeysRejectsInvalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", `{`},
+		{"short hash", `[{"name": "reader", "sha256": "abcd", "scopes": ["orders:read"]}]`},
+		{"plaintext key", `[{"name": "reader", "sha256": "` + testAPIKey + `"}]`},
+		{"no name", `[{"sha256": "` + hex.EncodeToString(make([]byte, sha256.Size)) + `"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAuthenticator(Config{APIKeysFile: writeFile(t, "api_keys.json", []byte(tt.data))}); err == nil {
+				t.Error("invalid api keys file was accepted")
+			}
+		})
+	}
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	otherKey := newRSAKey(t)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPublicKey(t, &rsaKey.PublicKey)})
+
+	withClaims := func(change func(claims jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		token   string
+		wantErr bool
+	}{
+		{
+			name:   "hmac",
+			config: Config{JWTHMACSecret: testHMACSecret},
+			token:  sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), validClaims()),
+		},
+		{
+			name:   "jwks",
+			config: Config{JWKSFile: writeJWKS(t, rsaKey)},
+			token:  sign(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+		},
+		{
+			name:   "jwks with hmac configured",
+			config: Config{JWTHMACSecret: testHMACSecret, JWKSFile: writeJWKS(t, rsaKey)},
+			token:  sign(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+		},
+		{
+			name:    "jwks wrong key",
+			config:  Config{JWKSFile: writeJWKS(t, rsaKey)},
+			token:   sign(t, jwt.SigningMethodRS256, otherKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			// Подмена алгоритма: HS256, подписанный открытым ключом RSA
+			name:    "hs256 signed with rsa public key",
+			config:  Config{JWKSFile: writeJWKS(t, rsaKey)},
+			token:   sign(t, jwt.SigningMethodHS256, publicPEM, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "hs256 signed with rsa public key, hmac configured",
+			config:  Config{JWTHMACSecret: testHMACSecret, JWKSFile: writeJWKS(t, rsaKey)},
+			token:   sign(t, jwt.SigningMethodHS256, publicPEM, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "rs256 without jwks",
+			config:  Config{JWTHMACSecret: testHMACSecret},
+			token:   sign(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "unsigned",
+			config:  Config{JWTHMACSecret: testHMACSecret},
+			token:   sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			config:  Config{JWTHMACSecret: testHMACSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "missing exp",
+			config:  Config{JWTHMACSecret: testHMACSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), withClaims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			config:  Config{JWTHMACSecret: testHMACSecret},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:   "expired within leeway",
+			config: Config{JWTHMACSecret: testHMACSecret, JWTLeeway: 5 * time.Minute},
+			token:  sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+		},
+		{
+			name:    "wrong issuer",
+			config:  Config{JWTHMACSecret: testHMACSecret, JWTIssuer: "https://auth.example.com"},
+			token:   sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), withClaims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			wantErr: true,
+		},
+		{
+			name:   "issuer and audience",
+			config: Config{JWTHMACSecret: testHMACSecret, JWTIssuer: "https://auth.example.com", JWTAudience: "order-service"},
+			token: sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), withClaims(func(c jwt.MapClaims) {
+				c["iss"] = "https://auth.example.com"
+				c["aud"] = "order-service"
+			})),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := NewAuthenticator(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			principal, err := authenticator.AuthenticateToken(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("AuthenticateToken() error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateToken() error = %v", err)
+			}
+			if principal.Subject != "dashboard" || principal.Method != dto.AuthMethodJWT {
+				t.Errorf("AuthenticateToken() = %+v", principal)
+			}
+			if want := []string{dto.ScopeOrdersRead, dto.ScopeOrdersPII}; !slices.Equal(principal.Scopes, want) {
+				t.Errorf("scopes = %v, want %v", principal.Scopes, want)
+			}
+		})
+	}
+}
+
+func TestJWTScpClaim(t *testing.T) {
+	authenticator, err := NewAuthenticator(Config{JWTHMACSecret: testHMACSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := validClaims()
+	delete(claims, "scope")
+	claims["scp"] = []string{dto.ScopeAdmin}
+	principal, err := authenticator.AuthenticateToken(sign(t, jwt.SigningMethodHS256, []byte(testHMACSecret), claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !principal.HasScope(dto.ScopeAdmin) || principal.HasScope(dto.ScopeOrdersRead) {
+		t.Errorf("scopes = %v, want [admin]", principal.Scopes)
+	}
+}
+
+func TestInvalidJWKSFile(t *testing.T) {
+	if _, err := NewAuthenticator(Config{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("missing jwks file was accepted")
+	}
+	if _, err := NewAuthenticator(Config{JWKSFile: writeFile(t, "jwks.json", []byte("not json"))}); err == nil {
+		t.Error("invalid jwks file was accepted")
+	}
+}
+
+func mustMarshalPublicKey(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
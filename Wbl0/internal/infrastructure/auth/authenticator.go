@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// ErrInvalidCredentials возвращается для неизвестного ключа и невалидного токена
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Config задает источники учетных данных. Должен быть задан хотя бы один.
+type Config struct {
+	// APIKeysFile - JSON-файл с хешами статических API-ключей
+	APIKeysFile string
+	// JWTHMACSecret - общий секрет для JWT с алгоритмами HS256/HS384/HS512
+	JWTHMACSecret string
+	// JWKSFile - JSON Web Key Set с открытыми ключами для JWT (RS*, PS*, ES*, EdDSA)
+	JWKSFile string
+	// JWTIssuer и JWTAudience, если заданы, сверяются с iss и aud токена
+	JWTIssuer   string
+	JWTAudience string
+	// JWTLeeway - допустимое расхождение часов при проверке exp и nbf
+	JWTLeeway time.Duration
+}
+
+type authenticator struct {
+	apiKeys *apiKeyStore
+	jwt     *jwtVerifier
+}
+
+// NewAuthenticator создает проверку API-ключей и JWT по конфигурации
+func NewAuthenticator(config Config) (interfaces.Authenticator, error) {
+	if config.APIKeysFile == "" && config.JWTHMACSecret == "" && config.JWKSFile == "" {
+		return nil, errors.New("no credentials configured: set an API keys file, JWT HMAC secret or JWKS file")
+	}
+
+	a := &authenticator{}
+
+	if config.APIKeysFile != "" {
+		store, err := loadAPIKeys(config.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		a.apiKeys = store
+	}
+
+	if config.JWTHMACSecret != "" || config.JWKSFile != "" {
+		verifier, err := newJWTVerifier(config)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = verifier
+	}
+
+	return a, nil
+}
+
+func (a *authenticator) AuthenticateAPIKey(key string) (*dto.Principal, error) {
+	if a.apiKeys == nil {
+		return nil, errors.New("api keys are not configured")
+	}
+	return a.apiKeys.authenticate(key)
+}
+
+func (a *authenticator) AuthenticateToken(token string) (*dto.Principal, error) {
+	if a.jwt == nil {
+		return nil, errors.New("jwt is not configured")
+	}
+	return a.jwt.authenticate(token)
+}
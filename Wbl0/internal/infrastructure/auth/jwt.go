@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	hmacMethods = []string{"HS256", "HS384", "HS512"}
+	jwksMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+)
+
+// tokenClaims - утверждения JWT. Области доступа берутся из scope (строка через
+// пробел, RFC 8693) и scp (массив строк).
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Scp   []string `json:"scp,omitempty"`
+}
+
+// jwtVerifier проверяет JWT общим HMAC-секретом и/или ключами из JWKS-файла
+type jwtVerifier struct {
+	secret []byte
+	jwks   keyfunc.Keyfunc
+	parser *jwt.Parser
+}
+
+func newJWTVerifier(config Config) (*jwtVerifier, error) {
+	v := &jwtVerifier{}
+
+	var methods []string
+	if config.JWTHMACSecret != "" {
+		v.secret = []byte(config.JWTHMACSecret)
+		methods = append(methods, hmacMethods...)
+	}
+	if config.JWKSFile != "" {
+		data, err := os.ReadFile(config.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks file: %w", err)
+		}
+		v.jwks, err = keyfunc.NewJWKSetJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks file: %w", err)
+		}
+		methods = append(methods, jwksMethods...)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.JWTLeeway),
+	}
+	if config.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(config.JWTIssuer))
+	}
+	if config.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.JWTAudience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
+}
+
+func (v *jwtVerifier) authenticate(token string) (*dto.Principal, error) {
+	var claims tokenClaims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	scopes := append(strings.Fields(claims.Scope), claims.Scp...)
+	return &dto.Principal{
+		Subject: claims.Subject,
+		Method:  dto.AuthMethodJWT,
+		Scopes:  scopes,
+	}, nil
+}
+
+// key выбирает ключ проверки по алгоритму токена. HMAC-секрет отдается только
+// для HS*, поэтому открытый ключ из JWKS нельзя использовать как HMAC-секрет.
+func (v *jwtVerifier) key(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if v.secret == nil {
+			return nil, fmt.Errorf("hmac tokens are not accepted")
+		}
+		return v.secret, nil
+	}
+
+	if v.jwks == nil {
+		return nil, fmt.Errorf("signing method %s is not accepted", token.Method.Alg())
+	}
+	return v.jwks.Keyfunc(token)
+}
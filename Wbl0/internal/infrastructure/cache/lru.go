@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// lruCache - кэш заказов в памяти процесса с вытеснением давно не использованных.
+// Поддерживает вторичные индексы по трек-номеру, транзакции и RID товаров.
+// Индекс отвечает на Lookup только для значений, полный набор заказов которых
+// сохранен через SetLookup: иначе в кэше может быть лишь часть заказов из БД.
+type lruCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	// indexes: поле -> значение -> множество order_uid
+	indexes map[dto.LookupField]map[string]map[string]struct{}
+	// complete: поле -> значения, для которых индекс содержит все заказы из БД
+	complete map[dto.LookupField]map[string]struct{}
+}
+
+// NewLRUCache создает локальный кэш на capacity заказов. capacity <= 0 - без ограничения.
+func NewLRUCache(capacity int) interfaces.OrderCache {
+	c := &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	c.resetIndexes()
+	return c
+}
+
+func (c *lruCache) Get(orderUID string) (*entities.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[orderUID]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*entities.Order), true
+}
+
+func (c *lruCache) Lookup(field dto.LookupField, value string) ([]*entities.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.complete[field][value]; !ok {
+		return nil, false
+	}
+	orderUIDs := c.indexes[field][value]
+
+	orders := make([]*entities.Order, 0, len(orderUIDs))
+	for orderUID := range orderUIDs {
+		element := c.entries[orderUID]
+		c.order.MoveToFront(element)
+		orders = append(orders, element.Value.(*entities.Order))
+	}
+	return orders, true
+}
+
+func (c *lruCache) Set(orders ...*entities.Order) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(orders)
+}
+
+func (c *lruCache) SetLookup(field dto.LookupField, value string, orders []*entities.Order) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(orders)
+
+	// Пустой результат не запоминается, а вытесненный при вставке заказ
+	// делает набор неполным
+	orderUIDs := c.indexes[field][value]
+	if len(orders) == 0 || len(orderUIDs) != len(orders) {
+		return
+	}
+	for _, order := range orders {
+		if _, ok := orderUIDs[order.OrderUID]; !ok {
+			return
+		}
+	}
+	c.complete[field][value] = struct{}{}
+}
+
+func (c *lruCache) set(orders []*entities.Order) {
+	for _, order := range orders {
+		if order == nil {
+			continue
+		}
+		if element, ok := c.entries[order.OrderUID]; ok {
+			c.unindex(element.Value.(*entities.Order))
+			element.Value = order
+			c.order.MoveToFront(element)
+		} else {
+			c.entries[order.OrderUID] = c.order.PushFront(order)
+		}
+		c.index(order)
+	}
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete удаляет заказы и сбрасывает полноту всех наборов: удаление означает
+// изменение заказа, новые значения полей которого кэшу неизвестны
+func (c *lruCache) Delete(orderUIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, orderUID := range orderUIDs {
+		if element, ok := c.entries[orderUID]; ok {
+			c.remove(element)
+		}
+	}
+	if len(orderUIDs) > 0 {
+		c.resetComplete()
+	}
+}
+
+func (c *lruCache) DeleteLocal(orderUIDs ...string) {
+	c.Delete(orderUIDs...)
+}
+
+func (c *lruCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.resetIndexes()
+}
+
+func (c *lruCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *lruCache) Orders() []*entities.Order {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	orders := make([]*entities.Order, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		orders = append(orders, element.Value.(*entities.Order))
+	}
+	return orders
+}
+
+// remove удаляет заказ из кэша. Наборы, в которые он входил, становятся неполными.
+func (c *lruCache) remove(element *list.Element) {
+	order := element.Value.(*entities.Order)
+	c.order.Remove(element)
+	delete(c.entries, order.OrderUID)
+	c.unindex(order)
+	for _, field := range dto.LookupFields {
+		for _, value := range dto.LookupValues(order, field) {
+			delete(c.complete[field], value)
+		}
+	}
+}
+
+func (c *lruCache) index(order *entities.Order) {
+	for _, field := range dto.LookupFields {
+		for _, value := range dto.LookupValues(order, field) {
+			if value == "" {
+				continue
+			}
+			orderUIDs, ok := c.indexes[field][value]
+			if !ok {
+				orderUIDs = make(map[string]struct{})
+				c.indexes[field][value] = orderUIDs
+			}
+			orderUIDs[order.OrderUID] = struct{}{}
+		}
+	}
+}
+
+func (c *lruCache) unindex(order *entities.Order) {
+	for _, field := range dto.LookupFields {
+		for _, value := range dto.LookupValues(order, field) {
+			orderUIDs := c.indexes[field][value]
+			delete(orderUIDs, order.OrderUID)
+			if len(orderUIDs) == 0 {
+				delete(c.indexes[field], value)
+				delete(c.complete[field], value)
+			}
+		}
+	}
+}
+
+func (c *lruCache) resetIndexes() {
+	c.indexes = make(map[dto.LookupField]map[string]map[string]struct{}, len(dto.LookupFields))
+	for _, field := range dto.LookupFields {
+		c.indexes[field] = make(map[string]map[string]struct{})
+	}
+	c.resetComplete()
+}
+
+func (c *lruCache) resetComplete() {
+	c.complete = make(map[dto.LookupField]map[string]struct{}, len(dto.LookupFields))
+	for _, field := range dto.LookupFields {
+		c.complete[field] = make(map[string]struct{})
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+func testOrder(orderUID, track string, rids ...string) *entities.Order {
+	order := &entities.Order{OrderUID: orderUID, TrackNumber: track}
+	for _, rid := range rids {
+		order.Items = append(order.Items, entities.Item{Rid: rid})
+	}
+	return order
+}
+
+// lookupUIDs возвращает отсортированные order_uid из Lookup и признак полного набора
+func lookupUIDs(c interface {
+	Lookup(dto.LookupField, string) ([]*entities.Order, bool)
+}, field dto.LookupField, value string) ([]string, bool) {
+	orders, ok := c.Lookup(field, value)
+	var orderUIDs []string
+	for _, order := range orders {
+		orderUIDs = append(orderUIDs, order.OrderUID)
+	}
+	slices.Sort(orderUIDs)
+	return orderUIDs, ok
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set(testOrder("a", "T1"))
+	c.SetLookup(dto.LookupTrackNumber, "T2", []*entities.Order{testOrder("b", "T2")})
+
+	// Чтение a делает b самым давним
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	c.Set(testOrder("c", "T3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, orderUID := range []string{"a", "c"} {
+		if _, ok := c.Get(orderUID); !ok {
+			t.Errorf("%s was evicted", orderUID)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T2"); ok {
+		t.Errorf("evicted order is still indexed: %v", got)
+	}
+}
+
+func TestLRUIndexes(t *testing.T) {
+	c := NewLRUCache(0)
+	a, b := testOrder("a", "T1", "r1", "r2"), testOrder("b", "T1", "r2")
+	c.SetLookup(dto.LookupTrackNumber, "T1", []*entities.Order{a, b})
+	c.SetLookup(dto.LookupRid, "r2", []*entities.Order{a, b})
+
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T1"); !ok || !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Lookup(track T1) = %v, %v, want [a b], true", got, ok)
+	}
+	if got, ok := lookupUIDs(c, dto.LookupRid, "r2"); !ok || !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Lookup(rid r2) = %v, %v, want [a b], true", got, ok)
+	}
+	// r1 в кэше есть, но полный набор для него из БД не загружался
+	if got, ok := lookupUIDs(c, dto.LookupRid, "r1"); ok {
+		t.Errorf("Lookup(rid r1) = %v, want miss", got)
+	}
+
+	// Новая версия заказа заменяет старые значения в индексах, набор T1 остается полным
+	c.Set(testOrder("a", "T2", "r3"))
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T1"); !ok || !slices.Equal(got, []string{"b"}) {
+		t.Errorf("Lookup(track T1) after update = %v, %v, want [b], true", got, ok)
+	}
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T2"); ok {
+		t.Errorf("Lookup(track T2) after update = %v, want miss", got)
+	}
+
+	// Удаление означает изменение заказа с неизвестными значениями полей
+	c.Delete("b")
+	if got, ok := lookupUIDs(c, dto.LookupRid, "r2"); ok {
+		t.Errorf("Lookup(rid r2) after delete = %v, want miss", got)
+	}
+
+	c.SetLookup(dto.LookupTrackNumber, "T2", []*entities.Order{testOrder("a", "T2", "r3")})
+	c.Clear()
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T2"); ok {
+		t.Errorf("Lookup(track T2) after clear = %v, want miss", got)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() after clear = %d, want 0", c.Len())
+	}
+}
+
+func TestLRULookupRequiresCompleteSet(t *testing.T) {
+	c := NewLRUCache(2)
+
+	// Заказ, попавший в кэш по order_uid, не делает набор полным
+	c.Set(testOrder("a", "T1"))
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T1"); ok {
+		t.Errorf("partial set returned as complete: %v", got)
+	}
+
+	// Набор больше емкости кэша вытесняет свои же заказы и полным не считается
+	c.SetLookup(dto.LookupTrackNumber, "T1", []*entities.Order{
+		testOrder("a", "T1"), testOrder("b", "T1"), testOrder("c", "T1"),
+	})
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T1"); ok {
+		t.Errorf("set larger than capacity returned as complete: %v", got)
+	}
+
+	// Пустой результат не запоминается
+	c.SetLookup(dto.LookupTrackNumber, "T9", nil)
+	if _, ok := c.Lookup(dto.LookupTrackNumber, "T9"); ok {
+		t.Error("empty result was cached")
+	}
+
+	// Изменение заказа другим экземпляром сбрасывает полноту всех наборов
+	c.Clear()
+	c.SetLookup(dto.LookupTrackNumber, "T2", []*entities.Order{testOrder("d", "T2")})
+	c.DeleteLocal("unknown")
+	if got, ok := lookupUIDs(c, dto.LookupTrackNumber, "T2"); ok {
+		t.Errorf("Lookup(track T2) after remote change = %v, want miss", got)
+	}
+}
+
+func TestLRUSkipsNilOrders(t *testing.T) {
+	c := NewLRUCache(10)
+	c.Set(nil, testOrder("a", "T1"), nil)
+
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
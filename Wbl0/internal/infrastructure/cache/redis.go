@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPipelineSize - количество заказов в одном конвейере записи
+const redisPipelineSize = 500
+
+// RedisConfig содержит параметры общего кэша в Redis
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix добавляется к order_uid в ключах Redis
+	KeyPrefix string
+	// TTL - время жизни заказа в кэше, 0 - без ограничения
+	TTL time.Duration
+	// Timeout ограничивает каждую операцию, чтобы медленный Redis не тормозил чтение из БД
+	Timeout time.Duration
+	// RetryInterval - пауза после ошибки, в течение которой Redis не опрашивается
+	RetryInterval time.Duration
+	Serializer    Serializer
+}
+
+// redisCache - общий для экземпляров сервиса кэш заказов в Redis.
+// Ошибки Redis журналируются и считаются промахом.
+type redisCache struct {
+	client *redis.Client
+	config RedisConfig
+
+	mu               sync.Mutex
+	unavailableUntil time.Time
+}
+
+// NewRedisCache создает кэш в Redis. Подключение проверяется лениво:
+// недоступный при старте Redis не мешает сервису запуститься.
+func NewRedisCache(cfg RedisConfig) interfaces.OrderCache {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "order:"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 200 * time.Millisecond
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 5 * time.Second
+	}
+	if cfg.Serializer == nil {
+		cfg.Serializer = jsonSerializer{}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+		MaxRetries:   -1,
+	})
+
+	return &redisCache{
+		client: client,
+		config: cfg,
+	}
+}
+
+func (c *redisCache) Get(orderUID string) (*entities.Order, bool) {
+	if !c.available() {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.key(orderUID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+	if err != nil {
+		c.fail("get", err)
+		return nil, false
+	}
+
+	order, err := c.config.Serializer.Unmarshal(data)
+	if err != nil {
+		log.Printf("Failed to decode order %s from redis cache: %v", orderUID, err)
+		return nil, false
+	}
+	// Значение, подмененное значением другого заказа, не принимается
+	if order.OrderUID != orderUID {
+		log.Printf("Redis cache entry %s holds order %s, ignoring", orderUID, order.OrderUID)
+		return nil, false
+	}
+	return order, true
+}
+
+// Lookup не поддерживается общим кэшем: вторичные индексы есть только в локальном
+func (c *redisCache) Lookup(field dto.LookupField, value string) ([]*entities.Order, bool) {
+	return nil, false
+}
+
+// SetLookup сохраняет только сами заказы
+func (c *redisCache) SetLookup(field dto.LookupField, value string, orders []*entities.Order) {
+	c.Set(orders...)
+}
+
+func (c *redisCache) Set(orders ...*entities.Order) {
+	if len(orders) == 0 || !c.available() {
+		return
+	}
+
+	// Большие пачки (например, при восстановлении кэша) пишутся частями,
+	// чтобы каждая укладывалась в таймаут операции
+	for start := 0; start < len(orders); start += redisPipelineSize {
+		if err := c.setChunk(orders[start:min(start+redisPipelineSize, len(orders))]); err != nil {
+			c.fail("set", err)
+			return
+		}
+	}
+}
+
+func (c *redisCache) setChunk(orders []*entities.Order) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	pipe := c.client.Pipeline()
+	for _, order := range orders {
+		if order == nil {
+			continue
+		}
+		data, err := c.config.Serializer.Marshal(order)
+		if err != nil {
+			log.Printf("Failed to encode order %s for redis cache: %v", order.OrderUID, err)
+			continue
+		}
+		pipe.Set(ctx, c.key(order.OrderUID), data, c.config.TTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (c *redisCache) Delete(orderUIDs ...string) {
+	if len(orderUIDs) == 0 || !c.available() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	keys := make([]string, len(orderUIDs))
+	for i, orderUID := range orderUIDs {
+		keys[i] = c.key(orderUID)
+	}
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		c.fail("delete", err)
+	}
+}
+
+// DeleteLocal ничего не делает: Redis - общий кэш
+func (c *redisCache) DeleteLocal(orderUIDs ...string) {}
+
+// Clear не очищает Redis: общий кэш используют другие экземпляры, а записи
+// в нем обновляет при сохранении тот, кто изменил заказ (сервис или import).
+// Если запись в Redis не удалась, устаревший заказ остается в нем до REDIS_TTL.
+func (c *redisCache) Clear() {}
+
+// Len не поддерживается для общего кэша и всегда возвращает 0
+func (c *redisCache) Len() int {
+	return 0
+}
+
+// Orders не поддерживается для общего кэша и всегда возвращает nil
+func (c *redisCache) Orders() []*entities.Order {
+	return nil
+}
+
+// Close закрывает соединения с Redis
+func (c *redisCache) Close() error {
+	return c.client.Close()
+}
+
+func (c *redisCache) key(orderUID string) string {
+	return c.config.KeyPrefix + orderUID
+}
+
+// available сообщает, можно ли обращаться к Redis, или он недавно был недоступен
+func (c *redisCache) available() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Now().After(c.unavailableUntil)
+}
+
+func (c *redisCache) fail(operation string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.unavailableUntil = time.Now().Add(c.config.RetryInterval)
+	log.Printf("Redis cache %s failed, falling back to database for %s: %v", operation, c.config.RetryInterval, err)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// sealedPrefix отмечает зашифрованное значение: sealed:<ID ключа данных>:<шифртекст>
+const sealedPrefix = "sealed:"
+
+// sealedOrderAAD привязывает шифртекст к значениям кэша заказов
+const sealedOrderAAD = "cache/order"
+
+// sealedSerializer шифрует сериализованный заказ ключом данных, чтобы данные
+// получателя не хранились во внешнем кэше открытым текстом
+type sealedSerializer struct {
+	inner  Serializer
+	cipher interfaces.PIICipher
+}
+
+// NewSealedSerializer оборачивает сериализатор шифрованием активным ключом данных.
+// Незашифрованные значения, записанные до включения шифрования, не читаются.
+func NewSealedSerializer(inner Serializer, cipher interfaces.PIICipher) Serializer {
+	return &sealedSerializer{inner: inner, cipher: cipher}
+}
+
+func (s *sealedSerializer) Marshal(order *entities.Order) ([]byte, error) {
+	data, err := s.inner.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	return seal(s.cipher, data, sealedOrderAAD)
+}
+
+func (s *sealedSerializer) Unmarshal(data []byte) (*entities.Order, error) {
+	plaintext, err := unseal(s.cipher, data, sealedOrderAAD)
+	if err != nil {
+		return nil, err
+	}
+	return s.inner.Unmarshal(plaintext)
+}
+
+// seal шифрует данные активным ключом и добавляет к шифртексту префикс с ID ключа
+func seal(cipher interfaces.PIICipher, data []byte, aad string) ([]byte, error) {
+	keyID := cipher.ActiveKeyID()
+	ciphertext, err := cipher.Encrypt(keyID, string(data), aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return []byte(sealedPrefix + strconv.Itoa(keyID) + ":" + ciphertext), nil
+}
+
+// unseal расшифровывает значение, записанное seal
+func unseal(cipher interfaces.PIICipher, data []byte, aad string) ([]byte, error) {
+	value, ok := strings.CutPrefix(string(data), sealedPrefix)
+	if !ok {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+	keyPart, ciphertext, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid encrypted value")
+	}
+	keyID, err := strconv.Atoi(keyPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key ID %q", keyPart)
+	}
+
+	plaintext, err := cipher.Decrypt(keyID, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return []byte(plaintext), nil
+}
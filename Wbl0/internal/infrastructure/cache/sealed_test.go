@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/encryption"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+const testPhone = "+79161234567"
+
+// memoryDataKeys хранит ключи данных в памяти
+type memoryDataKeys struct {
+	keys []dto.DataKey
+}
+
+func (r *memoryDataKeys) GetAll() ([]dto.DataKey, error) {
+	return r.keys, nil
+}
+
+func (r *memoryDataKeys) GetByID(id int) (*dto.DataKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryDataKeys) Create(key dto.DataKey) (int, error) {
+	key.ID = len(r.keys) + 1
+	r.keys = append(r.keys, key)
+	return key.ID, nil
+}
+
+func (r *memoryDataKeys) UpdateWrapped(keys []dto.DataKey) error {
+	return nil
+}
+
+func newTestCipher(t *testing.T) interfaces.PIICipher {
+	t.Helper()
+
+	key, err := encryption.GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	master, err := encryption.LoadMasterKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewKeyring(&memoryDataKeys{}, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func testOrderWithPhone(orderUID string) *entities.Order {
+	order := testOrder(orderUID, "T1")
+	order.Delivery.Phone = testPhone
+	return order
+}
+
+func TestSealedSerializerRoundTrip(t *testing.T) {
+	cipher := newTestCipher(t)
+
+	for _, format := range []string{SerializationJSON, SerializationMsgpack} {
+		inner, err := NewSerializer(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serializer := NewSealedSerializer(inner, cipher)
+
+		data, err := serializer.Marshal(testOrderWithPhone("a"))
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", format, err)
+		}
+		if bytes.Contains(data, []byte(testPhone)) {
+			t.Errorf("%s: sealed value contains plaintext phone", format)
+		}
+
+		order, err := serializer.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%s: Unmarshal() error = %v", format, err)
+		}
+		if order.OrderUID != "a" || order.Delivery.Phone != testPhone {
+			t.Errorf("%s: Unmarshal() = %s %s", format, order.OrderUID, order.Delivery.Phone)
+		}
+
+		// Значение, записанное до включения шифрования, не читается
+		plain, _ := inner.Marshal(testOrderWithPhone("a"))
+		if _, err := serializer.Unmarshal(plain); err == nil {
+			t.Errorf("%s: plaintext value was accepted", format)
+		}
+	}
+}
+
+func TestRedisRejectsSwappedSealedValue(t *testing.T) {
+	server := miniredis.RunT(t)
+	serializer := NewSealedSerializer(jsonSerializer{}, newTestCipher(t))
+	c := NewRedisCache(RedisConfig{Addr: server.Addr(), TTL: time.Hour, Serializer: serializer})
+	t.Cleanup(func() { c.(*redisCache).Close() })
+
+	c.Set(testOrderWithPhone("a"), testOrderWithPhone("b"))
+
+	value, err := server.Get("order:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(value, testPhone) {
+		t.Error("redis holds plaintext phone")
+	}
+	if order, ok := c.Get("a"); !ok || order.Delivery.Phone != testPhone {
+		t.Fatalf("Get(a) = %v, %v", order, ok)
+	}
+
+	// Значение заказа a под ключом b отклоняется
+	server.Set("order:b", value)
+	if order, ok := c.Get("b"); ok {
+		t.Errorf("Get(b) returned swapped order %s", order.OrderUID)
+	}
+}
+
+func TestEncryptedSnapshot(t *testing.T) {
+	cipher := newTestCipher(t)
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	snapshot := &dto.CacheSnapshot{
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Watermark: time.Now().UTC().Truncate(time.Second),
+		Orders:    []*entities.Order{testOrderWithPhone("a"), testOrderWithPhone("b")},
+	}
+
+	if err := NewFileSnapshotStore(path, cipher).Save(snapshot); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := NewFileSnapshotStore(path, cipher).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Orders) != 2 || loaded.Orders[0].Delivery.Phone != testPhone {
+		t.Errorf("Load() = %+v", loaded.Orders)
+	}
+	if !loaded.Watermark.Equal(snapshot.Watermark) {
+		t.Errorf("watermark = %s, want %s", loaded.Watermark, snapshot.Watermark)
+	}
+
+	// Без ключа зашифрованный снимок не читается
+	if _, err := NewFileSnapshotStore(path, nil).Load(); err == nil {
+		t.Error("encrypted snapshot was loaded without a data key")
+	}
+
+	// С ключом не читается открытый снимок, сохраненный до включения шифрования
+	if err := NewFileSnapshotStore(path, nil).Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileSnapshotStore(path, cipher).Load(); err == nil {
+		t.Error("plaintext snapshot was loaded with encryption enabled")
+	}
+}
+
+func TestEncryptedSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	store := NewFileSnapshotStore(path, newTestCipher(t))
+	snapshot := &dto.CacheSnapshot{Orders: []*entities.Order{testOrderWithPhone("a")}}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, _ := bytes.Cut(data, []byte("\n"))
+	if !bytes.Contains(header, []byte(`"encrypted":true`)) {
+		t.Errorf("snapshot header is not marked encrypted: %s", header)
+	}
+	if !bytes.HasPrefix(body, []byte(sealedPrefix)) {
+		t.Error("snapshot body is not sealed")
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"WbServis/Wbl0/internal/domain/entities"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Форматы сериализации заказов в общем кэше
+const (
+	SerializationJSON    = "json"
+	SerializationMsgpack = "msgpack"
+)
+
+// Serializer переводит заказ в байты для хранения во внешнем кэше
+type Serializer interface {
+	Marshal(order *entities.Order) ([]byte, error)
+	Unmarshal(data []byte) (*entities.Order, error)
+}
+
+// NewSerializer возвращает сериализатор по имени формата
+func NewSerializer(format string) (Serializer, error) {
+	switch format {
+	case "", SerializationJSON:
+		return jsonSerializer{}, nil
+	case SerializationMsgpack:
+		return msgpackSerializer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache serialization %q, expected json or msgpack", format)
+	}
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(order *entities.Order) ([]byte, error) {
+	return json.Marshal(order)
+}
+
+func (jsonSerializer) Unmarshal(data []byte) (*entities.Order, error) {
+	var order entities.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// msgpackSerializer использует json-теги сущностей, чтобы имена полей
+// совпадали в обоих форматах
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) Marshal(order *entities.Order) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(order); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackSerializer) Unmarshal(data []byte) (*entities.Order, error) {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+
+	var order entities.Order
+	if err := decoder.Decode(&order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
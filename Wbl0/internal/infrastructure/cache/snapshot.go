@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// snapshotVersion - версия формата файла снимка
+const snapshotVersion = 1
+
+// ErrNoSnapshot возвращается, если файла снимка нет
+var ErrNoSnapshot = errors.New("cache snapshot not found")
+
+// snapshotAAD привязывает шифртекст к файлу снимка
+const snapshotAAD = "cache/snapshot"
+
+// snapshotHeader - первая строка файла снимка. За ней идет сжатый gzip
+// JSON-массив заказов, при Encrypted - зашифрованный ключом данных.
+// Контрольная сумма считается по записанным в файл данным.
+type snapshotHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Watermark time.Time `json:"watermark"`
+	Count     int       `json:"count"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	SHA256    string    `json:"sha256"`
+}
+
+// fileSnapshotStore хранит снимок кэша в локальном файле
+type fileSnapshotStore struct {
+	path   string
+	cipher interfaces.PIICipher
+}
+
+// NewFileSnapshotStore создает хранилище снимка в файле path. С cipher снимок
+// шифруется, а незашифрованный снимок не загружается. cipher может быть nil.
+func NewFileSnapshotStore(path string, cipher interfaces.PIICipher) interfaces.CacheSnapshotStore {
+	return &fileSnapshotStore{path: path, cipher: cipher}
+}
+
+// Save пишет снимок во временный файл и переименовывает его, чтобы сбой
+// во время записи не испортил предыдущий снимок
+func (s *fileSnapshotStore) Save(snapshot *dto.CacheSnapshot) error {
+	var body bytes.Buffer
+	compressor := gzip.NewWriter(&body)
+	if err := json.NewEncoder(compressor).Encode(snapshot.Orders); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	data := body.Bytes()
+	if s.cipher != nil {
+		sealed, err := seal(s.cipher, data, snapshotAAD)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt snapshot: %w", err)
+		}
+		data = sealed
+	}
+
+	checksum := sha256.Sum256(data)
+	header, err := json.Marshal(snapshotHeader{
+		Version:   snapshotVersion,
+		CreatedAt: snapshot.CreatedAt,
+		Watermark: snapshot.Watermark,
+		Count:     len(snapshot.Orders),
+		Encrypted: s.cipher != nil,
+		SHA256:    hex.EncodeToString(checksum[:]),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot header: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(header, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *fileSnapshotStore) Load() (*dto.CacheSnapshot, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+
+	var header snapshotHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("invalid snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	checksum := sha256.Sum256(body)
+	if hex.EncodeToString(checksum[:]) != header.SHA256 {
+		return nil, fmt.Errorf("snapshot checksum mismatch")
+	}
+
+	// Открытый снимок при включенном шифровании не загружается, а перезаписывается
+	// зашифрованным при следующем сохранении
+	switch {
+	case header.Encrypted && s.cipher == nil:
+		return nil, fmt.Errorf("snapshot is encrypted, but no data key is configured")
+	case !header.Encrypted && s.cipher != nil:
+		return nil, fmt.Errorf("snapshot is not encrypted")
+	case header.Encrypted:
+		if body, err = unseal(s.cipher, body, snapshotAAD); err != nil {
+			return nil, fmt.Errorf("failed to decrypt snapshot: %w", err)
+		}
+	}
+
+	decompressor, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	defer decompressor.Close()
+
+	var orders []*entities.Order
+	if err := json.NewDecoder(decompressor).Decode(&orders); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if len(orders) != header.Count {
+		return nil, fmt.Errorf("snapshot contains %d orders, header says %d", len(orders), header.Count)
+	}
+
+	return &dto.CacheSnapshot{
+		CreatedAt: header.CreatedAt,
+		Watermark: header.Watermark,
+		Orders:    orders,
+	}, nil
+}
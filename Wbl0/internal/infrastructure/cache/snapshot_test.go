@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// saveTestSnapshot сохраняет снимок из двух заказов и возвращает путь к файлу
+func saveTestSnapshot(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	snapshot := &dto.CacheSnapshot{
+		CreatedAt: time.Now().UTC(),
+		Watermark: time.Now().UTC().Add(-time.Minute),
+		Orders:    []*entities.Order{testOrder("a", "T1"), testOrder("b", "T2")},
+	}
+	if err := NewFileSnapshotStore(path, nil).Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// rewriteSnapshot меняет заголовок и тело файла снимка. Если fixChecksum,
+// контрольная сумма пересчитывается по новому телу.
+func rewriteSnapshot(t *testing.T, path string, fixChecksum bool, change func(header *snapshotHeader, body []byte) []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, body, _ := bytes.Cut(data, []byte("\n"))
+	var header snapshotHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		t.Fatal(err)
+	}
+
+	body = change(&header, bytes.Clone(body))
+	if fixChecksum {
+		checksum := sha256.Sum256(body)
+		header.SHA256 = hex.EncodeToString(checksum[:])
+	}
+
+	line, err = json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(append(line, '\n'), body...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := saveTestSnapshot(t)
+
+	snapshot, err := NewFileSnapshotStore(path, nil).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(snapshot.Orders) != 2 || snapshot.Orders[0].OrderUID != "a" || snapshot.Orders[1].TrackNumber != "T2" {
+		t.Errorf("Load() orders = %+v", snapshot.Orders)
+	}
+
+	// Временные файлы не остаются рядом со снимком
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("snapshot directory has %d files, want 1", len(entries))
+	}
+}
+
+func TestSnapshotLoadMissingFile(t *testing.T) {
+	_, err := NewFileSnapshotStore(filepath.Join(t.TempDir(), "missing"), nil).Load()
+	if !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("Load() error = %v, want ErrNoSnapshot", err)
+	}
+}
+
+func TestSnapshotLoadRejectsCorruptFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixChecksum bool
+		change      func(header *snapshotHeader, body []byte) []byte
+		wantErr     string
+	}{
+		{
+			name: "bad checksum",
+			change: func(header *snapshotHeader, body []byte) []byte {
+				body[len(body)/2] ^= 0xff
+				return body
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			// Контрольная сумма сходится, но gzip обрезан, например диск заполнился
+			name:        "truncated gzip body",
+			fixChecksum: true,
+			change: func(header *snapshotHeader, body []byte) []byte {
+				return body[:len(body)/2]
+			},
+			wantErr: "failed to decode snapshot",
+		},
+		{
+			name:        "not gzip",
+			fixChecksum: true,
+			change: func(header *snapshotHeader, body []byte) []byte {
+				return []byte(`[{"order_uid": "a"}]`)
+			},
+			wantErr: "failed to decompress snapshot",
+		},
+		{
+			name: "count mismatch",
+			change: func(header *snapshotHeader, body []byte) []byte {
+				header.Count = 3
+				return body
+			},
+			wantErr: "contains 2 orders, header says 3",
+		},
+		{
+			name: "unknown version",
+			change: func(header *snapshotHeader, body []byte) []byte {
+				header.Version = snapshotVersion + 1
+				return body
+			},
+			wantErr: "unsupported snapshot version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := saveTestSnapshot(t)
+			rewriteSnapshot(t, path, tt.fixChecksum, tt.change)
+
+			snapshot, err := NewFileSnapshotStore(path, nil).Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+			if snapshot != nil {
+				t.Errorf("Load() returned %d orders from a corrupt snapshot", len(snapshot.Orders))
+			}
+		})
+	}
+}
+
+func TestSnapshotLoadRejectsMissingHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	if err := os.WriteFile(path, []byte(`{"version": 1`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileSnapshotStore(path, nil).Load(); err == nil {
+		t.Error("snapshot without header line was loaded")
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"io"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// tieredCache объединяет локальный кэш и общий: чтение идет сначала из
+// локального, затем из общего с заполнением локального
+type tieredCache struct {
+	local  interfaces.OrderCache
+	shared interfaces.OrderCache
+}
+
+// NewTieredCache создает двухуровневый кэш
+func NewTieredCache(local, shared interfaces.OrderCache) interfaces.OrderCache {
+	return &tieredCache{
+		local:  local,
+		shared: shared,
+	}
+}
+
+func (c *tieredCache) Get(orderUID string) (*entities.Order, bool) {
+	if order, ok := c.local.Get(orderUID); ok {
+		return order, true
+	}
+
+	order, ok := c.shared.Get(orderUID)
+	if ok {
+		c.local.Set(order)
+	}
+	return order, ok
+}
+
+func (c *tieredCache) Lookup(field dto.LookupField, value string) ([]*entities.Order, bool) {
+	return c.local.Lookup(field, value)
+}
+
+func (c *tieredCache) SetLookup(field dto.LookupField, value string, orders []*entities.Order) {
+	c.local.SetLookup(field, value, orders)
+	c.shared.Set(orders...)
+}
+
+func (c *tieredCache) Set(orders ...*entities.Order) {
+	c.local.Set(orders...)
+	c.shared.Set(orders...)
+}
+
+func (c *tieredCache) Delete(orderUIDs ...string) {
+	c.local.Delete(orderUIDs...)
+	c.shared.Delete(orderUIDs...)
+}
+
+func (c *tieredCache) DeleteLocal(orderUIDs ...string) {
+	c.local.DeleteLocal(orderUIDs...)
+}
+
+func (c *tieredCache) Clear() {
+	c.local.Clear()
+	c.shared.Clear()
+}
+
+func (c *tieredCache) Len() int {
+	return c.local.Len()
+}
+
+func (c *tieredCache) Orders() []*entities.Order {
+	return c.local.Orders()
+}
+
+// Close закрывает уровни, которые держат соединения
+func (c *tieredCache) Close() error {
+	for _, tier := range []interfaces.OrderCache{c.local, c.shared} {
+		if closer, ok := tier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
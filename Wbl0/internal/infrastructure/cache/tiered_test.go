@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestTieredCache возвращает двухуровневый кэш над miniredis и его уровни
+func newTestTieredCache(t *testing.T) (interfaces.OrderCache, interfaces.OrderCache, interfaces.OrderCache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	local := NewLRUCache(10)
+	shared := NewRedisCache(RedisConfig{Addr: server.Addr(), TTL: time.Hour})
+	t.Cleanup(func() { shared.(*redisCache).Close() })
+
+	return NewTieredCache(local, shared), local, shared, server
+}
+
+func TestTieredSetWritesBothTiers(t *testing.T) {
+	c, local, _, server := newTestTieredCache(t)
+	c.Set(testOrder("a", "T1"))
+
+	if _, ok := local.Get("a"); !ok {
+		t.Error("order is missing in local tier")
+	}
+	if !server.Exists("order:a") {
+		t.Error("order is missing in redis")
+	}
+	if ttl := server.TTL("order:a"); ttl != time.Hour {
+		t.Errorf("redis TTL = %s, want 1h", ttl)
+	}
+}
+
+func TestTieredGetFillsLocalTier(t *testing.T) {
+	c, local, shared, _ := newTestTieredCache(t)
+	shared.Set(testOrder("a", "T1"))
+
+	order, ok := c.Get("a")
+	if !ok || order.OrderUID != "a" {
+		t.Fatalf("Get() = %v, %v, want order a", order, ok)
+	}
+	if _, ok := local.Get("a"); !ok {
+		t.Error("order read from redis is not cached locally")
+	}
+}
+
+func TestTieredDeleteLocalKeepsSharedTier(t *testing.T) {
+	c, local, _, server := newTestTieredCache(t)
+	c.Set(testOrder("a", "T1"))
+
+	c.DeleteLocal("a")
+
+	if _, ok := local.Get("a"); ok {
+		t.Error("order is still in local tier")
+	}
+	if !server.Exists("order:a") {
+		t.Error("DeleteLocal removed order from redis")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("order is not read back from redis")
+	}
+}
+
+func TestTieredDeleteRemovesBothTiers(t *testing.T) {
+	c, local, _, server := newTestTieredCache(t)
+	c.Set(testOrder("a", "T1"))
+
+	c.Delete("a")
+
+	if _, ok := local.Get("a"); ok {
+		t.Error("order is still in local tier")
+	}
+	if server.Exists("order:a") {
+		t.Error("order is still in redis")
+	}
+}
+
+func TestTieredClearKeepsSharedTier(t *testing.T) {
+	c, local, _, server := newTestTieredCache(t)
+	c.Set(testOrder("a", "T1"))
+
+	c.Clear()
+
+	if local.Len() != 0 {
+		t.Errorf("local Len() = %d, want 0", local.Len())
+	}
+	if !server.Exists("order:a") {
+		t.Error("Clear removed order from redis")
+	}
+}
+
+func TestTieredRedisUnavailable(t *testing.T) {
+	c, local, _, server := newTestTieredCache(t)
+	server.Close()
+
+	c.Set(testOrder("a", "T1"))
+	if _, ok := local.Get("a"); !ok {
+		t.Error("order is missing in local tier")
+	}
+
+	local.Clear()
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() hit with redis unavailable and empty local tier")
+	}
+}
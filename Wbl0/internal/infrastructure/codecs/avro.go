@@ -0,0 +1,279 @@
+package codecs
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemaregistry"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroSchema - Avro-схема сообщения о заказе
+//
+//go:embed order.avsc
+var AvroSchema string
+
+// avroCodec кодирует заказ в Avro. Сообщения в wire-формате Confluent
+// декодируются схемой писателя из реестра, остальные - локальной схемой.
+type avroCodec struct {
+	local    *goavro.Codec
+	registry *schemaregistry.Client
+
+	mu      sync.RWMutex
+	writers map[int]*goavro.Codec
+}
+
+// NewAvroCodec создает Avro-кодек. registry может быть nil, тогда сообщения
+// в wire-формате Confluent не поддерживаются.
+func NewAvroCodec(registry *schemaregistry.Client) (Codec, error) {
+	local, err := goavro.NewCodec(AvroSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile avro schema: %w", err)
+	}
+
+	return &avroCodec{
+		local:    local,
+		registry: registry,
+		writers:  make(map[int]*goavro.Codec),
+	}, nil
+}
+
+func (c *avroCodec) Decode(message *dto.Message) (*entities.Order, error) {
+	codec := c.local
+	payload := message.Value
+
+	if schemaID, body, err := schemaregistry.DecodeWireFormat(payload); err == nil {
+		codec, err = c.writerCodec(schemaID)
+		if err != nil {
+			return nil, err
+		}
+		payload = body
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode avro: %w", err)
+	}
+
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("avro message is not a record")
+	}
+	return orderFromAvro(record), nil
+}
+
+func (c *avroCodec) Encode(order *entities.Order) ([]byte, error) {
+	return c.local.BinaryFromNative(nil, orderToAvro(order))
+}
+
+func (c *avroCodec) ContentType() string {
+	return "application/avro"
+}
+
+// writerCodec возвращает кодек схемы писателя из реестра
+func (c *avroCodec) writerCodec(schemaID int) (*goavro.Codec, error) {
+	if c.registry == nil {
+		return nil, errors.New("message uses schema registry wire format, but schema registry is not configured")
+	}
+
+	c.mu.RLock()
+	codec, ok := c.writers[schemaID]
+	c.mu.RUnlock()
+	if ok {
+		return codec, nil
+	}
+
+	schema, err := c.registry.SchemaByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	codec, err = goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile avro schema %d: %w", schemaID, err)
+	}
+
+	c.mu.Lock()
+	c.writers[schemaID] = codec
+	c.mu.Unlock()
+
+	return codec, nil
+}
+
+func orderToAvro(order *entities.Order) map[string]interface{} {
+	items := make([]interface{}, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, map[string]interface{}{
+			"chrt_id":      item.ChrtID,
+			"track_number": item.TrackNumber,
+			"price":        item.Price,
+			"rid":          item.Rid,
+			"name":         item.Name,
+			"sale":         item.Sale,
+			"size":         item.Size,
+			"total_price":  item.TotalPrice,
+			"nm_id":        item.NmID,
+			"brand":        item.Brand,
+			"status":       item.Status,
+		})
+	}
+
+	return map[string]interface{}{
+		"order_uid":    order.OrderUID,
+		"track_number": order.TrackNumber,
+		"entry":        order.Entry,
+		"delivery": map[string]interface{}{
+			"name":    order.Delivery.Name,
+			"phone":   order.Delivery.Phone,
+			"zip":     order.Delivery.Zip,
+			"city":    order.Delivery.City,
+			"address": order.Delivery.Address,
+			"region":  order.Delivery.Region,
+			"email":   order.Delivery.Email,
+		},
+		"payment": map[string]interface{}{
+			"transaction":   order.Payment.Transaction,
+			"request_id":    order.Payment.RequestID,
+			"currency":      order.Payment.Currency,
+			"provider":      order.Payment.Provider,
+			"amount":        order.Payment.Amount,
+			"payment_dt":    order.Payment.PaymentDt,
+			"bank":          order.Payment.Bank,
+			"delivery_cost": order.Payment.DeliveryCost,
+			"goods_total":   order.Payment.GoodsTotal,
+			"custom_fee":    order.Payment.CustomFee,
+		},
+		"items":              items,
+		"locale":             order.Locale,
+		"internal_signature": order.InternalSignature,
+		"customer_id":        order.CustomerID,
+		"delivery_service":   order.DeliveryService,
+		"shardkey":           order.ShardKey,
+		"sm_id":              order.SmID,
+		"date_created":       order.DateCreated,
+		"oof_shard":          order.OofShard,
+	}
+}
+
+func orderFromAvro(r avroRecord) *entities.Order {
+	delivery := r.record("delivery")
+	payment := r.record("payment")
+
+	order := &entities.Order{
+		OrderUID:    r.string("order_uid"),
+		TrackNumber: r.string("track_number"),
+		Entry:       r.string("entry"),
+		Delivery: entities.Delivery{
+			Name:    delivery.string("name"),
+			Phone:   delivery.string("phone"),
+			Zip:     delivery.string("zip"),
+			City:    delivery.string("city"),
+			Address: delivery.string("address"),
+			Region:  delivery.string("region"),
+			Email:   delivery.string("email"),
+		},
+		Payment: entities.Payment{
+			Transaction:  payment.string("transaction"),
+			RequestID:    payment.string("request_id"),
+			Currency:     payment.string("currency"),
+			Provider:     payment.string("provider"),
+			Amount:       int(payment.int64("amount")),
+			PaymentDt:    payment.int64("payment_dt"),
+			Bank:         payment.string("bank"),
+			DeliveryCost: int(payment.int64("delivery_cost")),
+			GoodsTotal:   int(payment.int64("goods_total")),
+			CustomFee:    int(payment.int64("custom_fee")),
+		},
+		Locale:            r.string("locale"),
+		InternalSignature: r.string("internal_signature"),
+		CustomerID:        r.string("customer_id"),
+		DeliveryService:   r.string("delivery_service"),
+		ShardKey:          r.string("shardkey"),
+		SmID:              int(r.int64("sm_id")),
+		DateCreated:       r.time("date_created"),
+		OofShard:          r.string("oof_shard"),
+	}
+
+	for _, value := range r.array("items") {
+		item, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ir := avroRecord(item)
+		order.Items = append(order.Items, entities.Item{
+			ChrtID:      int(ir.int64("chrt_id")),
+			TrackNumber: ir.string("track_number"),
+			Price:       int(ir.int64("price")),
+			Rid:         ir.string("rid"),
+			Name:        ir.string("name"),
+			Sale:        int(ir.int64("sale")),
+			Size:        ir.string("size"),
+			TotalPrice:  int(ir.int64("total_price")),
+			NmID:        int(ir.int64("nm_id")),
+			Brand:       ir.string("brand"),
+			Status:      int(ir.int64("status")),
+		})
+	}
+
+	return order
+}
+
+// avroRecord - запись Avro в нативном представлении goavro
+type avroRecord map[string]interface{}
+
+// value возвращает значение поля, разворачивая объединения вида {"string": "..."},
+// которые goavro возвращает для полей с типом-объединением
+func (r avroRecord) value(name string) interface{} {
+	value := r[name]
+	if union, ok := value.(map[string]interface{}); ok && len(union) == 1 {
+		for typeName, inner := range union {
+			switch typeName {
+			case "string", "int", "long", "long.timestamp-millis", "long.timestamp-micros":
+				return inner
+			}
+		}
+	}
+	return value
+}
+
+func (r avroRecord) string(name string) string {
+	value, _ := r.value(name).(string)
+	return value
+}
+
+func (r avroRecord) int64(name string) int64 {
+	switch value := r.value(name).(type) {
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	}
+	return 0
+}
+
+func (r avroRecord) time(name string) time.Time {
+	switch value := r.value(name).(type) {
+	case time.Time:
+		return value.UTC()
+	case int64:
+		return time.UnixMilli(value).UTC()
+	}
+	return time.Time{}
+}
+
+func (r avroRecord) record(name string) avroRecord {
+	value, _ := r.value(name).(map[string]interface{})
+	return value
+}
+
+func (r avroRecord) array(name string) []interface{} {
+	value, _ := r.value(name).([]interface{})
+	return value
+}
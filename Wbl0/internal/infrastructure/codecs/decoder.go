@@ -0,0 +1,140 @@
+package codecs
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemaregistry"
+)
+
+// Форматы сообщений о заказах
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+// ContentTypeHeader - заголовок сообщения с форматом тела
+const ContentTypeHeader = "content-type"
+
+// Codec кодирует и декодирует заказ в одном формате
+type Codec interface {
+	Decode(message *dto.Message) (*entities.Order, error)
+
+	Encode(order *entities.Order) ([]byte, error)
+
+	// ContentType возвращает значение заголовка content-type для формата
+	ContentType() string
+}
+
+// Config задает выбор формата сообщений
+type Config struct {
+	// DefaultFormat используется, если формат не задан ни заголовком, ни топиком
+	DefaultFormat string
+	// TopicFormats - формат по умолчанию для отдельных топиков
+	TopicFormats map[string]string
+	// Validator проверяет JSON-сообщения по схеме, может быть nil
+	Validator interfaces.MessageValidator
+	// Registry - реестр схем для сообщений в wire-формате Confluent, может быть nil
+	Registry *schemaregistry.Client
+}
+
+type orderDecoder struct {
+	codecs        map[string]Codec
+	defaultFormat string
+	topicFormats  map[string]string
+}
+
+// NewOrderDecoder создает декодер, выбирающий формат по заголовку content-type,
+// затем по настройке топика, затем по формату по умолчанию
+func NewOrderDecoder(config Config) (interfaces.OrderDecoder, error) {
+	avroCodec, err := NewAvroCodec(config.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &orderDecoder{
+		codecs: map[string]Codec{
+			FormatJSON:     NewJSONCodec(config.Validator),
+			FormatProtobuf: NewProtobufCodec(),
+			FormatAvro:     avroCodec,
+		},
+		defaultFormat: config.DefaultFormat,
+		topicFormats:  config.TopicFormats,
+	}
+
+	if d.defaultFormat == "" {
+		d.defaultFormat = FormatJSON
+	}
+	if _, ok := d.codecs[d.defaultFormat]; !ok {
+		return nil, fmt.Errorf("unknown message format %q", d.defaultFormat)
+	}
+	for topic, format := range d.topicFormats {
+		if _, ok := d.codecs[format]; !ok {
+			return nil, fmt.Errorf("unknown message format %q for topic %s", format, topic)
+		}
+	}
+
+	return d, nil
+}
+
+func (d *orderDecoder) Decode(message *dto.Message) (*entities.Order, error) {
+	format := d.defaultFormat
+	if topicFormat, ok := d.topicFormats[message.Topic]; ok {
+		format = topicFormat
+	}
+
+	if contentType := message.Headers[ContentTypeHeader]; contentType != "" {
+		headerFormat, err := FormatFromContentType(contentType)
+		if err != nil {
+			return nil, err
+		}
+		format = headerFormat
+	}
+
+	order, err := d.codecs[format].Decode(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s order: %w", format, err)
+	}
+	return order, nil
+}
+
+// FormatFromContentType определяет формат сообщения по значению content-type
+func FormatFromContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+
+	switch mediaType {
+	case "application/json", "application/schema+json":
+		return FormatJSON, nil
+	case "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf":
+		return FormatProtobuf, nil
+	case "application/avro", "avro/binary", "application/vnd.apache.avro+binary":
+		return FormatAvro, nil
+	default:
+		return "", fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// ParseTopicFormats разбирает список вида "orders=json,orders-proto=protobuf"
+func ParseTopicFormats(spec string) (map[string]string, error) {
+	formats := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		topic, format, ok := strings.Cut(pair, "=")
+		if !ok || topic == "" || format == "" {
+			return nil, fmt.Errorf("invalid topic format %q, expected topic=format", pair)
+		}
+		formats[strings.TrimSpace(topic)] = strings.TrimSpace(format)
+	}
+	return formats, nil
+}
@@ -0,0 +1,306 @@
+package codecs
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemaregistry"
+	"WbServis/Wbl0/pkg/schemas"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testOrder() *entities.Order {
+	return &entities.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: entities.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: entities.Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []entities.Item{{
+			ChrtID:      9934930,
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Rid:         "ab4219087a764ae0btest",
+			Name:        "Mascaras",
+			Sale:        30,
+			Size:        "0",
+			TotalPrice:  317,
+			NmID:        2389212,
+			Brand:       "Vivienne Sabo",
+			Status:      202,
+		}},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		ShardKey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+	}
+}
+
+// newTestRegistry запускает реестр схем в памяти и возвращает клиент к нему
+func newTestRegistry(t *testing.T) *schemaregistry.Client {
+	t.Helper()
+
+	server := httptest.NewServer(schemaregistry.NewStub())
+	t.Cleanup(server.Close)
+	return schemaregistry.NewClient(server.URL)
+}
+
+func newTestDecoder(t *testing.T, registry *schemaregistry.Client) *orderDecoder {
+	t.Helper()
+
+	decoder, err := NewOrderDecoder(Config{Registry: registry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoder.(*orderDecoder)
+}
+
+func assertOrder(t *testing.T, got *entities.Order) {
+	t.Helper()
+
+	want := testOrder()
+	got.DateCreated = got.DateCreated.UTC()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded order = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDecoderRoundTrip(t *testing.T) {
+	decoder := newTestDecoder(t, nil)
+
+	for format, codec := range decoder.codecs {
+		t.Run(format, func(t *testing.T) {
+			payload, err := codec.Encode(testOrder())
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			// Формат выбирается по заголовку content-type кодека
+			order, err := decoder.Decode(&dto.Message{
+				Topic:   "orders",
+				Value:   payload,
+				Headers: map[string]string{ContentTypeHeader: codec.ContentType()},
+			})
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			assertOrder(t, order)
+		})
+	}
+}
+
+func TestDecoderWireFormat(t *testing.T) {
+	registry := newTestRegistry(t)
+	decoder := newTestDecoder(t, registry)
+
+	avroID, err := registry.Register("orders-value", AvroSchema, schemaregistry.SchemaTypeAvro)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	avroPayload, err := decoder.codecs[FormatAvro].Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonPayload, err := decoder.codecs[FormatJSON].Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoPayload, err := decoder.codecs[FormatProtobuf].Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Индексы сообщения Protobuf: [0] сокращенно нулем или полной записью
+	shortIndexes := protowire.AppendVarint(nil, 0)
+	fullIndexes := protowire.AppendVarint(nil, protowire.EncodeZigZag(1))
+	fullIndexes = protowire.AppendVarint(fullIndexes, protowire.EncodeZigZag(0))
+
+	tests := []struct {
+		name    string
+		format  string
+		payload []byte
+	}{
+		{"avro", FormatAvro, schemaregistry.EncodeWireFormat(avroID, avroPayload)},
+		{"json", FormatJSON, schemaregistry.EncodeWireFormat(7, jsonPayload)},
+		{"protobuf short indexes", FormatProtobuf, schemaregistry.EncodeWireFormat(7, append(shortIndexes, protoPayload...))},
+		{"protobuf full indexes", FormatProtobuf, schemaregistry.EncodeWireFormat(7, append(fullIndexes, protoPayload...))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.payload[0] != 0 {
+				t.Fatalf("wire format magic byte = %d, want 0", tt.payload[0])
+			}
+			schemaID, _, err := schemaregistry.DecodeWireFormat(tt.payload)
+			if err != nil || (tt.format == FormatAvro && schemaID != avroID) {
+				t.Fatalf("DecodeWireFormat() = %d, %v", schemaID, err)
+			}
+
+			order, err := decoder.Decode(&dto.Message{
+				Topic:   "orders",
+				Value:   tt.payload,
+				Headers: map[string]string{ContentTypeHeader: decoder.codecs[tt.format].ContentType()},
+			})
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			assertOrder(t, order)
+		})
+	}
+}
+
+func TestDecoderWireFormatErrors(t *testing.T) {
+	avroPayload, err := newTestDecoder(t, nil).codecs[FormatAvro].Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoPayload, err := NewProtobufCodec().Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nestedIndex := protowire.AppendVarint(nil, protowire.EncodeZigZag(1))
+	nestedIndex = protowire.AppendVarint(nestedIndex, protowire.EncodeZigZag(1))
+
+	tests := []struct {
+		name     string
+		registry bool
+		format   string
+		payload  []byte
+		wantErr  string
+	}{
+		{"avro without registry", false, FormatAvro, schemaregistry.EncodeWireFormat(1, avroPayload), "schema registry is not configured"},
+		{"avro unknown schema ID", true, FormatAvro, schemaregistry.EncodeWireFormat(42, avroPayload), "42"},
+		{"protobuf nested message index", false, FormatProtobuf, schemaregistry.EncodeWireFormat(1, append(nestedIndex, protoPayload...)), "unsupported protobuf message index"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registry *schemaregistry.Client
+			if tt.registry {
+				registry = newTestRegistry(t)
+			}
+			decoder := newTestDecoder(t, registry)
+
+			_, err := decoder.Decode(&dto.Message{
+				Topic:   "orders",
+				Value:   tt.payload,
+				Headers: map[string]string{ContentTypeHeader: decoder.codecs[tt.format].ContentType()},
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoderFormatSelection(t *testing.T) {
+	decoder, err := NewOrderDecoder(Config{
+		DefaultFormat: FormatJSON,
+		TopicFormats:  map[string]string{"orders-avro": FormatAvro},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avroPayload, err := decoder.(*orderDecoder).codecs[FormatAvro].Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Без заголовка формат берется из настройки топика
+	order, err := decoder.Decode(&dto.Message{Topic: "orders-avro", Value: avroPayload})
+	if err != nil {
+		t.Fatalf("Decode() by topic format error = %v", err)
+	}
+	assertOrder(t, order)
+
+	// Заголовок важнее настройки топика
+	_, err = decoder.Decode(&dto.Message{
+		Topic:   "orders-avro",
+		Value:   avroPayload,
+		Headers: map[string]string{ContentTypeHeader: "application/json"},
+	})
+	if err == nil {
+		t.Error("avro payload decoded as json")
+	}
+
+	if _, err := decoder.Decode(&dto.Message{
+		Topic:   "orders",
+		Value:   avroPayload,
+		Headers: map[string]string{ContentTypeHeader: "text/plain"},
+	}); err == nil {
+		t.Error("unsupported content type accepted")
+	}
+}
+
+func TestJSONCodecSchemaVersionHeader(t *testing.T) {
+	validator, err := schemas.NewOrderValidator(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codec := NewJSONCodec(validator)
+	payload, err := codec.Encode(testOrder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	withDiscount := strings.Replace(string(payload), `{`, `{"discount":10,`, 1)
+
+	tests := []struct {
+		name    string
+		version string
+		payload string
+		wantErr string
+	}{
+		{name: "no header uses latest", payload: string(payload)},
+		{name: "version 1", version: "1", payload: string(payload)},
+		{name: "prefixed version", version: "v1", payload: string(payload)},
+		{name: "unknown version", version: "7", payload: string(payload), wantErr: schemas.ErrUnknownVersion.Error()},
+		{name: "unknown field in strict mode", version: "1", payload: withDiscount, wantErr: "discount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &dto.Message{Topic: "orders", Value: []byte(tt.payload)}
+			if tt.version != "" {
+				message.Headers = map[string]string{schemas.VersionHeader: tt.version}
+			}
+
+			order, err := codec.Decode(message)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			assertOrder(t, order)
+		})
+	}
+}
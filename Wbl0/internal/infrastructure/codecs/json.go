@@ -0,0 +1,57 @@
+package codecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemaregistry"
+	"WbServis/Wbl0/pkg/schemas"
+)
+
+type jsonCodec struct {
+	validator interfaces.MessageValidator
+}
+
+// NewJSONCodec создает JSON-кодек. Если validator не nil, входящие сообщения
+// проверяются по версии схемы из заголовка schema-version.
+func NewJSONCodec(validator interfaces.MessageValidator) Codec {
+	return &jsonCodec{validator: validator}
+}
+
+func (c *jsonCodec) Decode(message *dto.Message) (*entities.Order, error) {
+	payload := message.Value
+	// JSON Schema в wire-формате Confluent: схема уже закреплена идентификатором
+	if _, body, err := schemaregistry.DecodeWireFormat(payload); err == nil {
+		payload = body
+	}
+
+	if c.validator != nil {
+		version := message.Headers[schemas.VersionHeader]
+		problems, err := c.validator.Validate(version, payload)
+		if err != nil {
+			return nil, fmt.Errorf("schema validation failed: %w", err)
+		}
+		if len(problems) > 0 {
+			log.Printf("Warning: message does not match order schema: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	var order entities.Order
+	if err := json.Unmarshal(payload, &order); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order: %w", err)
+	}
+	return &order, nil
+}
+
+func (c *jsonCodec) Encode(order *entities.Order) ([]byte, error) {
+	return json.Marshal(order)
+}
+
+func (c *jsonCodec) ContentType() string {
+	return "application/json"
+}
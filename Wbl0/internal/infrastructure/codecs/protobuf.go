@@ -0,0 +1,364 @@
+package codecs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemaregistry"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protobufCodec кодирует заказ по определению order.proto.
+// Сообщения разбираются напрямую через protowire, без сгенерированного кода.
+type protobufCodec struct{}
+
+// NewProtobufCodec создает Protobuf-кодек
+func NewProtobufCodec() Codec {
+	return &protobufCodec{}
+}
+
+func (c *protobufCodec) Decode(message *dto.Message) (*entities.Order, error) {
+	payload := message.Value
+	if _, body, err := schemaregistry.DecodeWireFormat(payload); err == nil {
+		payload, err = stripMessageIndexes(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var order entities.Order
+	if err := decodeProtoOrder(payload, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (c *protobufCodec) Encode(order *entities.Order) ([]byte, error) {
+	return appendProtoOrder(nil, order), nil
+}
+
+func (c *protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+// stripMessageIndexes убирает индексы сообщения, которые wire-формат Confluent
+// добавляет для Protobuf после идентификатора схемы. Поддерживается только
+// первое сообщение файла - Order.
+func stripMessageIndexes(data []byte) ([]byte, error) {
+	count, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return nil, errors.New("invalid protobuf message indexes")
+	}
+	data = data[n:]
+
+	// Нулевое количество - сокращенная запись для индекса [0]
+	length := protowire.DecodeZigZag(count)
+	for i := int64(0); i < length; i++ {
+		index, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return nil, errors.New("invalid protobuf message indexes")
+		}
+		if protowire.DecodeZigZag(index) != 0 {
+			return nil, fmt.Errorf("unsupported protobuf message index %d", protowire.DecodeZigZag(index))
+		}
+		data = data[n:]
+	}
+
+	return data, nil
+}
+
+// protoFields обходит поля сообщения и передает каждое в callback
+func protoFields(data []byte, field func(num protowire.Number, typ protowire.Type, value []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		consumed, err := field(num, typ, data)
+		if err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+		if consumed == 0 {
+			// Неизвестное поле - пропускаем
+			consumed = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if consumed < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(consumed))
+		}
+		data = data[consumed:]
+	}
+	return nil
+}
+
+func consumeString(typ protowire.Type, data []byte, dst *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errors.New("expected length-delimited value")
+	}
+	value, n := protowire.ConsumeString(data)
+	*dst = value
+	return n, nil
+}
+
+func consumeInt(typ protowire.Type, data []byte, dst *int) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errors.New("expected varint value")
+	}
+	value, n := protowire.ConsumeVarint(data)
+	*dst = int(int64(value))
+	return n, nil
+}
+
+func consumeInt64(typ protowire.Type, data []byte, dst *int64) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errors.New("expected varint value")
+	}
+	value, n := protowire.ConsumeVarint(data)
+	*dst = int64(value)
+	return n, nil
+}
+
+func consumeMessage(typ protowire.Type, data []byte, decode func([]byte) error) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errors.New("expected embedded message")
+	}
+	value, n := protowire.ConsumeBytes(data)
+	if n < 0 {
+		return n, nil
+	}
+	return n, decode(value)
+}
+
+func decodeProtoOrder(data []byte, order *entities.Order) error {
+	return protoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, value, &order.OrderUID)
+		case 2:
+			return consumeString(typ, value, &order.TrackNumber)
+		case 3:
+			return consumeString(typ, value, &order.Entry)
+		case 4:
+			return consumeMessage(typ, value, func(b []byte) error { return decodeProtoDelivery(b, &order.Delivery) })
+		case 5:
+			return consumeMessage(typ, value, func(b []byte) error { return decodeProtoPayment(b, &order.Payment) })
+		case 6:
+			return consumeMessage(typ, value, func(b []byte) error {
+				var item entities.Item
+				if err := decodeProtoItem(b, &item); err != nil {
+					return err
+				}
+				order.Items = append(order.Items, item)
+				return nil
+			})
+		case 7:
+			return consumeString(typ, value, &order.Locale)
+		case 8:
+			return consumeString(typ, value, &order.InternalSignature)
+		case 9:
+			return consumeString(typ, value, &order.CustomerID)
+		case 10:
+			return consumeString(typ, value, &order.DeliveryService)
+		case 11:
+			return consumeString(typ, value, &order.ShardKey)
+		case 12:
+			return consumeInt(typ, value, &order.SmID)
+		case 13:
+			return consumeMessage(typ, value, func(b []byte) error { return decodeProtoTimestamp(b, &order.DateCreated) })
+		case 14:
+			return consumeString(typ, value, &order.OofShard)
+		}
+		return 0, nil
+	})
+}
+
+func decodeProtoDelivery(data []byte, delivery *entities.Delivery) error {
+	return protoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, value, &delivery.Name)
+		case 2:
+			return consumeString(typ, value, &delivery.Phone)
+		case 3:
+			return consumeString(typ, value, &delivery.Zip)
+		case 4:
+			return consumeString(typ, value, &delivery.City)
+		case 5:
+			return consumeString(typ, value, &delivery.Address)
+		case 6:
+			return consumeString(typ, value, &delivery.Region)
+		case 7:
+			return consumeString(typ, value, &delivery.Email)
+		}
+		return 0, nil
+	})
+}
+
+func decodeProtoPayment(data []byte, payment *entities.Payment) error {
+	return protoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, value, &payment.Transaction)
+		case 2:
+			return consumeString(typ, value, &payment.RequestID)
+		case 3:
+			return consumeString(typ, value, &payment.Currency)
+		case 4:
+			return consumeString(typ, value, &payment.Provider)
+		case 5:
+			return consumeInt(typ, value, &payment.Amount)
+		case 6:
+			return consumeInt64(typ, value, &payment.PaymentDt)
+		case 7:
+			return consumeString(typ, value, &payment.Bank)
+		case 8:
+			return consumeInt(typ, value, &payment.DeliveryCost)
+		case 9:
+			return consumeInt(typ, value, &payment.GoodsTotal)
+		case 10:
+			return consumeInt(typ, value, &payment.CustomFee)
+		}
+		return 0, nil
+	})
+}
+
+func decodeProtoItem(data []byte, item *entities.Item) error {
+	return protoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeInt(typ, value, &item.ChrtID)
+		case 2:
+			return consumeString(typ, value, &item.TrackNumber)
+		case 3:
+			return consumeInt(typ, value, &item.Price)
+		case 4:
+			return consumeString(typ, value, &item.Rid)
+		case 5:
+			return consumeString(typ, value, &item.Name)
+		case 6:
+			return consumeInt(typ, value, &item.Sale)
+		case 7:
+			return consumeString(typ, value, &item.Size)
+		case 8:
+			return consumeInt(typ, value, &item.TotalPrice)
+		case 9:
+			return consumeInt(typ, value, &item.NmID)
+		case 10:
+			return consumeString(typ, value, &item.Brand)
+		case 11:
+			return consumeInt(typ, value, &item.Status)
+		}
+		return 0, nil
+	})
+}
+
+// decodeProtoTimestamp разбирает google.protobuf.Timestamp
+func decodeProtoTimestamp(data []byte, dst *time.Time) error {
+	var seconds int64
+	var nanos int
+	err := protoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeInt64(typ, value, &seconds)
+		case 2:
+			return consumeInt(typ, value, &nanos)
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return err
+	}
+	*dst = time.Unix(seconds, int64(nanos)).UTC()
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendInt(b []byte, num protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func appendMessage(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func appendProtoOrder(b []byte, order *entities.Order) []byte {
+	b = appendString(b, 1, order.OrderUID)
+	b = appendString(b, 2, order.TrackNumber)
+	b = appendString(b, 3, order.Entry)
+	b = appendMessage(b, 4, appendProtoDelivery(nil, &order.Delivery))
+	b = appendMessage(b, 5, appendProtoPayment(nil, &order.Payment))
+	for i := range order.Items {
+		b = appendMessage(b, 6, appendProtoItem(nil, &order.Items[i]))
+	}
+	b = appendString(b, 7, order.Locale)
+	b = appendString(b, 8, order.InternalSignature)
+	b = appendString(b, 9, order.CustomerID)
+	b = appendString(b, 10, order.DeliveryService)
+	b = appendString(b, 11, order.ShardKey)
+	b = appendInt(b, 12, int64(order.SmID))
+	if !order.DateCreated.IsZero() {
+		var timestamp []byte
+		timestamp = appendInt(timestamp, 1, order.DateCreated.Unix())
+		timestamp = appendInt(timestamp, 2, int64(order.DateCreated.Nanosecond()))
+		b = appendMessage(b, 13, timestamp)
+	}
+	b = appendString(b, 14, order.OofShard)
+	return b
+}
+
+func appendProtoDelivery(b []byte, delivery *entities.Delivery) []byte {
+	b = appendString(b, 1, delivery.Name)
+	b = appendString(b, 2, delivery.Phone)
+	b = appendString(b, 3, delivery.Zip)
+	b = appendString(b, 4, delivery.City)
+	b = appendString(b, 5, delivery.Address)
+	b = appendString(b, 6, delivery.Region)
+	b = appendString(b, 7, delivery.Email)
+	return b
+}
+
+func appendProtoPayment(b []byte, payment *entities.Payment) []byte {
+	b = appendString(b, 1, payment.Transaction)
+	b = appendString(b, 2, payment.RequestID)
+	b = appendString(b, 3, payment.Currency)
+	b = appendString(b, 4, payment.Provider)
+	b = appendInt(b, 5, int64(payment.Amount))
+	b = appendInt(b, 6, payment.PaymentDt)
+	b = appendString(b, 7, payment.Bank)
+	b = appendInt(b, 8, int64(payment.DeliveryCost))
+	b = appendInt(b, 9, int64(payment.GoodsTotal))
+	b = appendInt(b, 10, int64(payment.CustomFee))
+	return b
+}
+
+func appendProtoItem(b []byte, item *entities.Item) []byte {
+	b = appendInt(b, 1, int64(item.ChrtID))
+	b = appendString(b, 2, item.TrackNumber)
+	b = appendInt(b, 3, int64(item.Price))
+	b = appendString(b, 4, item.Rid)
+	b = appendString(b, 5, item.Name)
+	b = appendInt(b, 6, int64(item.Sale))
+	b = appendString(b, 7, item.Size)
+	b = appendInt(b, 8, int64(item.TotalPrice))
+	b = appendInt(b, 9, int64(item.NmID))
+	b = appendString(b, 10, item.Brand)
+	b = appendInt(b, 11, int64(item.Status))
+	return b
+}
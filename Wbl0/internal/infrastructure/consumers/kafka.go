@@ -4,45 +4,175 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"regexp"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"WbServis/Wbl0/internal/application/dto"
 	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/pkg/kafkaconfig"
 
 	"github.com/IBM/sarama"
 )
 
+// KafkaConfig содержит параметры подключения потребителя к Kafka
+type KafkaConfig struct {
+	Brokers []string
+	GroupID string
+	Topics  []string
+	// TopicPattern - регулярное выражение для подписки на топики по имени, дополняет Topics
+	TopicPattern string
+	// TopicRefreshInterval - период проверки появления новых топиков под TopicPattern
+	TopicRefreshInterval time.Duration
+	// RebalanceStrategies - стратегии распределения партиций в порядке приоритета:
+	// range, roundrobin, sticky
+	RebalanceStrategies []string
+	// InitialOffset - откуда начинать чтение при отсутствии закоммиченного офсета: oldest или newest
+	InitialOffset string
+	// CommitInterval - период ручного коммита отмеченных офсетов
+	CommitInterval time.Duration
+	// LagInterval - период замера отставания группы
+	LagInterval time.Duration
+	// LagWarnThreshold - отставание партиции в сообщениях, после которого пишется предупреждение.
+	// 0 отключает предупреждения.
+	LagWarnThreshold int64
+	// Client - параметры подключения: TLS, SASL, client ID и версия протокола
+	Client kafkaconfig.Config
+	Pool   WorkerPoolConfig
+}
+
 type kafkaConsumer struct {
-	consumer sarama.ConsumerGroup
-	topics   []string
-	handler  interfaces.OrderService
-	ctx      context.Context
-	cancel   context.CancelFunc
-	wg       sync.WaitGroup
+	client         sarama.Client
+	admin          sarama.ClusterAdmin
+	consumer       sarama.ConsumerGroup
+	groupID        string
+	staticTopics   []string
+	topicPattern   *regexp.Regexp
+	topicRefresh   time.Duration
+	handler        interfaces.MessageHandler
+	pool           WorkerPoolConfig
+	commitInterval time.Duration
+	lag            *lagTracker
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+
+	// stopping закрывается в Stop: сессии перестают читать новые сообщения.
+	// abortCtx отменяется, если дообработка не уложилась в срок.
+	stopping  chan struct{}
+	drained   chan struct{}
+	abortCtx  context.Context
+	abort     context.CancelFunc
+	stopOnce  sync.Once
+	drainOnce sync.Once
+
+	mu            sync.Mutex
+	topics        []string
+	sessionCancel context.CancelFunc
+	activeClaims  int
+	stopped       bool
+	commitStop    chan struct{}
+	commitDone    chan struct{}
+	pendingResets map[string]map[int32]int64
+	// resetApplied получает партиции, к которым Setup применил pendingResets
+	resetApplied chan map[string]map[int32]bool
+	// resetMu не дает двум сбросам ждать применения одновременно
+	resetMu sync.Mutex
+	paused  bool
 }
 
-func NewKafkaConsumer(brokers []string, groupID string, topics []string, handler interfaces.OrderService) (interfaces.MessageConsumer, error) {
-	config := sarama.NewConfig()
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+func NewKafkaConsumer(cfg KafkaConfig, handler interfaces.MessageHandler) (interfaces.MessageConsumer, error) {
+	config, err := kafkaconfig.NewSaramaConfig(cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+	strategies, err := balanceStrategies(cfg.RebalanceStrategies)
+	if err != nil {
+		return nil, err
+	}
+	config.Consumer.Group.Rebalance.GroupStrategies = strategies
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	switch cfg.InitialOffset {
+	case "", "oldest":
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "newest":
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	default:
+		return nil, fmt.Errorf("unknown initial offset %q", cfg.InitialOffset)
+	}
+
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = time.Second
+	}
+
+	var topicPattern *regexp.Regexp
+	if cfg.TopicPattern != "" {
+		topicPattern, err = regexp.Compile(cfg.TopicPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic pattern: %w", err)
+		}
+	}
+	if topicPattern == nil && len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("no topics configured")
+	}
+	if cfg.TopicRefreshInterval <= 0 {
+		cfg.TopicRefreshInterval = time.Minute
+	}
+
+	client, err := sarama.NewClient(cfg.Brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
 
-	consumer, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	consumer, err := sarama.NewConsumerGroupFromClient(cfg.GroupID, client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		consumer.Close()
+		client.Close()
+		return nil, fmt.Errorf("failed to create cluster admin: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	abortCtx, abort := context.WithCancel(context.Background())
+
+	k := &kafkaConsumer{
+		client:         client,
+		admin:          admin,
+		consumer:       consumer,
+		groupID:        cfg.GroupID,
+		staticTopics:   cfg.Topics,
+		topicPattern:   topicPattern,
+		topicRefresh:   cfg.TopicRefreshInterval,
+		topics:         cfg.Topics,
+		handler:        handler,
+		pool:           cfg.Pool.normalize(),
+		commitInterval: cfg.CommitInterval,
+		ctx:            ctx,
+		cancel:         cancel,
+		stopping:       make(chan struct{}),
+		drained:        make(chan struct{}),
+		abortCtx:       abortCtx,
+		abort:          abort,
+	}
+	k.lag = newLagTracker(k.Offsets, cfg.LagInterval, cfg.LagWarnThreshold)
 
-	return &kafkaConsumer{
-		consumer: consumer,
-		topics:   topics,
-		handler:  handler,
-		ctx:      ctx,
-		cancel:   cancel,
-	}, nil
+	return k, nil
 }
 
 func (k *kafkaConsumer) Start() error {
-	log.Printf("Starting Kafka consumer for topics: %v", k.topics)
+	if k.topicPattern != nil {
+		log.Printf("Starting Kafka consumer for topics: %v and pattern %s", k.staticTopics, k.topicPattern)
+	} else {
+		log.Printf("Starting Kafka consumer for topics: %v", k.staticTopics)
+	}
 
 	k.wg.Add(1)
 	go func() {
@@ -51,8 +181,34 @@ func (k *kafkaConsumer) Start() error {
 			select {
 			case <-k.ctx.Done():
 				return
+			case <-k.stopping:
+				// Новые сессии после остановки не начинаем
+				return
 			default:
-				err := k.consumer.Consume(k.ctx, k.topics, k)
+				topics, err := k.resolveTopics()
+				if err != nil || len(topics) == 0 {
+					if err != nil {
+						log.Printf("Failed to resolve topics: %v", err)
+					} else {
+						log.Printf("No topics match pattern %s, waiting", k.topicPattern)
+					}
+					select {
+					case <-time.After(k.topicRefresh):
+					case <-k.ctx.Done():
+					}
+					continue
+				}
+
+				// Каждая сессия получает собственный контекст, чтобы сброс
+				// офсетов мог перезапустить ее, не останавливая потребителя
+				sessionCtx, sessionCancel := context.WithCancel(k.ctx)
+				k.mu.Lock()
+				k.topics = topics
+				k.sessionCancel = sessionCancel
+				k.mu.Unlock()
+
+				err = k.consumer.Consume(sessionCtx, topics, k)
+				sessionCancel()
 				if err != nil {
 					log.Printf("Error from consumer: %v", err)
 				}
@@ -60,46 +216,296 @@ func (k *kafkaConsumer) Start() error {
 		}
 	}()
 
+	if k.topicPattern != nil {
+		k.wg.Add(1)
+		go k.watchTopics()
+	}
+
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		k.lag.run(k.ctx)
+	}()
+
 	return nil
 }
 
-func (k *kafkaConsumer) Stop() error {
-	log.Println("Stopping Kafka consumer...")
+// Lag возвращает последний замер отставания группы
+func (k *kafkaConsumer) Lag() dto.ConsumerLag {
+	return k.lag.lag()
+}
+
+// resolveTopics возвращает явно заданные топики и топики, подходящие под шаблон
+func (k *kafkaConsumer) resolveTopics() ([]string, error) {
+	if k.topicPattern == nil {
+		return k.staticTopics, nil
+	}
+
+	if err := k.client.RefreshMetadata(); err != nil {
+		return nil, fmt.Errorf("failed to refresh metadata: %w", err)
+	}
+	all, err := k.client.Topics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+
+	topics := slices.Clone(k.staticTopics)
+	for _, topic := range all {
+		// Служебные топики (__consumer_offsets и т.п.) не читаем
+		if strings.HasPrefix(topic, "__") || !k.topicPattern.MatchString(topic) {
+			continue
+		}
+		if !slices.Contains(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	slices.Sort(topics)
+	return topics, nil
+}
+
+// watchTopics перезапускает сессию, когда набор топиков под шаблоном меняется
+func (k *kafkaConsumer) watchTopics() {
+	defer k.wg.Done()
+
+	ticker := time.NewTicker(k.topicRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			topics, err := k.resolveTopics()
+			if err != nil {
+				log.Printf("Failed to resolve topics: %v", err)
+				continue
+			}
+
+			k.mu.Lock()
+			changed := !slices.Equal(topics, k.topics)
+			sessionCancel := k.sessionCancel
+			k.mu.Unlock()
+
+			if changed && sessionCancel != nil {
+				log.Printf("Subscribed topics changed to %v, restarting session", topics)
+				sessionCancel()
+			}
+
+		case <-k.ctx.Done():
+			return
+		}
+	}
+}
+
+// currentTopics возвращает топики текущей подписки
+func (k *kafkaConsumer) currentTopics() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.topics
+}
+
+// Stop прекращает чтение новых сообщений и ждет, пока воркеры обработают уже
+// полученные. Если ctx истекает раньше, оставшиеся сообщения бросаются без
+// подтверждения и будут перечитаны с последнего закоммиченного офсета.
+func (k *kafkaConsumer) Stop(ctx context.Context) error {
+	log.Println("Stopping Kafka consumer: no new messages will be fetched")
+
+	k.stopOnce.Do(func() {
+		k.mu.Lock()
+		k.stopped = true
+		close(k.stopping)
+		if k.activeClaims == 0 {
+			k.closeDrained()
+		}
+		k.mu.Unlock()
+	})
+
+	select {
+	case <-k.drained:
+		log.Println("Kafka consumer drained in-flight messages")
+		return nil
+	case <-ctx.Done():
+		k.abort()
+		return fmt.Errorf("in-flight messages were not drained: %w", ctx.Err())
+	}
+}
+
+// Close завершает сессию группы, коммитя отмеченные офсеты в Cleanup,
+// и закрывает клиентов
+func (k *kafkaConsumer) Close() error {
+	k.abort()
 	k.cancel()
 	k.wg.Wait()
-	return nil
+	log.Println("Kafka consumer session closed, offsets committed")
+
+	if err := k.consumer.Close(); err != nil {
+		return err
+	}
+	// ClusterAdmin закрывает и общий клиент
+	return k.admin.Close()
 }
 
-func (k *kafkaConsumer) Close() error {
-	return k.consumer.Close()
+func (k *kafkaConsumer) closeDrained() {
+	k.drainOnce.Do(func() { close(k.drained) })
 }
 
-func (k *kafkaConsumer) Setup(sarama.ConsumerGroupSession) error {
+func (k *kafkaConsumer) Setup(session sarama.ConsumerGroupSession) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.applyPendingResets(session)
+
+	k.commitStop = make(chan struct{})
+	k.commitDone = make(chan struct{})
+	go k.commitLoop(session, k.commitStop, k.commitDone)
+
 	log.Println("Kafka consumer setup completed")
 	return nil
 }
 
-func (k *kafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+func (k *kafkaConsumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	k.mu.Lock()
+	close(k.commitStop)
+	done := k.commitDone
+	k.mu.Unlock()
+	<-done
+
+	// Все ConsumeClaim к этому моменту завершены - фиксируем последние отмеченные офсеты
+	session.Commit()
+
 	log.Println("Kafka consumer cleanup completed")
 	return nil
 }
 
+// commitLoop периодически коммитит отмеченные офсеты сессии
+func (k *kafkaConsumer) commitLoop(session sarama.ConsumerGroupSession, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(k.commitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			session.Commit()
+		case <-stop:
+			return
+		}
+	}
+}
+
 func (k *kafkaConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	k.mu.Lock()
+	if k.paused {
+		k.consumer.Pause(map[string][]int32{claim.Topic(): {claim.Partition()}})
+	}
+	k.activeClaims++
+	k.mu.Unlock()
+
+	defer func() {
+		k.mu.Lock()
+		k.activeClaims--
+		if k.stopped && k.activeClaims == 0 {
+			k.closeDrained()
+		}
+		k.mu.Unlock()
+	}()
+
+	// Контекст воркеров не наследуется от сессии: при остановке сессия
+	// завершается, как только первая партиция дообработана, а остальные
+	// должны успеть дообработать свои сообщения
+	claimCtx, claimCancel := context.WithCancel(k.abortCtx)
+	defer claimCancel()
+
+	tracker := newOffsetTracker()
+	processor := newProcessor(claimCtx, k.handler, k.pool)
+	defer processor.close()
+
 	for {
+		// Проверяем остановку до выбора сообщения: select выбирает
+		// среди готовых веток случайно
+		select {
+		case <-k.stopping:
+			return nil
+		default:
+		}
+
 		select {
-		case message := <-claim.Messages():
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
 			log.Printf("Received message from topic %s, partition %d, offset %d",
 				message.Topic, message.Partition, message.Offset)
 
-			err := k.handler.ProcessMessage(message.Value)
-			if err != nil {
-				log.Printf("Failed to process message: %v", err)
-			} else {
-				session.MarkMessage(message, "")
+			tracker.track(message.Offset)
+			ack := func() {
+				if offset, ok := tracker.complete(message.Offset); ok {
+					session.MarkOffset(message.Topic, message.Partition, offset+1, "")
+				}
 			}
 
+			if !processor.dispatch(delivery{message: toMessage(message), ack: ack}) {
+				return nil
+			}
+
+		case <-k.stopping:
+			return nil
+
 		case <-session.Context().Done():
+			// Ребалансировка: партиция может уйти другому экземпляру,
+			// поэтому оставшиеся сообщения не дообрабатываем
+			select {
+			case <-k.stopping:
+			default:
+				claimCancel()
+			}
 			return nil
 		}
 	}
 }
+
+// toMessage переводит сообщение Kafka в формат, не зависящий от брокера
+func toMessage(message *sarama.ConsumerMessage) *dto.Message {
+	headers := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		if header != nil {
+			headers[strings.ToLower(string(header.Key))] = string(header.Value)
+		}
+	}
+
+	return &dto.Message{
+		Topic:     message.Topic,
+		Key:       message.Key,
+		Value:     message.Value,
+		Headers:   headers,
+		Timestamp: message.Timestamp,
+	}
+}
+
+// balanceStrategies переводит имена стратегий в стратегии sarama
+func balanceStrategies(names []string) ([]sarama.BalanceStrategy, error) {
+	if len(names) == 0 {
+		return []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}, nil
+	}
+
+	strategies := make([]sarama.BalanceStrategy, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case sarama.RangeBalanceStrategyName:
+			strategies = append(strategies, sarama.NewBalanceStrategyRange())
+		case sarama.RoundRobinBalanceStrategyName, "round-robin":
+			strategies = append(strategies, sarama.NewBalanceStrategyRoundRobin())
+		case sarama.StickyBalanceStrategyName:
+			strategies = append(strategies, sarama.NewBalanceStrategySticky())
+		case "cooperative-sticky":
+			// sarama поддерживает только eager-протокол ребалансировки.
+			// Sticky дает то же распределение, но с отзывом всех партиций на время ребалансировки.
+			log.Println("Warning: cooperative rebalancing is not supported by the Kafka client, using sticky")
+			strategies = append(strategies, sarama.NewBalanceStrategySticky())
+		default:
+			return nil, fmt.Errorf("unknown rebalance strategy %q, expected range, roundrobin, sticky or cooperative-sticky", name)
+		}
+	}
+	return strategies, nil
+}
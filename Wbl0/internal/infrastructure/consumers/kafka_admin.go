@@ -0,0 +1,272 @@
+package consumers
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+
+	"github.com/IBM/sarama"
+)
+
+// Offsets возвращает закоммиченные офсеты группы, начало лога и high-water mark
+// для всех партиций топиков потребителя
+func (k *kafkaConsumer) Offsets() ([]dto.PartitionOffsets, error) {
+	topics := k.currentTopics()
+	topicPartitions, err := k.topicPartitions(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	committed, err := k.admin.ListConsumerGroupOffsets(k.groupID, topicPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumer group offsets: %w", err)
+	}
+
+	var offsets []dto.PartitionOffsets
+	for _, topic := range topics {
+		for _, partition := range topicPartitions[topic] {
+			logStart, err := k.client.GetOffset(topic, partition, sarama.OffsetOldest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get log start offset for %s/%d: %w", topic, partition, err)
+			}
+			highWaterMark, err := k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get high-water mark for %s/%d: %w", topic, partition, err)
+			}
+
+			committedOffset := int64(-1)
+			if block := committed.GetBlock(topic, partition); block != nil {
+				committedOffset = block.Offset
+			}
+
+			// Без закоммиченного офсета отставание считаем от начала лога
+			lag := highWaterMark - logStart
+			if committedOffset >= 0 {
+				lag = highWaterMark - committedOffset
+			}
+
+			offsets = append(offsets, dto.PartitionOffsets{
+				Topic:         topic,
+				Partition:     partition,
+				Committed:     committedOffset,
+				LogStart:      logStart,
+				HighWaterMark: highWaterMark,
+				Lag:           max(lag, 0),
+			})
+		}
+	}
+
+	return offsets, nil
+}
+
+// resetApplyTimeout ограничивает ожидание новой сессии группы при сбросе офсетов.
+// Должен быть меньше WriteTimeout HTTP-сервера (15s), иначе соединение закроется
+// раньше, чем клиент узнает результат сброса.
+const resetApplyTimeout = 10 * time.Second
+
+// ResetOffsets вычисляет целевые офсеты и применяет их в начале следующей сессии группы.
+// Текущая сессия перезапускается, и ответ возвращается после ее начала. Офсеты партиций,
+// назначенных другим экземплярам, не меняются: они коммитят собственные офсеты поверх
+// сброса, поэтому такие партиции возвращаются с Applied = false.
+func (k *kafkaConsumer) ResetOffsets(request dto.OffsetResetRequest) ([]dto.OffsetTarget, error) {
+	k.resetMu.Lock()
+	defer k.resetMu.Unlock()
+
+	topics := k.currentTopics()
+	if request.Topic != "" {
+		if !slices.Contains(topics, request.Topic) {
+			return nil, fmt.Errorf("topic %s is not consumed", request.Topic)
+		}
+		topics = []string{request.Topic}
+	}
+
+	topicPartitions, err := k.topicPartitions(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []dto.OffsetTarget
+	for _, topic := range topics {
+		partitions := topicPartitions[topic]
+		if len(request.Partitions) > 0 {
+			for _, partition := range request.Partitions {
+				if !slices.Contains(partitions, partition) {
+					return nil, fmt.Errorf("partition %d does not exist in topic %s", partition, topic)
+				}
+			}
+			partitions = request.Partitions
+		}
+
+		for _, partition := range partitions {
+			offset, err := k.resolveOffset(topic, partition, request)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, dto.OffsetTarget{
+				Topic:     topic,
+				Partition: partition,
+				Offset:    offset,
+			})
+		}
+	}
+
+	applied := make(chan map[string]map[int32]bool, 1)
+	k.mu.Lock()
+	k.pendingResets = make(map[string]map[int32]int64)
+	for _, target := range targets {
+		if k.pendingResets[target.Topic] == nil {
+			k.pendingResets[target.Topic] = make(map[int32]int64)
+		}
+		k.pendingResets[target.Topic][target.Partition] = target.Offset
+	}
+	k.resetApplied = applied
+	sessionCancel := k.sessionCancel
+	k.mu.Unlock()
+
+	if sessionCancel != nil {
+		sessionCancel()
+	}
+	log.Printf("Offset reset to %s scheduled for %d partitions", request.To, len(targets))
+
+	var result map[string]map[int32]bool
+	select {
+	case result = <-applied:
+	case <-time.After(resetApplyTimeout):
+		k.mu.Lock()
+		if k.resetApplied == nil {
+			// Setup применил сброс одновременно с истечением ожидания
+			k.mu.Unlock()
+			result = <-applied
+			break
+		}
+		k.pendingResets = nil
+		k.resetApplied = nil
+		k.mu.Unlock()
+		return nil, fmt.Errorf("consumer group session did not restart within %s, offsets were not reset", resetApplyTimeout)
+	}
+
+	for i := range targets {
+		targets[i].Applied = result[targets[i].Topic][targets[i].Partition]
+	}
+	return targets, nil
+}
+
+// Pause приостанавливает получение сообщений во всех назначенных партициях
+func (k *kafkaConsumer) Pause() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.paused = true
+	k.consumer.PauseAll()
+	log.Println("Kafka consumer paused")
+}
+
+// Resume возобновляет получение сообщений
+func (k *kafkaConsumer) Resume() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.paused = false
+	k.consumer.ResumeAll()
+	log.Println("Kafka consumer resumed")
+}
+
+// Paused сообщает, приостановлен ли потребитель
+func (k *kafkaConsumer) Paused() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.paused
+}
+
+// applyPendingResets применяет отложенные сбросы к партициям сессии и передает
+// примененные партиции в ResetOffsets. Вызывается из Setup до начала чтения, под k.mu.
+func (k *kafkaConsumer) applyPendingResets(session sarama.ConsumerGroupSession) {
+	if k.resetApplied == nil {
+		return
+	}
+
+	applied := make(map[string]map[int32]bool)
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			offset, ok := k.pendingResets[topic][partition]
+			if !ok {
+				continue
+			}
+			// ResetOffset только уменьшает офсет, MarkOffset только увеличивает:
+			// вместе они задают офсет в обе стороны, в том числе без закоммиченного
+			session.ResetOffset(topic, partition, offset, "")
+			session.MarkOffset(topic, partition, offset, "")
+			delete(k.pendingResets[topic], partition)
+			if applied[topic] == nil {
+				applied[topic] = make(map[int32]bool)
+			}
+			applied[topic][partition] = true
+			log.Printf("Offset of %s/%d reset to %d", topic, partition, offset)
+		}
+	}
+
+	for topic, partitions := range k.pendingResets {
+		for partition := range partitions {
+			log.Printf("Warning: partition %s/%d is not assigned to this instance, offset reset skipped", topic, partition)
+		}
+	}
+	k.pendingResets = nil
+
+	session.Commit()
+
+	k.resetApplied <- applied
+	k.resetApplied = nil
+}
+
+func (k *kafkaConsumer) resolveOffset(topic string, partition int32, request dto.OffsetResetRequest) (int64, error) {
+	var (
+		offset int64
+		err    error
+	)
+
+	switch request.To {
+	case dto.OffsetResetEarliest:
+		offset, err = k.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	case dto.OffsetResetLatest:
+		offset, err = k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	case dto.OffsetResetTimestamp:
+		if request.Timestamp.IsZero() {
+			return 0, fmt.Errorf("timestamp is required")
+		}
+		offset, err = k.client.GetOffset(topic, partition, request.Timestamp.UnixMilli())
+		if err == nil && offset < 0 {
+			// Сообщений позже указанного времени нет - переходим в конец лога
+			offset, err = k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		}
+	case dto.OffsetResetOffset:
+		var logStart, highWaterMark int64
+		logStart, err = k.client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err == nil {
+			highWaterMark, err = k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		}
+		offset = min(max(request.Offset, logStart), highWaterMark)
+	default:
+		return 0, fmt.Errorf("unknown reset position %q", request.To)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve offset for %s/%d: %w", topic, partition, err)
+	}
+
+	return offset, nil
+}
+
+func (k *kafkaConsumer) topicPartitions(topics []string) (map[string][]int32, error) {
+	topicPartitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		partitions, err := k.client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get partitions of topic %s: %w", topic, err)
+		}
+		topicPartitions[topic] = partitions
+	}
+	return topicPartitions, nil
+}
@@ -0,0 +1,158 @@
+package consumers
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+const testGroup = "order-service"
+
+// offsetSession - сессия группы, которая двигает офсеты настоящим менеджером офсетов
+// sarama, коммитящим их в тестовый брокер
+type offsetSession struct {
+	sarama.ConsumerGroupSession
+	claims  map[string][]int32
+	manager sarama.OffsetManager
+	managed map[int32]sarama.PartitionOffsetManager
+}
+
+func (s *offsetSession) Claims() map[string][]int32 {
+	return s.claims
+}
+
+func (s *offsetSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.managed[partition].ResetOffset(offset, metadata)
+}
+
+func (s *offsetSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.managed[partition].MarkOffset(offset, metadata)
+}
+
+func (s *offsetSession) Commit() {
+	s.manager.Commit()
+}
+
+// newOffsetSession запускает брокер с закоммиченными офсетами committed (-1 - офсета нет)
+// и сессию, которой назначены партиции claimed топика orders
+func newOffsetSession(t *testing.T, committed map[int32]int64, claimed []int32) (*offsetSession, *sarama.MockBroker) {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	offsets := sarama.NewMockOffsetFetchResponse(t)
+	for partition, offset := range committed {
+		metadata.SetLeader("orders", partition, broker.BrokerID())
+		offsets.SetOffset(testGroup, "orders", partition, offset, "", sarama.ErrNoError)
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, testGroup, broker),
+		"OffsetFetchRequest":  offsets,
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	manager, err := sarama.NewOffsetManagerFromClient(testGroup, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Close менеджера закрывает и менеджеры партиций
+	t.Cleanup(func() { manager.Close() })
+
+	session := &offsetSession{
+		claims:  map[string][]int32{"orders": claimed},
+		manager: manager,
+		managed: make(map[int32]sarama.PartitionOffsetManager),
+	}
+	for _, partition := range claimed {
+		managed, err := manager.ManagePartition("orders", partition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.managed[partition] = managed
+	}
+	return session, broker
+}
+
+// committedOffsets возвращает последние офсеты из запросов коммита к брокеру
+func committedOffsets(t *testing.T, broker *sarama.MockBroker) map[int32]int64 {
+	t.Helper()
+
+	result := make(map[int32]int64)
+	for _, exchange := range broker.History() {
+		request, ok := exchange.Request.(*sarama.OffsetCommitRequest)
+		if !ok {
+			continue
+		}
+		for partition := range int32(4) {
+			if offset, _, err := request.Offset("orders", partition); err == nil {
+				result[partition] = offset
+			}
+		}
+	}
+	return result
+}
+
+func TestApplyPendingResetsCommitsTargetOffsets(t *testing.T) {
+	session, broker := newOffsetSession(t,
+		map[int32]int64{0: 50, 1: 50, 2: -1, 3: 50},
+		[]int32{0, 1, 2},
+	)
+
+	applied := make(chan map[string]map[int32]bool, 1)
+	k := &kafkaConsumer{
+		// Назад, вперед (пропуск плохого диапазона), партиция без офсета
+		// и партиция другого экземпляра
+		pendingResets: map[string]map[int32]int64{"orders": {0: 10, 1: 80, 2: 30, 3: 5}},
+		resetApplied:  applied,
+	}
+
+	k.applyPendingResets(session)
+
+	want := map[int32]int64{0: 10, 1: 80, 2: 30}
+	got := committedOffsets(t, broker)
+	if len(got) != len(want) {
+		t.Errorf("committed offsets = %v, want %v", got, want)
+	}
+	for partition, offset := range want {
+		if got[partition] != offset {
+			t.Errorf("committed offset of partition %d = %d, want %d", partition, got[partition], offset)
+		}
+	}
+
+	result := <-applied
+	for partition := range want {
+		if !result["orders"][partition] {
+			t.Errorf("partition %d not reported as applied: %v", partition, result)
+		}
+	}
+	if result["orders"][3] {
+		t.Error("partition 3 of another instance reported as applied")
+	}
+	if k.pendingResets != nil || k.resetApplied != nil {
+		t.Error("pending reset was not cleared")
+	}
+}
+
+func TestApplyPendingResetsWithoutRequest(t *testing.T) {
+	session, broker := newOffsetSession(t, map[int32]int64{0: 50}, []int32{0})
+
+	// Сессия без запроса сброса ничего не коммитит
+	(&kafkaConsumer{}).applyPendingResets(session)
+
+	if got := committedOffsets(t, broker); len(got) != 0 {
+		t.Errorf("committed offsets = %v, want none", got)
+	}
+}
@@ -0,0 +1,101 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// lagTracker периодически сравнивает закоммиченные офсеты группы с high-water mark
+// партиций и предупреждает в логе, когда отставание партиции превышает порог
+type lagTracker struct {
+	offsets   func() ([]dto.PartitionOffsets, error)
+	interval  time.Duration
+	threshold int64
+
+	mu       sync.RWMutex
+	snapshot dto.ConsumerLag
+	lagging  map[string]bool
+}
+
+func newLagTracker(offsets func() ([]dto.PartitionOffsets, error), interval time.Duration, threshold int64) *lagTracker {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &lagTracker{
+		offsets:   offsets,
+		interval:  interval,
+		threshold: threshold,
+		lagging:   make(map[string]bool),
+	}
+}
+
+func (t *lagTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		t.check()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *lagTracker) check() {
+	partitions, err := t.offsets()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.snapshot.UpdatedAt = time.Now()
+	if err != nil {
+		log.Printf("Failed to measure consumer lag: %v", err)
+		t.snapshot.Error = err.Error()
+		return
+	}
+
+	var total int64
+	for _, partition := range partitions {
+		total += partition.Lag
+		t.warn(partition)
+	}
+
+	t.snapshot = dto.ConsumerLag{
+		Total:      total,
+		Partitions: partitions,
+		UpdatedAt:  t.snapshot.UpdatedAt,
+	}
+}
+
+// warn пишет в лог при переходе партиции через порог в обе стороны,
+// чтобы медленный потребитель не засорял лог на каждом замере
+func (t *lagTracker) warn(partition dto.PartitionOffsets) {
+	if t.threshold <= 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%s/%d", partition.Topic, partition.Partition)
+	switch {
+	case partition.Lag >= t.threshold && !t.lagging[key]:
+		t.lagging[key] = true
+		log.Printf("Warning: consumer lag of %s is %d messages (threshold %d)", key, partition.Lag, t.threshold)
+	case partition.Lag < t.threshold && t.lagging[key]:
+		delete(t.lagging, key)
+		log.Printf("Consumer lag of %s is back to %d messages", key, partition.Lag)
+	}
+}
+
+func (t *lagTracker) lag() dto.ConsumerLag {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.snapshot
+}
@@ -0,0 +1,46 @@
+package consumers
+
+import "sync"
+
+// offsetTracker отслеживает завершенные офсеты партиции и вычисляет наибольший
+// офсет, до которого все сообщения обработаны без пропусков
+type offsetTracker struct {
+	mu       sync.Mutex
+	inflight []int64
+	done     map[int64]struct{}
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		done: make(map[int64]struct{}),
+	}
+}
+
+// track регистрирует офсет в порядке получения
+func (t *offsetTracker) track(offset int64) {
+	t.mu.Lock()
+	t.inflight = append(t.inflight, offset)
+	t.mu.Unlock()
+}
+
+// complete отмечает офсет обработанным и возвращает наибольший непрерывно
+// завершенный офсет, если он сдвинулся
+func (t *offsetTracker) complete(offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done[offset] = struct{}{}
+
+	committed := int64(-1)
+	for len(t.inflight) > 0 {
+		head := t.inflight[0]
+		if _, ok := t.done[head]; !ok {
+			break
+		}
+		delete(t.done, head)
+		t.inflight = t.inflight[1:]
+		committed = head
+	}
+
+	return committed, committed >= 0
+}
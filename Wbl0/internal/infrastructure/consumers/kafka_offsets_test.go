@@ -0,0 +1,73 @@
+package consumers
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestOffsetTrackerCommitsContiguousPrefix(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(10); offset < 15; offset++ {
+		tracker.track(offset)
+	}
+
+	steps := []struct {
+		complete  int64
+		committed int64
+		moved     bool
+	}{
+		// 12 и 11 завершены раньше 10: коммитить нечего, пока 10 в обработке
+		{complete: 12, moved: false},
+		{complete: 11, moved: false},
+		{complete: 10, committed: 12, moved: true},
+		{complete: 14, moved: false},
+		{complete: 13, committed: 14, moved: true},
+	}
+	for _, step := range steps {
+		committed, moved := tracker.complete(step.complete)
+		if moved != step.moved || (moved && committed != step.committed) {
+			t.Fatalf("complete(%d) = %d, %v, want %d, %v",
+				step.complete, committed, moved, step.committed, step.moved)
+		}
+	}
+}
+
+func TestOffsetTrackerRandomCompletionOrder(t *testing.T) {
+	const count = 1000
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	tracker := newOffsetTracker()
+	offsets := make([]int64, count)
+	for i := range offsets {
+		// Офсеты партиции идут по возрастанию, но могут быть с пропусками (компакция)
+		offsets[i] = int64(i * 2)
+		tracker.track(offsets[i])
+	}
+
+	done := make(map[int64]bool, count)
+	committed := int64(-1)
+	for _, i := range rng.Perm(count) {
+		done[offsets[i]] = true
+		offset, moved := tracker.complete(offsets[i])
+		if !moved {
+			continue
+		}
+		if offset <= committed {
+			t.Fatalf("commit moved backwards from %d to %d", committed, offset)
+		}
+		committed = offset
+
+		// Все офсеты до закоммиченного включительно должны быть завершены
+		for _, o := range offsets {
+			if o > committed {
+				break
+			}
+			if !done[o] {
+				t.Fatalf("committed %d while %d is still in flight", committed, o)
+			}
+		}
+	}
+	if committed != offsets[count-1] {
+		t.Errorf("final commit = %d, want %d", committed, offsets[count-1])
+	}
+}
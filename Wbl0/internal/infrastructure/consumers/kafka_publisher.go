@@ -0,0 +1,55 @@
+package consumers
+
+import (
+	"fmt"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/pkg/kafkaconfig"
+
+	"github.com/IBM/sarama"
+)
+
+type kafkaPublisher struct {
+	producer sarama.SyncProducer
+}
+
+// NewKafkaPublisher создает синхронного продюсера Kafka для публикации
+// сообщений, например в топик недоставленных сообщений. Публикация
+// подтверждается всеми репликами.
+func NewKafkaPublisher(brokers []string, client kafkaconfig.Config) (interfaces.MessagePublisher, error) {
+	config, err := kafkaconfig.NewSaramaConfig(client)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	return &kafkaPublisher{producer: producer}, nil
+}
+
+func (k *kafkaPublisher) Publish(message *dto.Message) error {
+	headers := make([]sarama.RecordHeader, 0, len(message.Headers))
+	for key, value := range message.Headers {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+	}
+
+	_, _, err := k.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   message.Topic,
+		Key:     sarama.ByteEncoder(message.Key),
+		Value:   sarama.ByteEncoder(message.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message to %s: %w", message.Topic, err)
+	}
+	return nil
+}
+
+func (k *kafkaPublisher) Close() error {
+	return k.producer.Close()
+}
@@ -0,0 +1,121 @@
+package consumers
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+)
+
+func TestBalanceStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		names       []string
+		want        []string
+		wantWarning bool
+		wantErr     bool
+	}{
+		{name: "default", want: []string{sarama.RoundRobinBalanceStrategyName}},
+		{name: "range", names: []string{"range"}, want: []string{sarama.RangeBalanceStrategyName}},
+		{name: "case and spaces", names: []string{" RoundRobin "}, want: []string{sarama.RoundRobinBalanceStrategyName}},
+		{name: "round-robin alias", names: []string{"round-robin"}, want: []string{sarama.RoundRobinBalanceStrategyName}},
+		{
+			name:  "priority order",
+			names: []string{"sticky", "range"},
+			want:  []string{sarama.StickyBalanceStrategyName, sarama.RangeBalanceStrategyName},
+		},
+		{
+			// Кооперативной ребалансировки в sarama нет: sticky и предупреждение
+			name:        "cooperative-sticky falls back to sticky",
+			names:       []string{"cooperative-sticky", "range"},
+			want:        []string{sarama.StickyBalanceStrategyName, sarama.RangeBalanceStrategyName},
+			wantWarning: true,
+		},
+		{name: "unknown", names: []string{"range", "fastest"}, wantErr: true},
+		{name: "empty name", names: []string{""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			strategies, err := balanceStrategies(tt.names)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("balanceStrategies(%q) error = nil", tt.names)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("balanceStrategies(%q) error = %v", tt.names, err)
+			}
+
+			var got []string
+			for _, strategy := range strategies {
+				got = append(got, strategy.Name())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("strategies = %v, want %v", got, tt.want)
+			}
+
+			warned := strings.Contains(logs.String(), "Warning: cooperative rebalancing is not supported")
+			if warned != tt.wantWarning {
+				t.Errorf("warning logged = %v, want %v; log: %q", warned, tt.wantWarning, logs.String())
+			}
+		})
+	}
+}
+
+func TestResolveTopics(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for _, topic := range []string{"orders", "orders-eu", "orders_backup", "payments", "__consumer_offsets"} {
+		metadata.SetLeader(topic, 0, broker.BrokerID())
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{"MetadataRequest": metadata})
+
+	client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	tests := []struct {
+		name    string
+		static  []string
+		pattern string
+		want    []string
+	}{
+		{name: "static topics", static: []string{"orders", "audit"}, want: []string{"orders", "audit"}},
+		{name: "pattern", pattern: `^orders(-.+)?$`, want: []string{"orders", "orders-eu"}},
+		// Явно заданный топик читается, даже если его еще нет в кластере
+		{name: "pattern and static", static: []string{"audit", "orders"}, pattern: `^orders(-.+)?$`, want: []string{"audit", "orders", "orders-eu"}},
+		{name: "internal topics are skipped", pattern: `.*`, want: []string{"orders", "orders-eu", "orders_backup", "payments"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &kafkaConsumer{client: client, staticTopics: tt.static}
+			if tt.pattern != "" {
+				k.topicPattern = regexp.MustCompile(tt.pattern)
+			}
+
+			topics, err := k.resolveTopics()
+			if err != nil {
+				t.Fatalf("resolveTopics() error = %v", err)
+			}
+			if !slices.Equal(topics, tt.want) {
+				t.Errorf("resolveTopics() = %v, want %v", topics, tt.want)
+			}
+		})
+	}
+}
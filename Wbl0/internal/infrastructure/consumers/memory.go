@@ -0,0 +1,175 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// MemoryBroker - брокер сообщений в памяти процесса на каналах.
+// Используется в тестах и при локальном запуске без Kafka.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	bufferSize int
+	topics     map[string]chan *dto.Message
+}
+
+// NewMemoryBroker создает брокер в памяти с буфером bufferSize сообщений на топик
+func NewMemoryBroker(bufferSize int) *MemoryBroker {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &MemoryBroker{
+		bufferSize: bufferSize,
+		topics:     make(map[string]chan *dto.Message),
+	}
+}
+
+// Publish помещает сообщение в топик. Возвращает ошибку, если буфер топика заполнен.
+func (b *MemoryBroker) Publish(message *dto.Message) error {
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+
+	select {
+	case b.topic(message.Topic) <- message:
+		return nil
+	default:
+		return fmt.Errorf("topic %s buffer is full", message.Topic)
+	}
+}
+
+func (b *MemoryBroker) topic(name string) chan *dto.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, ok := b.topics[name]
+	if !ok {
+		ch = make(chan *dto.Message, b.bufferSize)
+		b.topics[name] = ch
+	}
+	return ch
+}
+
+type memoryConsumer struct {
+	broker  *MemoryBroker
+	topics  []string
+	handler interfaces.MessageHandler
+	pool    WorkerPoolConfig
+	// ctx отменяется, если дообработка при остановке не уложилась в срок
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewMemoryConsumer создает потребителя топиков брокера в памяти
+func NewMemoryConsumer(broker *MemoryBroker, topics []string, handler interfaces.MessageHandler, pool WorkerPoolConfig) interfaces.MessageConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &memoryConsumer{
+		broker:   broker,
+		topics:   topics,
+		handler:  handler,
+		pool:     pool.normalize(),
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+	}
+}
+
+func (m *memoryConsumer) Start() error {
+	log.Printf("Starting in-memory consumer for topics: %v", m.topics)
+
+	for _, topic := range m.topics {
+		m.wg.Add(1)
+		go m.consume(topic)
+	}
+	return nil
+}
+
+// consume читает топик. Неподтвержденные к остановке сообщения возвращаются
+// в топик, как при повторной доставке в Kafka и NATS.
+func (m *memoryConsumer) consume(topic string) {
+	defer m.wg.Done()
+
+	messages := m.broker.topic(topic)
+	processor := newProcessor(m.ctx, m.handler, m.pool)
+
+	var (
+		mu       sync.Mutex
+		sequence uint64
+		inflight = make(map[uint64]*dto.Message)
+	)
+
+	defer func() {
+		processor.close()
+
+		keys := make([]uint64, 0, len(inflight))
+		for key := range inflight {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		for _, key := range keys {
+			select {
+			case messages <- inflight[key]:
+			default:
+				log.Printf("Warning: in-memory topic %s is full, unacked message dropped", topic)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-m.stopping:
+			return
+		default:
+		}
+
+		select {
+		case message := <-messages:
+			mu.Lock()
+			sequence++
+			id := sequence
+			inflight[id] = message
+			mu.Unlock()
+
+			ack := func() {
+				mu.Lock()
+				delete(inflight, id)
+				mu.Unlock()
+			}
+			if !processor.dispatch(delivery{message: message, ack: ack}) {
+				return
+			}
+
+		case <-m.stopping:
+			return
+		}
+	}
+}
+
+func (m *memoryConsumer) Stop(ctx context.Context) error {
+	log.Println("Stopping in-memory consumer: no new messages will be read")
+	m.stopOnce.Do(func() { close(m.stopping) })
+
+	if err := waitGroupWait(ctx, &m.wg); err != nil {
+		m.cancel()
+		return fmt.Errorf("in-flight messages were not drained: %w", err)
+	}
+	log.Println("In-memory consumer drained in-flight messages")
+	return nil
+}
+
+func (m *memoryConsumer) Close() error {
+	m.cancel()
+	m.wg.Wait()
+	return nil
+}
@@ -0,0 +1,84 @@
+package consumers
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// countingHandler считает обработанные сообщения и закрывает done, когда их want.
+// delay имитирует запись пачки в БД.
+type countingHandler struct {
+	want    int64
+	delay   time.Duration
+	handled atomic.Int64
+	done    chan struct{}
+}
+
+func (h *countingHandler) HandleMessages(messages []*dto.Message) error {
+	time.Sleep(h.delay)
+	if h.handled.Add(int64(len(messages))) == h.want {
+		close(h.done)
+	}
+	return nil
+}
+
+func BenchmarkWorkerPoolThroughput(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, delay := range []time.Duration{0, time.Millisecond} {
+		for _, workers := range []int{1, 4, 16} {
+			b.Run("batch_delay="+delay.String()+"/workers="+strconv.Itoa(workers), func(b *testing.B) {
+				benchmarkWorkerPool(b, workers, delay)
+			})
+		}
+	}
+}
+
+func benchmarkWorkerPool(b *testing.B, workers int, delay time.Duration) {
+	broker := NewMemoryBroker(b.N)
+	handler := &countingHandler{want: int64(b.N), delay: delay, done: make(chan struct{})}
+	consumer := NewMemoryConsumer(broker, []string{"orders"}, handler, WorkerPoolConfig{
+		Workers:     workers,
+		QueueSize:   1000,
+		BatchSize:   100,
+		BatchLinger: time.Millisecond,
+	})
+
+	messages := make([]*dto.Message, b.N)
+	for i := range messages {
+		messages[i] = &dto.Message{
+			Topic: "orders",
+			Key:   []byte("order-" + strconv.Itoa(i%1024)),
+			Value: []byte(`{}`),
+		}
+	}
+
+	b.ResetTimer()
+	if err := consumer.Start(); err != nil {
+		b.Fatal(err)
+	}
+	for _, message := range messages {
+		if err := broker.Publish(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+	<-handler.done
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := consumer.Stop(ctx); err != nil {
+		b.Fatal(err)
+	}
+	consumer.Close()
+}
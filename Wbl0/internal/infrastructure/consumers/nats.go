@@ -0,0 +1,202 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSKeyHeader - заголовок сообщения NATS с ключом шардирования (order_uid)
+const NATSKeyHeader = "key"
+
+// NATSConfig содержит параметры подключения потребителя к NATS JetStream
+type NATSConfig struct {
+	URL      string
+	Stream   string
+	Subjects []string
+	// Durable - имя долговременного потребителя, аналог группы в Kafka
+	Durable string
+	// AckWait - время до повторной доставки неподтвержденного сообщения.
+	// Должно превышать время обработки пачки со всеми повторами.
+	AckWait time.Duration
+	Pool    WorkerPoolConfig
+}
+
+type natsConsumer struct {
+	conn     *nats.Conn
+	consumer jetstream.Consumer
+	subjects []string
+	handler  interfaces.MessageHandler
+	pool     WorkerPoolConfig
+	// ctx отменяется, если дообработка при остановке не уложилась в срок
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopping chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewNATSConsumer создает pull-потребителя JetStream. Поток и долговременный
+// потребитель создаются или обновляются при подключении.
+func NewNATSConsumer(cfg NATSConfig, handler interfaces.MessageHandler) (interfaces.MessageConsumer, error) {
+	conn, err := nats.Connect(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	setupCtx, setupCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer setupCancel()
+
+	stream, err := js.CreateOrUpdateStream(setupCtx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: cfg.Subjects,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", cfg.Stream, err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(setupCtx, jetstream.ConsumerConfig{
+		Durable:        cfg.Durable,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        cfg.AckWait,
+		FilterSubjects: cfg.Subjects,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create consumer %s: %w", cfg.Durable, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &natsConsumer{
+		conn:     conn,
+		consumer: consumer,
+		subjects: cfg.Subjects,
+		handler:  handler,
+		pool:     cfg.Pool.normalize(),
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+	}, nil
+}
+
+func (n *natsConsumer) Start() error {
+	log.Printf("Starting NATS consumer for subjects: %v", n.subjects)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		processor := newProcessor(n.ctx, n.handler, n.pool)
+		defer processor.close()
+
+		for !n.isStopping() {
+			batch, err := n.consumer.Fetch(n.pool.BatchSize, jetstream.FetchMaxWait(time.Second))
+			if err != nil {
+				log.Printf("Error from NATS consumer: %v", err)
+				select {
+				case <-time.After(time.Second):
+				case <-n.stopping:
+				}
+				continue
+			}
+
+			for msg := range batch.Messages() {
+				// Оставшиеся сообщения пачки не подтверждаются и будут доставлены
+				// повторно по истечении AckWait
+				if n.isStopping() {
+					break
+				}
+
+				message := natsMessage(msg)
+				log.Printf("Received message from subject %s", message.Topic)
+
+				ack := func() {
+					if err := msg.Ack(); err != nil {
+						log.Printf("Failed to ack NATS message: %v", err)
+					}
+				}
+				inProgress := func() {
+					if err := msg.InProgress(); err != nil {
+						log.Printf("Failed to extend NATS ack deadline: %v", err)
+					}
+				}
+				if !processor.dispatch(delivery{message: message, ack: ack, inProgress: inProgress}) {
+					return
+				}
+			}
+
+			if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+				log.Printf("Error from NATS fetch: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (n *natsConsumer) Stop(ctx context.Context) error {
+	log.Println("Stopping NATS consumer: no new messages will be fetched")
+	n.stopOnce.Do(func() { close(n.stopping) })
+
+	if err := waitGroupWait(ctx, &n.wg); err != nil {
+		n.cancel()
+		return fmt.Errorf("in-flight messages were not drained: %w", err)
+	}
+	log.Println("NATS consumer drained in-flight messages")
+	return nil
+}
+
+func (n *natsConsumer) isStopping() bool {
+	select {
+	case <-n.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func (n *natsConsumer) Close() error {
+	n.cancel()
+	n.wg.Wait()
+	n.conn.Close()
+	return nil
+}
+
+// natsMessage переводит сообщение JetStream в формат, не зависящий от брокера
+func natsMessage(msg jetstream.Msg) *dto.Message {
+	headers := make(map[string]string, len(msg.Headers()))
+	for key, values := range msg.Headers() {
+		if len(values) > 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+
+	message := &dto.Message{
+		Topic:   msg.Subject(),
+		Key:     []byte(headers[NATSKeyHeader]),
+		Value:   msg.Data(),
+		Headers: headers,
+	}
+	if metadata, err := msg.Metadata(); err == nil {
+		message.Timestamp = metadata.Timestamp
+	}
+	return message
+}
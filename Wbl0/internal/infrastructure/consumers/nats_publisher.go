@@ -0,0 +1,71 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type natsPublisher struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// NewNATSPublisher создает издателя JetStream. Поток stream с единственным
+// subject создается или обновляется при подключении.
+func NewNATSPublisher(url, stream, subject string) (interfaces.MessagePublisher, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     stream,
+		Subjects: []string{subject},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s: %w", stream, err)
+	}
+
+	return &natsPublisher{conn: conn, js: js}, nil
+}
+
+func (n *natsPublisher) Publish(message *dto.Message) error {
+	msg := nats.NewMsg(message.Topic)
+	msg.Data = message.Value
+	for key, value := range message.Headers {
+		msg.Header.Set(key, value)
+	}
+	if len(message.Key) > 0 {
+		msg.Header.Set(NATSKeyHeader, string(message.Key))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := n.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish message to %s: %w", message.Topic, err)
+	}
+	return nil
+}
+
+func (n *natsPublisher) Close() error {
+	n.conn.Close()
+	return nil
+}
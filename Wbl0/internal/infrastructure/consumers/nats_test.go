@@ -0,0 +1,285 @@
+package consumers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	testNATSStream  = "ORDERS"
+	testNATSSubject = "orders"
+)
+
+// runNATSServer запускает встроенный nats-server с JetStream на случайном порту
+func runNATSServer(t *testing.T) string {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+	t.Cleanup(func() {
+		srv.Shutdown()
+		srv.WaitForShutdown()
+	})
+	return srv.ClientURL()
+}
+
+func newTestNATSConsumer(t *testing.T, url, durable string, ackWait time.Duration, handler interfaces.MessageHandler, pool WorkerPoolConfig) interfaces.MessageConsumer {
+	t.Helper()
+
+	consumer, err := NewNATSConsumer(NATSConfig{
+		URL:      url,
+		Stream:   testNATSStream,
+		Subjects: []string{testNATSSubject},
+		Durable:  durable,
+		AckWait:  ackWait,
+		Pool:     pool,
+	}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return consumer
+}
+
+func stopConsumer(consumer interfaces.MessageConsumer, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := consumer.Stop(ctx)
+	consumer.Close()
+	return err
+}
+
+func newTestNATSPublisher(t *testing.T, url string) interfaces.MessagePublisher {
+	t.Helper()
+
+	publisher, err := NewNATSPublisher(url, testNATSStream, testNATSSubject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { publisher.(io.Closer).Close() })
+	return publisher
+}
+
+// natsConsumerInfo возвращает состояние долговременного потребителя на сервере
+func natsConsumerInfo(t *testing.T, url, durable string) *jetstream.ConsumerInfo {
+	t.Helper()
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	consumer, err := js.Consumer(ctx, testNATSStream, durable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// eventually ждет выполнения условия, периодически проверяя его
+func eventually(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return condition()
+}
+
+// keyHeaderHandler проверяет, что ключ сообщения прочитан из заголовка key
+type keyHeaderHandler struct {
+	*orderingHandler
+	mismatched atomic.Int32
+}
+
+func (h *keyHeaderHandler) HandleMessages(messages []*dto.Message) error {
+	for _, message := range messages {
+		if string(message.Key) != string(orderUIDShardKey(message)) {
+			h.mismatched.Add(1)
+		}
+	}
+	return h.orderingHandler.HandleMessages(messages)
+}
+
+func TestNATSConsumerKeepsOrderPerKeyHeader(t *testing.T) {
+	const orders, versions = 10, 5
+
+	url := runNATSServer(t)
+	handler := &keyHeaderHandler{orderingHandler: &orderingHandler{
+		seen: make(map[string][]int),
+		want: orders * versions,
+		done: make(chan struct{}),
+	}}
+	// Без ShardKey сообщения шардируются только по заголовку key
+	consumer := newTestNATSConsumer(t, url, "order-service", 30*time.Second, handler, WorkerPoolConfig{
+		Workers:     4,
+		QueueSize:   10,
+		BatchSize:   3,
+		BatchLinger: time.Millisecond,
+	})
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer stopConsumer(consumer, 5*time.Second)
+
+	publisher := newTestNATSPublisher(t, url)
+	for seq := range versions {
+		for order := range orders {
+			orderUID := fmt.Sprintf("order-%d", order)
+			err := publisher.Publish(&dto.Message{
+				Topic: testNATSSubject,
+				Key:   []byte(orderUID),
+				Value: []byte(fmt.Sprintf(`{"order_uid":"%s","seq":%d}`, orderUID, seq)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	select {
+	case <-handler.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("messages were not processed")
+	}
+
+	if got := handler.mismatched.Load(); got != 0 {
+		t.Errorf("%d messages with key not taken from the key header", got)
+	}
+	handler.mu.Lock()
+	for orderUID, seqs := range handler.seen {
+		if !slices.IsSorted(seqs) || len(seqs) != versions {
+			t.Errorf("%s processed out of order: %v", orderUID, seqs)
+		}
+	}
+	handler.mu.Unlock()
+
+	// Все сообщения подтверждены после обработки
+	if !eventually(t, 5*time.Second, func() bool {
+		info := natsConsumerInfo(t, url, "order-service")
+		return info.NumAckPending == 0 && info.NumPending == 0
+	}) {
+		t.Errorf("messages were not acked: %+v", natsConsumerInfo(t, url, "order-service"))
+	}
+}
+
+func TestNATSConsumerAcksAfterRetries(t *testing.T) {
+	url := runNATSServer(t)
+	handler := &failingHandler{failures: 2}
+	consumer := newTestNATSConsumer(t, url, "order-service", 30*time.Second, handler, WorkerPoolConfig{
+		MaxAttempts:     1,
+		RetryBackoff:    10 * time.Millisecond,
+		MaxRetryBackoff: 10 * time.Millisecond,
+	})
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer stopConsumer(consumer, 5*time.Second)
+
+	publisher := newTestNATSPublisher(t, url)
+	if err := publisher.Publish(&dto.Message{Topic: testNATSSubject, Key: []byte("order-1"), Value: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ошибки обработки повторяются внутри потребителя, а не повторной доставкой:
+	// сообщение подтверждается один раз после успешной попытки
+	if !eventually(t, 5*time.Second, func() bool {
+		info := natsConsumerInfo(t, url, "order-service")
+		return handler.calls.Load() == 3 && info.NumAckPending == 0
+	}) {
+		t.Fatalf("handler calls = %d, consumer %+v", handler.calls.Load(), natsConsumerInfo(t, url, "order-service"))
+	}
+	if info := natsConsumerInfo(t, url, "order-service"); info.Delivered.Consumer != 1 {
+		t.Errorf("message was delivered %d times, want 1", info.Delivered.Consumer)
+	}
+}
+
+func TestNATSConsumerRedeliversUnackedMessages(t *testing.T) {
+	const ackWait = time.Second
+
+	url := runNATSServer(t)
+	failing := &failingHandler{failures: 1 << 30}
+	consumer := newTestNATSConsumer(t, url, "order-service", ackWait, failing, WorkerPoolConfig{
+		MaxAttempts:     1,
+		RetryBackoff:    10 * time.Millisecond,
+		MaxRetryBackoff: 10 * time.Millisecond,
+	})
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := newTestNATSPublisher(t, url)
+	if err := publisher.Publish(&dto.Message{Topic: testNATSSubject, Key: []byte("order-1"), Value: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(t, 5*time.Second, func() bool { return failing.calls.Load() >= 2 }) {
+		t.Fatalf("handler calls = %d, want retries", failing.calls.Load())
+	}
+
+	// Остановка не дожидается успешной обработки: сообщение остается неподтвержденным
+	if err := stopConsumer(consumer, 100*time.Millisecond); err == nil {
+		t.Error("Stop() drained a message that was never processed")
+	}
+	if info := natsConsumerInfo(t, url, "order-service"); info.NumAckPending != 1 {
+		t.Fatalf("ack pending = %d, want 1", info.NumAckPending)
+	}
+
+	// Следующий экземпляр того же durable получает сообщение после AckWait
+	handler := &failingHandler{}
+	next := newTestNATSConsumer(t, url, "order-service", ackWait, handler, WorkerPoolConfig{})
+	if err := next.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer stopConsumer(next, 5*time.Second)
+
+	if !eventually(t, 5*ackWait, func() bool {
+		info := natsConsumerInfo(t, url, "order-service")
+		return handler.calls.Load() == 1 && info.NumAckPending == 0
+	}) {
+		t.Fatalf("handler calls = %d, consumer %+v", handler.calls.Load(), natsConsumerInfo(t, url, "order-service"))
+	}
+	if info := natsConsumerInfo(t, url, "order-service"); info.Delivered.Consumer < 2 {
+		t.Errorf("message was delivered %d times, want redelivery", info.Delivered.Consumer)
+	}
+}
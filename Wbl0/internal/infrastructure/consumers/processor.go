@@ -0,0 +1,325 @@
+package consumers
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// WorkerPoolConfig задает параметры обработки сообщений, общие для всех брокеров
+type WorkerPoolConfig struct {
+	// Workers - количество воркеров (шардов) на партицию или подписку
+	Workers int
+	// QueueSize - глубина очереди каждого воркера
+	QueueSize int
+	// BatchSize - максимальный размер микропачки, сохраняемой одной транзакцией
+	BatchSize int
+	// BatchLinger - максимальное время ожидания заполнения микропачки
+	BatchLinger time.Duration
+	// MaxAttempts - количество попыток обработки пачки до ее отправки в DeadLetter
+	MaxAttempts int
+	// RetryBackoff - пауза перед первой повторной попыткой, дальше удваивается
+	RetryBackoff time.Duration
+	// MaxRetryBackoff ограничивает рост паузы между попытками
+	MaxRetryBackoff time.Duration
+	// DeadLetter принимает сообщения пачки, не обработанной за MaxAttempts попыток;
+	// пачка подтверждается только после их публикации. nil - пачка повторяется,
+	// пока контекст не отменен, и не подтверждается.
+	DeadLetter interfaces.MessagePublisher
+	// DeadLetterTopic - топик (subject) для DeadLetter
+	DeadLetterTopic string
+	// ShardKey возвращает ключ шардирования сообщения (order_uid). nil или пустой
+	// результат - шардирование по ключу сообщения.
+	ShardKey func(message *dto.Message) []byte
+}
+
+// Заголовки сообщения, отправленного в DeadLetterTopic
+const (
+	DeadLetterErrorHeader = "dead-letter-error"
+	DeadLetterTopicHeader = "dead-letter-source-topic"
+)
+
+func (c WorkerPoolConfig) normalize() WorkerPoolConfig {
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 1
+	}
+	// Без паузы бесконечные повторы при недоступной БД загрузили бы процессор
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+	if c.MaxRetryBackoff < c.RetryBackoff {
+		c.MaxRetryBackoff = max(c.RetryBackoff, 30*time.Second)
+	}
+	return c
+}
+
+// delivery - сообщение вместе с подтверждением, специфичным для брокера
+type delivery struct {
+	message *dto.Message
+	ack     func()
+	// inProgress, если задан, продлевает срок подтверждения на время повторов,
+	// чтобы брокер не доставил сообщение повторно
+	inProgress func()
+}
+
+// processor обрабатывает поток сообщений пулом воркеров.
+// Сообщения шардируются по ShardKey (order_uid), поэтому сообщения одного заказа
+// обрабатываются строго по порядку, а разные заказы - параллельно.
+//
+// Семантика подтверждения одинакова для всех брокеров: сообщение подтверждается
+// только после обработки его пачки. Неудачная пачка после MaxAttempts попыток
+// отправляется в DeadLetter и подтверждается, а без DeadLetter повторяется до
+// отмены контекста. Невалидные сообщения (InvalidMessagesError) отправляются
+// в DeadLetter без повторов. Неподтвержденные сообщения будут доставлены повторно.
+type processor struct {
+	ctx     context.Context
+	handler interfaces.MessageHandler
+	config  WorkerPoolConfig
+	shards  []chan delivery
+	wg      sync.WaitGroup
+}
+
+func newProcessor(ctx context.Context, handler interfaces.MessageHandler, config WorkerPoolConfig) *processor {
+	config = config.normalize()
+
+	p := &processor{
+		ctx:     ctx,
+		handler: handler,
+		config:  config,
+		shards:  make([]chan delivery, config.Workers),
+	}
+
+	for i := range p.shards {
+		p.shards[i] = make(chan delivery, config.QueueSize)
+		p.wg.Add(1)
+		go p.worker(p.shards[i])
+	}
+
+	return p
+}
+
+// dispatch ставит сообщение в очередь его шарда. Возвращает false, если контекст отменен.
+func (p *processor) dispatch(d delivery) bool {
+	select {
+	case p.shards[p.shardFor(p.shardKey(d.message))] <- d:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// close останавливает прием сообщений и ждет завершения обрабатываемых
+func (p *processor) close() {
+	for _, shard := range p.shards {
+		close(shard)
+	}
+	p.wg.Wait()
+}
+
+// shardKey возвращает ключ шардирования сообщения
+func (p *processor) shardKey(message *dto.Message) []byte {
+	if p.config.ShardKey != nil {
+		if key := p.config.ShardKey(message); len(key) > 0 {
+			return key
+		}
+	}
+	return message.Key
+}
+
+func (p *processor) shardFor(key []byte) int {
+	if len(p.shards) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(len(p.shards)))
+}
+
+// worker собирает сообщения своего шарда в микропачки, ограниченные
+// размером BatchSize и временем BatchLinger
+func (p *processor) worker(deliveries <-chan delivery) {
+	defer p.wg.Done()
+
+	batch := make([]delivery, 0, p.config.BatchSize)
+	var linger <-chan time.Time
+
+	flush := func() {
+		p.processBatch(batch)
+		batch = batch[:0]
+		linger = nil
+	}
+
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, d)
+			if len(batch) >= p.config.BatchSize {
+				flush()
+			} else if len(batch) == 1 {
+				linger = time.After(p.config.BatchLinger)
+			}
+
+		case <-linger:
+			flush()
+		}
+	}
+}
+
+// processBatch обрабатывает микропачку и подтверждает сообщения только после ее обработки
+func (p *processor) processBatch(batch []delivery) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// Контекст отменен (например, партиция отозвана) - оставшиеся сообщения
+	// не обрабатываем, их доставят повторно
+	if p.ctx.Err() != nil {
+		return
+	}
+
+	messages := make([]*dto.Message, len(batch))
+	for i, d := range batch {
+		messages[i] = d.message
+	}
+
+	if !p.handleWithRetry(batch, messages) {
+		return
+	}
+
+	for _, d := range batch {
+		d.ack()
+	}
+}
+
+// handleWithRetry передает пачку обработчику, повторяя попытки с экспоненциальной
+// паузой. После MaxAttempts попыток пачка отправляется в DeadLetter, если он задан;
+// невалидные сообщения отправляются туда сразу. Возвращает true, если пачку можно подтвердить, и false, если контекст отменен
+// до завершения обработки.
+func (p *processor) handleWithRetry(batch []delivery, messages []*dto.Message) bool {
+	backoff := p.config.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := p.handler.HandleMessages(messages)
+		if err == nil {
+			return true
+		}
+
+		// Остальные сообщения пачки обработаны, а невалидные повтор не исправит:
+		// они сразу отправляются в DeadLetter, а без него повторяются только они
+		var invalid *interfaces.InvalidMessagesError
+		if errors.As(err, &invalid) {
+			messages = invalid.Messages
+			if p.config.DeadLetter != nil {
+				dlErr := p.deadLetterInvalid(invalid)
+				if dlErr == nil {
+					log.Printf("Sent %d invalid messages from %s to %s: %v",
+						len(messages), messages[0].Topic, p.config.DeadLetterTopic, err)
+					return true
+				}
+				log.Printf("Failed to send %d invalid messages from %s to %s: %v",
+					len(messages), messages[0].Topic, p.config.DeadLetterTopic, dlErr)
+			}
+		}
+
+		first, last := messages[0], messages[len(messages)-1]
+		if invalid == nil && attempt >= p.config.MaxAttempts && p.config.DeadLetter != nil {
+			dlErr := p.deadLetter(messages, err)
+			if dlErr == nil {
+				log.Printf("Failed to process batch of %d messages from %s (keys %q..%q) after %d attempts, sent to %s: %v",
+					len(messages), first.Topic, first.Key, last.Key, attempt, p.config.DeadLetterTopic, err)
+				return true
+			}
+			log.Printf("Failed to send batch of %d messages from %s to %s: %v",
+				len(messages), first.Topic, p.config.DeadLetterTopic, dlErr)
+		}
+
+		log.Printf("Failed to process batch of %d messages from %s (attempt %d), retrying in %s: %v",
+			len(messages), first.Topic, attempt, backoff, err)
+
+		for _, d := range batch {
+			if d.inProgress != nil {
+				d.inProgress()
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-p.ctx.Done():
+			return false
+		}
+		backoff = min(backoff*2, p.config.MaxRetryBackoff)
+	}
+}
+
+// deadLetter публикует сообщения пачки в DeadLetterTopic с исходными ключом,
+// телом и заголовками, добавляя текст ошибки и исходный топик
+func (p *processor) deadLetter(messages []*dto.Message, cause error) error {
+	for _, message := range messages {
+		if err := p.publishDeadLetter(message, cause); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadLetterInvalid публикует невалидные сообщения, каждое со своей причиной
+func (p *processor) deadLetterInvalid(invalid *interfaces.InvalidMessagesError) error {
+	for i, message := range invalid.Messages {
+		if err := p.publishDeadLetter(message, invalid.Errors[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *processor) publishDeadLetter(message *dto.Message, cause error) error {
+	headers := make(map[string]string, len(message.Headers)+2)
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+	headers[DeadLetterErrorHeader] = cause.Error()
+	headers[DeadLetterTopicHeader] = message.Topic
+
+	return p.config.DeadLetter.Publish(&dto.Message{
+		Topic:   p.config.DeadLetterTopic,
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	})
+}
+
+// waitGroupWait ждет завершения wg, но не дольше ctx
+func waitGroupWait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
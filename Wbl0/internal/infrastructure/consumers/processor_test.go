@@ -0,0 +1,342 @@
+package consumers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// failingHandler возвращает ошибку первые failures вызовов
+type failingHandler struct {
+	failures int32
+	calls    atomic.Int32
+}
+
+func (h *failingHandler) HandleMessages(messages []*dto.Message) error {
+	if h.calls.Add(1) <= h.failures {
+		return errors.New("database is unavailable")
+	}
+	return nil
+}
+
+// recordingPublisher запоминает опубликованные сообщения, пока failures > 0 - падает
+type recordingPublisher struct {
+	failures  int
+	published []*dto.Message
+}
+
+func (p *recordingPublisher) Publish(message *dto.Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker is unavailable")
+	}
+	p.published = append(p.published, message)
+	return nil
+}
+
+func testBatch(acked *atomic.Int32, keys ...string) []delivery {
+	batch := make([]delivery, len(keys))
+	for i, key := range keys {
+		batch[i] = delivery{
+			message: &dto.Message{
+				Topic:   "orders",
+				Key:     []byte(key),
+				Value:   []byte(`{}`),
+				Headers: map[string]string{"content-type": "application/json"},
+			},
+			ack: func() { acked.Add(1) },
+		}
+	}
+	return batch
+}
+
+func testProcessor(ctx context.Context, handler interfaces.MessageHandler, config WorkerPoolConfig) *processor {
+	config.RetryBackoff = time.Millisecond
+	config.MaxRetryBackoff = time.Millisecond
+	return &processor{ctx: ctx, handler: handler, config: config.normalize()}
+}
+
+func TestProcessBatchRetriesUntilSuccess(t *testing.T) {
+	handler := &failingHandler{failures: 5}
+	p := testProcessor(context.Background(), handler, WorkerPoolConfig{MaxAttempts: 2})
+
+	var acked atomic.Int32
+	p.processBatch(testBatch(&acked, "a", "b"))
+
+	// Без DeadLetter исчерпание MaxAttempts не прекращает повторы
+	if got := handler.calls.Load(); got != 6 {
+		t.Fatalf("handler calls = %d, want 6", got)
+	}
+	if got := acked.Load(); got != 2 {
+		t.Fatalf("acked = %d, want 2", got)
+	}
+}
+
+func TestProcessBatchNeverAcksWithoutDeadLetter(t *testing.T) {
+	handler := &failingHandler{failures: 1 << 30}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := testProcessor(ctx, handler, WorkerPoolConfig{MaxAttempts: 1})
+
+	var acked atomic.Int32
+	inProgress := 0
+	batch := testBatch(&acked, "a")
+	batch[0].inProgress = func() { inProgress++ }
+	p.processBatch(batch)
+
+	if got := acked.Load(); got != 0 {
+		t.Fatalf("acked = %d, want 0", got)
+	}
+	if handler.calls.Load() < 2 {
+		t.Fatalf("handler calls = %d, want retries until context is cancelled", handler.calls.Load())
+	}
+	if inProgress == 0 {
+		t.Fatal("ack deadline was not extended between retries")
+	}
+}
+
+func TestProcessBatchSendsExhaustedBatchToDeadLetter(t *testing.T) {
+	handler := &failingHandler{failures: 1 << 30}
+	publisher := &recordingPublisher{failures: 1}
+	p := testProcessor(context.Background(), handler, WorkerPoolConfig{
+		MaxAttempts:     3,
+		DeadLetter:      publisher,
+		DeadLetterTopic: "orders-dead-letter",
+	})
+
+	var acked atomic.Int32
+	p.processBatch(testBatch(&acked, "a", "b"))
+
+	// Первая публикация падает, поэтому пачка подтверждается после четвертой попытки
+	if got := handler.calls.Load(); got != 4 {
+		t.Fatalf("handler calls = %d, want 4", got)
+	}
+	if got := acked.Load(); got != 2 {
+		t.Fatalf("acked = %d, want 2", got)
+	}
+	if len(publisher.published) != 2 {
+		t.Fatalf("published %d messages, want 2", len(publisher.published))
+	}
+	for i, message := range publisher.published {
+		if message.Topic != "orders-dead-letter" {
+			t.Errorf("message %d topic = %s", i, message.Topic)
+		}
+		if message.Headers[DeadLetterTopicHeader] != "orders" {
+			t.Errorf("message %d source topic header = %q", i, message.Headers[DeadLetterTopicHeader])
+		}
+		if message.Headers[DeadLetterErrorHeader] != "database is unavailable" {
+			t.Errorf("message %d error header = %q", i, message.Headers[DeadLetterErrorHeader])
+		}
+		if message.Headers["content-type"] != "application/json" {
+			t.Errorf("message %d lost original headers: %v", i, message.Headers)
+		}
+	}
+	if string(publisher.published[0].Key) != "a" || string(publisher.published[1].Key) != "b" {
+		t.Errorf("dead letter keys = %q, %q", publisher.published[0].Key, publisher.published[1].Key)
+	}
+}
+
+// invalidHandler обрабатывает сообщения пачки, кроме сообщений с ключом bad-*,
+// и запоминает ключи каждого вызова
+type invalidHandler struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (h *invalidHandler) HandleMessages(messages []*dto.Message) error {
+	var (
+		keys    []string
+		invalid interfaces.InvalidMessagesError
+	)
+	for _, message := range messages {
+		keys = append(keys, string(message.Key))
+		if strings.HasPrefix(string(message.Key), "bad-") {
+			invalid.Add(message, fmt.Errorf("order_uid is required"))
+		}
+	}
+
+	h.mu.Lock()
+	h.calls = append(h.calls, keys)
+	h.mu.Unlock()
+
+	if len(invalid.Messages) > 0 {
+		return &invalid
+	}
+	return nil
+}
+
+func TestProcessBatchSendsInvalidMessagesToDeadLetter(t *testing.T) {
+	handler := &invalidHandler{}
+	publisher := &recordingPublisher{failures: 1}
+	p := testProcessor(context.Background(), handler, WorkerPoolConfig{
+		MaxAttempts:     5,
+		DeadLetter:      publisher,
+		DeadLetterTopic: "orders-dead-letter",
+	})
+
+	var acked atomic.Int32
+	p.processBatch(testBatch(&acked, "a", "bad-1", "b"))
+
+	// Невалидное сообщение не ждет MaxAttempts; после неудачной публикации
+	// повторяется только оно, а не вся пачка
+	want := [][]string{{"a", "bad-1", "b"}, {"bad-1"}}
+	if !slices.EqualFunc(handler.calls, want, slices.Equal) {
+		t.Errorf("handler calls = %v, want %v", handler.calls, want)
+	}
+	if len(publisher.published) != 1 || string(publisher.published[0].Key) != "bad-1" {
+		t.Fatalf("published %v, want only bad-1", publisher.published)
+	}
+	if got := publisher.published[0].Headers[DeadLetterErrorHeader]; got != "order_uid is required" {
+		t.Errorf("error header = %q, want the message's own error", got)
+	}
+	if got := acked.Load(); got != 3 {
+		t.Errorf("acked = %d, want 3", got)
+	}
+}
+
+func TestProcessBatchNeverAcksInvalidMessagesWithoutDeadLetter(t *testing.T) {
+	handler := &invalidHandler{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := testProcessor(ctx, handler, WorkerPoolConfig{MaxAttempts: 1})
+
+	var acked atomic.Int32
+	p.processBatch(testBatch(&acked, "a", "bad-1"))
+
+	if got := acked.Load(); got != 0 {
+		t.Fatalf("acked = %d, want 0", got)
+	}
+	if len(handler.calls) < 2 {
+		t.Fatalf("handler calls = %v, want retries until context is cancelled", handler.calls)
+	}
+	for _, keys := range handler.calls[1:] {
+		if !slices.Equal(keys, []string{"bad-1"}) {
+			t.Errorf("retry with %v, want only the invalid message", keys)
+		}
+	}
+}
+
+// orderingHandler запоминает порядок сообщений каждого заказа. Сообщения
+// обрабатываются с задержкой, чтобы воркеры работали одновременно.
+type orderingHandler struct {
+	mu      sync.Mutex
+	seen    map[string][]int
+	handled int
+	want    int
+	done    chan struct{}
+}
+
+func (h *orderingHandler) HandleMessages(messages []*dto.Message) error {
+	time.Sleep(time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, message := range messages {
+		var body struct {
+			OrderUID string `json:"order_uid"`
+			Seq      int    `json:"seq"`
+		}
+		if err := json.Unmarshal(message.Value, &body); err != nil {
+			return err
+		}
+		h.seen[body.OrderUID] = append(h.seen[body.OrderUID], body.Seq)
+		h.handled++
+	}
+	if h.handled == h.want {
+		close(h.done)
+	}
+	return nil
+}
+
+// orderUIDShardKey достает order_uid из тела сообщения
+func orderUIDShardKey(message *dto.Message) []byte {
+	var body struct {
+		OrderUID string `json:"order_uid"`
+	}
+	json.Unmarshal(message.Value, &body)
+	return []byte(body.OrderUID)
+}
+
+func TestWorkerPoolKeepsOrderPerOrderUID(t *testing.T) {
+	const orders, versions = 20, 10
+
+	handler := &orderingHandler{seen: make(map[string][]int), want: orders * versions, done: make(chan struct{})}
+	broker := NewMemoryBroker(orders * versions)
+	consumer := NewMemoryConsumer(broker, []string{"orders"}, handler, WorkerPoolConfig{
+		Workers:     8,
+		QueueSize:   10,
+		BatchSize:   3,
+		BatchLinger: time.Millisecond,
+		ShardKey:    orderUIDShardKey,
+	})
+	if err := consumer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		consumer.Stop(ctx)
+		consumer.Close()
+	}()
+
+	rng := rand.New(rand.NewSource(1))
+	for seq := range versions {
+		for order := range orders {
+			// Ключ брокера у версий одного заказа разный или отсутствует
+			var key []byte
+			if rng.Intn(2) == 0 {
+				key = []byte(strconv.Itoa(rng.Int()))
+			}
+			value := fmt.Sprintf(`{"order_uid":"order-%d","seq":%d}`, order, seq)
+			if err := broker.Publish(&dto.Message{Topic: "orders", Key: key, Value: []byte(value)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	select {
+	case <-handler.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("messages were not processed")
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	for orderUID, seqs := range handler.seen {
+		if !slices.IsSorted(seqs) || len(seqs) != versions {
+			t.Errorf("%s processed out of order: %v", orderUID, seqs)
+		}
+	}
+}
+
+func TestShardKeyFallsBackToMessageKey(t *testing.T) {
+	p := &processor{config: WorkerPoolConfig{ShardKey: orderUIDShardKey}, shards: make([]chan delivery, 16)}
+
+	// Сообщения без ключа брокера распределяются по order_uid, а не в один шард
+	shards := make(map[int]bool)
+	for i := range 100 {
+		message := &dto.Message{Value: []byte(fmt.Sprintf(`{"order_uid":"order-%d"}`, i))}
+		shards[p.shardFor(p.shardKey(message))] = true
+	}
+	if len(shards) < 2 {
+		t.Errorf("keyless messages used %d shards", len(shards))
+	}
+
+	// Без order_uid используется ключ брокера
+	message := &dto.Message{Key: []byte("a"), Value: []byte(`not json`)}
+	if got := p.shardKey(message); string(got) != "a" {
+		t.Errorf("shardKey() = %q, want message key", got)
+	}
+}
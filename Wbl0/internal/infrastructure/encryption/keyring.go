@@ -0,0 +1,303 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// keyring шифрует поля ключами данных из БД (envelope encryption): ключи данных
+// хранятся зашифрованными мастер-ключом и расшифровываются в памяти при загрузке
+type keyring struct {
+	repository interfaces.DataKeyRepository
+	master     *MasterKey
+
+	mu       sync.RWMutex
+	keys     map[int]cipher.AEAD
+	activeID int
+	indexKey []byte
+}
+
+// NewKeyring загружает ключи данных, расшифровывая их мастер-ключом. Если ключа
+// данных или ключа слепого индекса еще нет, он создается. Ключи, зашифрованные
+// другим мастер-ключом, - ошибка: их нужно сначала перешифровать (RewrapKeys).
+func NewKeyring(repository interfaces.DataKeyRepository, master *MasterKey) (interfaces.PIICipher, error) {
+	k := &keyring{
+		repository: repository,
+		master:     master,
+		keys:       make(map[int]cipher.AEAD),
+	}
+
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+	if k.indexKey == nil {
+		if err := k.createIndexKey(); err != nil {
+			return nil, err
+		}
+	}
+	if k.activeID == 0 {
+		if _, err := k.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// load расшифровывает все ключи из БД; активным становится последний ключ данных
+func (k *keyring) load() error {
+	keys, err := k.repository.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		raw, err := k.unwrap(key)
+		if err != nil {
+			return err
+		}
+
+		switch key.Purpose {
+		case dto.DataKeyPurposeData:
+			aead, err := newAEAD(raw)
+			if err != nil {
+				return err
+			}
+			k.keys[key.ID] = aead
+			k.activeID = max(k.activeID, key.ID)
+		case dto.DataKeyPurposeIndex:
+			k.indexKey = raw
+		}
+	}
+
+	return nil
+}
+
+// createIndexKey создает ключ слепого индекса. Если его одновременно создал
+// другой экземпляр, используется уже сохраненный.
+func (k *keyring) createIndexKey() error {
+	raw, id, err := k.create(dto.DataKeyPurposeIndex)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		k.indexKey = raw
+		return nil
+	}
+
+	if err := k.load(); err != nil {
+		return err
+	}
+	if k.indexKey == nil {
+		return fmt.Errorf("failed to load blind index key")
+	}
+	return nil
+}
+
+// create генерирует ключ, сохраняет его зашифрованным и возвращает ключ и его ID
+func (k *keyring) create(purpose string) ([]byte, int, error) {
+	raw := make([]byte, keySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, 0, fmt.Errorf("failed to generate %s key: %w", purpose, err)
+	}
+
+	wrapped, err := k.master.wrap(raw, purpose)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to wrap %s key: %w", purpose, err)
+	}
+
+	id, err := k.repository.Create(dto.DataKey{
+		Purpose:     purpose,
+		WrappedKey:  wrapped,
+		MasterKeyID: k.master.ID(),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return raw, id, nil
+}
+
+func (k *keyring) unwrap(key dto.DataKey) ([]byte, error) {
+	if key.MasterKeyID != k.master.ID() {
+		return nil, fmt.Errorf("data key %d is wrapped with master key %s, loaded master key is %s",
+			key.ID, key.MasterKeyID, k.master.ID())
+	}
+	raw, err := k.master.unwrap(key.WrappedKey, key.Purpose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %d: %w", key.ID, err)
+	}
+	return raw, nil
+}
+
+func (k *keyring) ActiveKeyID() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.activeID
+}
+
+func (k *keyring) Encrypt(keyID int, plaintext, aad string) (string, error) {
+	aead, err := k.dataKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(aead, []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (k *keyring) Decrypt(keyID int, ciphertext, aad string) (string, error) {
+	aead, err := k.dataKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+	plaintext, err := open(aead, data, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// dataKey возвращает ключ данных, загружая из БД неизвестные ключи
+func (k *keyring) dataKey(id int) (cipher.AEAD, error) {
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+	if ok {
+		return aead, nil
+	}
+
+	key, err := k.repository.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.Purpose != dto.DataKeyPurposeData {
+		return nil, fmt.Errorf("data key %d not found", id)
+	}
+
+	raw, err := k.unwrap(*key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err = newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keys[id] = aead
+	k.mu.Unlock()
+	return aead, nil
+}
+
+func (k *keyring) BlindIndex(field, value string) string {
+	value = normalize(field, value)
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalize приводит значение к виду, в котором сравниваются значения слепого
+// индекса: у телефона остаются цифры и ведущий +, email сравнивается без учета регистра
+func normalize(field, value string) string {
+	value = strings.TrimSpace(value)
+
+	switch dto.LookupField(field) {
+	case dto.LookupPhone:
+		var normalized strings.Builder
+		if strings.HasPrefix(value, "+") {
+			normalized.WriteByte('+')
+		}
+		for _, r := range value {
+			if unicode.IsDigit(r) {
+				normalized.WriteRune(r)
+			}
+		}
+		if normalized.Len() == 0 || normalized.String() == "+" {
+			return ""
+		}
+		return normalized.String()
+	case dto.LookupEmail:
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+func (k *keyring) Rotate() (int, error) {
+	raw, id, err := k.create(dto.DataKeyPurposeData)
+	if err != nil {
+		return 0, err
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	k.mu.Lock()
+	k.keys[id] = aead
+	k.activeID = id
+	k.mu.Unlock()
+	return id, nil
+}
+
+// RewrapKeys перешифровывает ключи данных мастер-ключом next. Ключи, уже
+// зашифрованные next, пропускаются, поэтому прерванную смену можно повторить.
+// Возвращает число перешифрованных ключей.
+func RewrapKeys(repository interfaces.DataKeyRepository, previous, next *MasterKey) (int, error) {
+	keys, err := repository.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	var rewrapped []dto.DataKey
+	for _, key := range keys {
+		switch key.MasterKeyID {
+		case next.ID():
+			continue
+		case previous.ID():
+		default:
+			return 0, fmt.Errorf("data key %d is wrapped with unknown master key %s", key.ID, key.MasterKeyID)
+		}
+
+		raw, err := previous.unwrap(key.WrappedKey, key.Purpose)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unwrap data key %d: %w", key.ID, err)
+		}
+		key.WrappedKey, err = next.wrap(raw, key.Purpose)
+		if err != nil {
+			return 0, fmt.Errorf("failed to wrap data key %d: %w", key.ID, err)
+		}
+		key.MasterKeyID = next.ID()
+		rewrapped = append(rewrapped, key)
+	}
+
+	if len(rewrapped) == 0 {
+		return 0, nil
+	}
+	if err := repository.UpdateWrapped(rewrapped); err != nil {
+		return 0, err
+	}
+	return len(rewrapped), nil
+}
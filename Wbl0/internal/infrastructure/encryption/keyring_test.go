@@ -0,0 +1,253 @@
+package encryption
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// memoryKeyRepository хранит ключи данных в памяти, как таблица data_keys
+type memoryKeyRepository struct {
+	keys []dto.DataKey
+}
+
+func (r *memoryKeyRepository) GetAll() ([]dto.DataKey, error) {
+	return slices.Clone(r.keys), nil
+}
+
+func (r *memoryKeyRepository) GetByID(id int) (*dto.DataKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryKeyRepository) Create(key dto.DataKey) (int, error) {
+	if key.Purpose == dto.DataKeyPurposeIndex {
+		for _, existing := range r.keys {
+			if existing.Purpose == dto.DataKeyPurposeIndex {
+				return 0, nil
+			}
+		}
+	}
+	key.ID = len(r.keys) + 1
+	r.keys = append(r.keys, key)
+	return key.ID, nil
+}
+
+func (r *memoryKeyRepository) UpdateWrapped(keys []dto.DataKey) error {
+	for _, key := range keys {
+		r.keys[key.ID-1] = key
+	}
+	return nil
+}
+
+func newKeyring(t *testing.T, repository interfaces.DataKeyRepository, master *MasterKey) interfaces.PIICipher {
+	t.Helper()
+
+	keyring, err := NewKeyring(repository, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	repository := &memoryKeyRepository{}
+	keyring := newKeyring(t, repository, newMasterKey(t))
+
+	// Первый запуск создает ключ индекса и ключ данных
+	if len(repository.keys) != 2 || keyring.ActiveKeyID() == 0 {
+		t.Fatalf("keys = %d, active key = %d", len(repository.keys), keyring.ActiveKeyID())
+	}
+
+	keyID := keyring.ActiveKeyID()
+	ciphertext, err := keyring.Encrypt(keyID, "Test Testov", "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ciphertext, "Testov") {
+		t.Fatalf("ciphertext contains plaintext: %s", ciphertext)
+	}
+
+	plaintext, err := keyring.Decrypt(keyID, ciphertext, "order-1")
+	if err != nil || plaintext != "Test Testov" {
+		t.Fatalf("Decrypt() = %q, %v", plaintext, err)
+	}
+
+	// aad привязывает шифртекст к заказу: значение не перенести в другую строку
+	if _, err := keyring.Decrypt(keyID, ciphertext, "order-2"); err == nil {
+		t.Error("ciphertext decrypted with another aad")
+	}
+	if _, err := keyring.Decrypt(keyID+100, ciphertext, "order-1"); err == nil {
+		t.Error("ciphertext decrypted with unknown key")
+	}
+	if _, err := keyring.Decrypt(keyID, "not base64!", "order-1"); err == nil {
+		t.Error("invalid ciphertext decrypted")
+	}
+
+	// Одинаковые значения шифруются по-разному
+	again, err := keyring.Encrypt(keyID, "Test Testov", "order-1")
+	if err != nil || again == ciphertext {
+		t.Errorf("Encrypt() repeated ciphertext: %v", err)
+	}
+}
+
+func TestKeyringRotate(t *testing.T) {
+	repository := &memoryKeyRepository{}
+	master := newMasterKey(t)
+	keyring := newKeyring(t, repository, master)
+	// Экземпляр, запущенный до ротации
+	stale := newKeyring(t, repository, master)
+
+	oldID := keyring.ActiveKeyID()
+	oldCiphertext, err := keyring.Encrypt(oldID, "+79161234567", "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newID, err := keyring.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newID == oldID || keyring.ActiveKeyID() != newID {
+		t.Fatalf("Rotate() = %d, active key %d, old key %d", newID, keyring.ActiveKeyID(), oldID)
+	}
+	newCiphertext, err := keyring.Encrypt(newID, "+79161234567", "order-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Строки под старым ключом читаются после ротации, в том числе новым
+	// экземпляром, а экземпляр до ротации догружает новый ключ из БД
+	for name, reader := range map[string]interfaces.PIICipher{
+		"rotated":      keyring,
+		"restarted":    newKeyring(t, repository, master),
+		"not reloaded": stale,
+	} {
+		if got, err := reader.Decrypt(oldID, oldCiphertext, "order-1"); err != nil || got != "+79161234567" {
+			t.Errorf("%s: Decrypt(old key) = %q, %v", name, got, err)
+		}
+		if got, err := reader.Decrypt(newID, newCiphertext, "order-2"); err != nil || got != "+79161234567" {
+			t.Errorf("%s: Decrypt(new key) = %q, %v", name, got, err)
+		}
+	}
+
+	if restarted := newKeyring(t, repository, master); restarted.ActiveKeyID() != newID {
+		t.Errorf("restarted keyring active key = %d, want %d", restarted.ActiveKeyID(), newID)
+	}
+}
+
+func TestNewKeyringRejectsOtherMasterKey(t *testing.T) {
+	repository := &memoryKeyRepository{}
+	newKeyring(t, repository, newMasterKey(t))
+
+	if _, err := NewKeyring(repository, newMasterKey(t)); err == nil {
+		t.Error("keys wrapped with another master key were loaded")
+	}
+}
+
+func TestRewrapKeys(t *testing.T) {
+	repository := &memoryKeyRepository{}
+	previous := newMasterKey(t)
+	next := newMasterKey(t)
+
+	keyring := newKeyring(t, repository, previous)
+	keyID := keyring.ActiveKeyID()
+	ciphertext, err := keyring.Encrypt(keyID, "Ploshad Mira 15", "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ротация до смены мастер-ключа: перешифровать нужно оба ключа данных
+	if _, err := keyring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	index := keyring.BlindIndex(string(dto.LookupEmail), "test@gmail.com")
+
+	// Ключ индекса и два ключа данных
+	count, err := RewrapKeys(repository, previous, next)
+	if err != nil || count != 3 {
+		t.Fatalf("RewrapKeys() = %d, %v, want 3", count, err)
+	}
+	for _, key := range repository.keys {
+		if key.MasterKeyID != next.ID() {
+			t.Errorf("key %d is wrapped with %s, want %s", key.ID, key.MasterKeyID, next.ID())
+		}
+	}
+
+	// Прерванную смену можно повторить: перешифрованные ключи пропускаются
+	if count, err := RewrapKeys(repository, previous, next); err != nil || count != 0 {
+		t.Errorf("repeated RewrapKeys() = %d, %v, want 0", count, err)
+	}
+
+	// Данные и слепой индекс не меняются: перешифрованы только ключи
+	rewrapped := newKeyring(t, repository, next)
+	if got, err := rewrapped.Decrypt(keyID, ciphertext, "order-1"); err != nil || got != "Ploshad Mira 15" {
+		t.Errorf("Decrypt() after rewrap = %q, %v", got, err)
+	}
+	if got := rewrapped.BlindIndex(string(dto.LookupEmail), "test@gmail.com"); got != index {
+		t.Error("blind index changed after rewrap")
+	}
+
+	if _, err := NewKeyring(repository, previous); err == nil {
+		t.Error("keys loaded with the previous master key after rewrap")
+	}
+	if _, err := RewrapKeys(repository, previous, newMasterKey(t)); err == nil {
+		t.Error("keys wrapped with an unknown master key were rewrapped")
+	}
+}
+
+func TestBlindIndexNormalization(t *testing.T) {
+	keyring := newKeyring(t, &memoryKeyRepository{}, newMasterKey(t))
+	phone, email := string(dto.LookupPhone), string(dto.LookupEmail)
+
+	same := []struct {
+		field string
+		a, b  string
+	}{
+		{phone, "+79161234567", "+7 (916) 123-45-67"},
+		{phone, "+79161234567", " +7 916 123 45 67 "},
+		{phone, "89161234567", "8-916-123-45-67"},
+		{email, "test@gmail.com", "Test@Gmail.COM"},
+		{email, "test@gmail.com", "  test@gmail.com "},
+	}
+	for _, tt := range same {
+		if keyring.BlindIndex(tt.field, tt.a) != keyring.BlindIndex(tt.field, tt.b) {
+			t.Errorf("BlindIndex(%s, %q) != BlindIndex(%q)", tt.field, tt.a, tt.b)
+		}
+	}
+
+	different := []struct {
+		fieldA, a string
+		fieldB, b string
+	}{
+		// Ведущий + значим: номер без кода страны - другой номер
+		{phone, "+79161234567", phone, "79161234567"},
+		{phone, "+79161234567", phone, "+79161234568"},
+		{email, "test@gmail.com", email, "test@gmail.co"},
+		// Поле входит в индекс: одно значение в разных полях не совпадает
+		{phone, "12345", email, "12345"},
+	}
+	for _, tt := range different {
+		if keyring.BlindIndex(tt.fieldA, tt.a) == keyring.BlindIndex(tt.fieldB, tt.b) {
+			t.Errorf("BlindIndex(%s, %q) == BlindIndex(%s, %q)", tt.fieldA, tt.a, tt.fieldB, tt.b)
+		}
+	}
+
+	for _, value := range []string{"", "  ", "+", "+ ()-"} {
+		if got := keyring.BlindIndex(phone, value); got != "" {
+			t.Errorf("BlindIndex(phone, %q) = %q, want empty", value, got)
+		}
+	}
+
+	// Индекс зависит от ключа базы, а не только от значения
+	other := newKeyring(t, &memoryKeyRepository{}, newMasterKey(t))
+	if keyring.BlindIndex(email, "test@gmail.com") == other.BlindIndex(email, "test@gmail.com") {
+		t.Error("blind index does not depend on the index key")
+	}
+}
@@ -0,0 +1,107 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// keySize - размер мастер-ключа и ключей данных (AES-256)
+const keySize = 32
+
+// MasterKey шифрует ключи данных. Сам мастер-ключ в БД не попадает,
+// там хранится только его отпечаток.
+type MasterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// GenerateMasterKey возвращает новый случайный мастер-ключ в base64 для файла ключа
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate master key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadMasterKey читает мастер-ключ из файла: 32 байта в base64
+func LoadMasterKey(path string) (*MasterKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode master key %s: %w", path, err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key %s must be %d bytes, got %d", path, keySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(key)
+	return &MasterKey{id: hex.EncodeToString(sum[:8]), aead: aead}, nil
+}
+
+// ID возвращает отпечаток ключа - первые 8 байт SHA-256 в hex
+func (m *MasterKey) ID() string {
+	return m.id
+}
+
+// wrap шифрует ключ данных. purpose входит в aad, чтобы ключ данных
+// нельзя было подставить вместо ключа индекса.
+func (m *MasterKey) wrap(key []byte, purpose string) ([]byte, error) {
+	return seal(m.aead, key, purpose)
+}
+
+// unwrap расшифровывает ключ данных
+func (m *MasterKey) unwrap(wrapped []byte, purpose string) ([]byte, error) {
+	return open(m.aead, wrapped, purpose)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+// seal шифрует plaintext со случайным nonce и возвращает nonce и шифртекст подряд
+func seal(aead cipher.AEAD, plaintext []byte, aad string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(aad)), nil
+}
+
+// open расшифровывает результат seal
+func open(aead cipher.AEAD, data []byte, aad string) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
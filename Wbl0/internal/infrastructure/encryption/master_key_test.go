@@ -0,0 +1,84 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newMasterKey создает файл со случайным мастер-ключом и загружает его
+func newMasterKey(t *testing.T) *MasterKey {
+	t.Helper()
+
+	encoded, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "master.key")
+	// Перевод строки в конце файла допустим
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	master, err := LoadMasterKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master
+}
+
+func TestLoadMasterKeyRejectsInvalidFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not base64", "not a key!"},
+		{"short key", base64.StdEncoding.EncodeToString(make([]byte, 16))},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "master.key")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMasterKey(path); err == nil {
+				t.Error("invalid master key was loaded")
+			}
+		})
+	}
+
+	if _, err := LoadMasterKey(filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Error("missing master key file was loaded")
+	}
+}
+
+func TestMasterKeyWrap(t *testing.T) {
+	master := newMasterKey(t)
+	other := newMasterKey(t)
+	if master.ID() == other.ID() || len(master.ID()) != 16 {
+		t.Fatalf("master key IDs = %q, %q", master.ID(), other.ID())
+	}
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	wrapped, err := master.wrap(key, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unwrapped, err := master.unwrap(wrapped, "data")
+	if err != nil || string(unwrapped) != string(key) {
+		t.Fatalf("unwrap() = %q, %v", unwrapped, err)
+	}
+	// Назначение входит в aad: ключ данных не подставить вместо ключа индекса
+	if _, err := master.unwrap(wrapped, "index"); err == nil {
+		t.Error("data key unwrapped as index key")
+	}
+	if _, err := other.unwrap(wrapped, "data"); err == nil {
+		t.Error("key unwrapped with another master key")
+	}
+	if _, err := master.unwrap(wrapped[:8], "data"); err == nil {
+		t.Error("truncated key unwrapped")
+	}
+}
@@ -0,0 +1,84 @@
+package exporters
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// CSVHeader - колонки CSV-выгрузки в порядке полей row
+var CSVHeader = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address",
+	"delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider",
+	"payment_amount", "payment_dt", "payment_bank", "payment_delivery_cost",
+	"payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale",
+	"item_size", "item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+// csvWriter записывает заказы плоскими строками, по одной на товар
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (interfaces.OrderWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSVHeader); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (w *csvWriter) Write(order *entities.Order) error {
+	for _, r := range flatten(order) {
+		record := []string{
+			r.OrderUID, r.TrackNumber, r.Entry, r.Locale, r.InternalSignature, r.CustomerID,
+			r.DeliveryService, r.ShardKey, formatInt(r.SmID),
+			time.UnixMilli(r.DateCreated).UTC().Format(time.RFC3339Nano), r.OofShard,
+			r.DeliveryName, r.DeliveryPhone, r.DeliveryZip, r.DeliveryCity, r.DeliveryAddress,
+			r.DeliveryRegion, r.DeliveryEmail,
+			r.PaymentTransaction, r.PaymentRequestID, r.PaymentCurrency, r.PaymentProvider,
+			formatInt(r.PaymentAmount), formatInt(r.PaymentDt), r.PaymentBank, formatInt(r.PaymentDeliveryCost),
+			formatInt(r.PaymentGoodsTotal), formatInt(r.PaymentCustomFee),
+			formatOptionalInt(r.ItemChrtID), optionalString(r.ItemTrackNumber), formatOptionalInt(r.ItemPrice),
+			optionalString(r.ItemRid), optionalString(r.ItemName), formatOptionalInt(r.ItemSale),
+			optionalString(r.ItemSize), formatOptionalInt(r.ItemTotalPrice), formatOptionalInt(r.ItemNmID),
+			optionalString(r.ItemBrand), formatOptionalInt(r.ItemStatus),
+		}
+		if err := w.writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func formatOptionalInt(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return formatInt(*value)
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
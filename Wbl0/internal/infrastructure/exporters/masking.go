@@ -0,0 +1,21 @@
+package exporters
+
+import (
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// maskingWriter маскирует данные получателя перед записью заказа
+type maskingWriter struct {
+	interfaces.OrderWriter
+	masker interfaces.PIIMasker
+}
+
+// NewMaskingWriter оборачивает запись заказов маскированием персональных данных
+func NewMaskingWriter(writer interfaces.OrderWriter, masker interfaces.PIIMasker) interfaces.OrderWriter {
+	return &maskingWriter{OrderWriter: writer, masker: masker}
+}
+
+func (w *maskingWriter) Write(order *entities.Order) error {
+	return w.OrderWriter.Write(w.masker.MaskOrder(order))
+}
@@ -0,0 +1,26 @@
+package exporters
+
+import (
+	"encoding/json"
+	"io"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// ndjsonWriter записывает по одному полному заказу в JSON на строку
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) interfaces.OrderWriter {
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *ndjsonWriter) Write(order *entities.Order) error {
+	return w.encoder.Encode(order)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package exporters
+
+import (
+	"fmt"
+	"io"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRowGroupSize ограничивает буфер группы строк: по умолчанию он 128 МБ,
+// а выгрузка должна занимать постоянную и небольшую память
+const parquetRowGroupSize = 16 * 1024 * 1024
+
+// parquetWriter записывает заказы плоскими строками (как CSV) в Parquet
+type parquetWriter struct {
+	writer *writer.ParquetWriter
+}
+
+func newParquetWriter(w io.Writer) (interfaces.OrderWriter, error) {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(row), 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	return &parquetWriter{writer: pw}, nil
+}
+
+func (w *parquetWriter) Write(order *entities.Order) error {
+	for _, r := range flatten(order) {
+		if err := w.writer.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	return w.writer.WriteStop()
+}
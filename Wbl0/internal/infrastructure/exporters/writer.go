@@ -0,0 +1,156 @@
+package exporters
+
+import (
+	"fmt"
+	"io"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// Форматы выгрузки заказов
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Formats - поддерживаемые форматы выгрузки
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// NewOrderWriter создает запись заказов в w в указанном формате
+func NewOrderWriter(format string, w io.Writer) (interfaces.OrderWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatParquet:
+		return newParquetWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// ContentType возвращает MIME-тип формата выгрузки
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// row - плоская строка выгрузки: заказ с одним товаром.
+// Заказ без товаров выгружается одной строкой с пустыми полями товара.
+type row struct {
+	OrderUID          string `parquet:"name=order_uid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TrackNumber       string `parquet:"name=track_number, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Entry             string `parquet:"name=entry, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Locale            string `parquet:"name=locale, type=BYTE_ARRAY, convertedtype=UTF8"`
+	InternalSignature string `parquet:"name=internal_signature, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CustomerID        string `parquet:"name=customer_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryService   string `parquet:"name=delivery_service, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ShardKey          string `parquet:"name=shardkey, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SmID              int64  `parquet:"name=sm_id, type=INT64"`
+	DateCreated       int64  `parquet:"name=date_created, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	OofShard          string `parquet:"name=oof_shard, type=BYTE_ARRAY, convertedtype=UTF8"`
+
+	DeliveryName    string `parquet:"name=delivery_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryPhone   string `parquet:"name=delivery_phone, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryZip     string `parquet:"name=delivery_zip, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryCity    string `parquet:"name=delivery_city, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryAddress string `parquet:"name=delivery_address, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryRegion  string `parquet:"name=delivery_region, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DeliveryEmail   string `parquet:"name=delivery_email, type=BYTE_ARRAY, convertedtype=UTF8"`
+
+	PaymentTransaction  string `parquet:"name=payment_transaction, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentRequestID    string `parquet:"name=payment_request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentCurrency     string `parquet:"name=payment_currency, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentProvider     string `parquet:"name=payment_provider, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentAmount       int64  `parquet:"name=payment_amount, type=INT64"`
+	PaymentDt           int64  `parquet:"name=payment_dt, type=INT64"`
+	PaymentBank         string `parquet:"name=payment_bank, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PaymentDeliveryCost int64  `parquet:"name=payment_delivery_cost, type=INT64"`
+	PaymentGoodsTotal   int64  `parquet:"name=payment_goods_total, type=INT64"`
+	PaymentCustomFee    int64  `parquet:"name=payment_custom_fee, type=INT64"`
+
+	ItemChrtID      *int64  `parquet:"name=item_chrt_id, type=INT64, repetitiontype=OPTIONAL"`
+	ItemTrackNumber *string `parquet:"name=item_track_number, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemPrice       *int64  `parquet:"name=item_price, type=INT64, repetitiontype=OPTIONAL"`
+	ItemRid         *string `parquet:"name=item_rid, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemName        *string `parquet:"name=item_name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemSale        *int64  `parquet:"name=item_sale, type=INT64, repetitiontype=OPTIONAL"`
+	ItemSize        *string `parquet:"name=item_size, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemTotalPrice  *int64  `parquet:"name=item_total_price, type=INT64, repetitiontype=OPTIONAL"`
+	ItemNmID        *int64  `parquet:"name=item_nm_id, type=INT64, repetitiontype=OPTIONAL"`
+	ItemBrand       *string `parquet:"name=item_brand, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	ItemStatus      *int64  `parquet:"name=item_status, type=INT64, repetitiontype=OPTIONAL"`
+}
+
+// flatten разворачивает заказ в строки выгрузки, по одной на товар
+func flatten(order *entities.Order) []row {
+	base := row{
+		OrderUID:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerID:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		ShardKey:          order.ShardKey,
+		SmID:              int64(order.SmID),
+		DateCreated:       order.DateCreated.UnixMilli(),
+		OofShard:          order.OofShard,
+
+		DeliveryName:    order.Delivery.Name,
+		DeliveryPhone:   order.Delivery.Phone,
+		DeliveryZip:     order.Delivery.Zip,
+		DeliveryCity:    order.Delivery.City,
+		DeliveryAddress: order.Delivery.Address,
+		DeliveryRegion:  order.Delivery.Region,
+		DeliveryEmail:   order.Delivery.Email,
+
+		PaymentTransaction:  order.Payment.Transaction,
+		PaymentRequestID:    order.Payment.RequestID,
+		PaymentCurrency:     order.Payment.Currency,
+		PaymentProvider:     order.Payment.Provider,
+		PaymentAmount:       int64(order.Payment.Amount),
+		PaymentDt:           order.Payment.PaymentDt,
+		PaymentBank:         order.Payment.Bank,
+		PaymentDeliveryCost: int64(order.Payment.DeliveryCost),
+		PaymentGoodsTotal:   int64(order.Payment.GoodsTotal),
+		PaymentCustomFee:    int64(order.Payment.CustomFee),
+	}
+
+	if len(order.Items) == 0 {
+		return []row{base}
+	}
+
+	rows := make([]row, len(order.Items))
+	for i, item := range order.Items {
+		rows[i] = base
+		rows[i].ItemChrtID = int64Ptr(item.ChrtID)
+		rows[i].ItemTrackNumber = &item.TrackNumber
+		rows[i].ItemPrice = int64Ptr(item.Price)
+		rows[i].ItemRid = &item.Rid
+		rows[i].ItemName = &item.Name
+		rows[i].ItemSale = int64Ptr(item.Sale)
+		rows[i].ItemSize = &item.Size
+		rows[i].ItemTotalPrice = int64Ptr(item.TotalPrice)
+		rows[i].ItemNmID = int64Ptr(item.NmID)
+		rows[i].ItemBrand = &item.Brand
+		rows[i].ItemStatus = int64Ptr(item.Status)
+	}
+	return rows
+}
+
+func int64Ptr(value int) *int64 {
+	v := int64(value)
+	return &v
+}
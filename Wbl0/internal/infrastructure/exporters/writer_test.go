@@ -0,0 +1,209 @@
+package exporters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// testOrder возвращает заказ с несколькими товарами; строковые поля содержат
+// запятые, кавычки и переводы строк, требующие экранирования в CSV
+func testOrder() *entities.Order {
+	return &entities.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Delivery: entities.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15, кв. \"7\"",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: entities.Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []entities.Item{
+			{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest", Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtID: 9934931, TrackNumber: "WBILMTESTTRACK", Price: 100, Rid: "ab4219087a764ae1btest", Name: "Книга\n\"Go на практике\"", Size: "0", TotalPrice: 100, NmID: 1456607, Brand: "ДМК Пресс", Status: 202},
+		},
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		ShardKey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		OofShard:        "1",
+	}
+}
+
+func TestFlattenOneRowPerItem(t *testing.T) {
+	order := testOrder()
+	rows := flatten(order)
+
+	if len(rows) != len(order.Items) {
+		t.Fatalf("flatten() = %d rows, want %d", len(rows), len(order.Items))
+	}
+	for i, r := range rows {
+		if r.OrderUID != order.OrderUID || r.DeliveryName != order.Delivery.Name || r.PaymentAmount != int64(order.Payment.Amount) {
+			t.Errorf("row %d does not repeat order fields: %+v", i, r)
+		}
+		if r.ItemChrtID == nil || *r.ItemChrtID != int64(order.Items[i].ChrtID) || *r.ItemName != order.Items[i].Name {
+			t.Errorf("row %d has wrong item fields", i)
+		}
+	}
+	if r := rows[0]; r.DateCreated != order.DateCreated.UnixMilli() {
+		t.Errorf("date_created = %d, want %d", r.DateCreated, order.DateCreated.UnixMilli())
+	}
+
+	// Заказ без товаров - одна строка с пустыми полями товара
+	order.Items = nil
+	rows = flatten(order)
+	if len(rows) != 1 || rows[0].ItemChrtID != nil || rows[0].ItemName != nil {
+		t.Errorf("flatten() of order without items = %+v", rows)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewOrderWriter(FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withItems := testOrder()
+	withoutItems := testOrder()
+	withoutItems.OrderUID = "without-items"
+	withoutItems.Items = nil
+	for _, order := range []*entities.Order{withItems, withoutItems} {
+		if err := writer.Write(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want header and 3 rows", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(CSVHeader, ",") {
+		t.Errorf("header = %v", records[0])
+	}
+
+	column := make(map[string]int, len(CSVHeader))
+	for i, name := range CSVHeader {
+		column[name] = i
+	}
+	if got := records[1][column["date_created"]]; got != "2021-11-26T06:22:19Z" {
+		t.Errorf("date_created = %q", got)
+	}
+	if got := records[1][column["delivery_address"]]; got != withItems.Delivery.Address {
+		t.Errorf("delivery_address = %q", got)
+	}
+	if got := records[2][column["item_name"]]; got != withItems.Items[1].Name {
+		t.Errorf("item_name = %q", got)
+	}
+	if got := records[3][column["item_chrt_id"]]; got != "" || records[3][column["order_uid"]] != "without-items" {
+		t.Errorf("order without items exported as %v", records[3])
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewOrderWriter(FormatNDJSON, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := testOrder()
+	second := testOrder()
+	second.OrderUID = "second"
+	for _, order := range []*entities.Order{first, second} {
+		if err := writer.Write(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Один полный заказ на строку, товары не разворачиваются
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	for i, want := range []*entities.Order{first, second} {
+		var got entities.Order
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("line %d = %s, want %s", i+1, gotJSON, wantJSON)
+		}
+	}
+}
+
+// upperNameMasker подменяет имя получателя, чтобы было видно, что маскирование применено.
+// Остальные методы интерфейса не реализованы.
+type upperNameMasker struct {
+	interfaces.PIIMasker
+}
+
+func (upperNameMasker) MaskOrder(order *entities.Order) *entities.Order {
+	masked := *order
+	masked.Delivery.Name = strings.ToUpper(order.Delivery.Name)
+	return &masked
+}
+
+func TestMaskingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewOrderWriter(FormatNDJSON, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer = NewMaskingWriter(writer, upperNameMasker{})
+
+	order := testOrder()
+	if err := writer.Write(order); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "TEST TESTOV") {
+		t.Errorf("masked export = %s", buf.String())
+	}
+	if order.Delivery.Name != "Test Testov" {
+		t.Error("masking changed the source order")
+	}
+}
+
+func TestNewOrderWriterUnsupportedFormat(t *testing.T) {
+	if _, err := NewOrderWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("unsupported format accepted")
+	}
+}
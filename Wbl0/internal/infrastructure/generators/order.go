@@ -0,0 +1,247 @@
+package generators
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// market - связанные между собой локаль, валюта и адреса покупателей
+type market struct {
+	locale   string
+	currency string
+	phone    string
+	cities   []city
+	names    []string
+}
+
+type city struct {
+	name   string
+	region string
+	zip    string
+}
+
+var markets = []market{
+	{
+		locale: "ru", currency: "RUB", phone: "+7",
+		cities: []city{
+			{"Москва", "Москва", "101000"},
+			{"Санкт-Петербург", "Санкт-Петербург", "190000"},
+			{"Казань", "Татарстан", "420000"},
+			{"Новосибирск", "Новосибирская область", "630000"},
+		},
+		names: []string{"Иван Петров", "Анна Смирнова", "Олег Кузнецов", "Мария Иванова"},
+	},
+	{
+		locale: "kk", currency: "KZT", phone: "+7",
+		cities: []city{
+			{"Алматы", "Алматы", "050000"},
+			{"Астана", "Астана", "010000"},
+		},
+		names: []string{"Айгерим Сейткали", "Нурлан Абенов"},
+	},
+	{
+		locale: "be", currency: "BYN", phone: "+375",
+		cities: []city{
+			{"Минск", "Минск", "220000"},
+			{"Гомель", "Гомельская область", "246000"},
+		},
+		names: []string{"Алесь Ковалев", "Ольга Шевчук"},
+	},
+	{
+		locale: "en", currency: "USD", phone: "+1",
+		cities: []city{
+			{"New York", "NY", "10001"},
+			{"Los Angeles", "CA", "90001"},
+			{"Kiryat Mozkin", "Kraiot", "2639809"},
+		},
+		names: []string{"John Doe", "Jane Smith", "Test Testov"},
+	},
+	{
+		locale: "de", currency: "EUR", phone: "+49",
+		cities: []city{
+			{"Berlin", "Berlin", "10115"},
+			{"München", "Bayern", "80331"},
+		},
+		names: []string{"Max Müller", "Lena Schmidt"},
+	},
+}
+
+type product struct {
+	name  string
+	brand string
+	nmID  int
+	price int
+	sizes []string
+}
+
+var products = []product{
+	{"Mascaras", "Vivienne Sabo", 2389212, 453, []string{"0"}},
+	{"Футболка хлопковая", "Befree", 1456001, 999, []string{"S", "M", "L", "XL"}},
+	{"Кроссовки беговые", "Demix", 1456102, 4599, []string{"40", "41", "42", "43", "44"}},
+	{"Рюкзак городской", "Xiaomi", 1456203, 2890, []string{"0"}},
+	{"Наушники беспроводные", "JBL", 1456304, 3990, []string{"0"}},
+	{"Платье летнее", "Zarina", 1456405, 2199, []string{"XS", "S", "M", "L"}},
+	{"Чайник электрический", "Polaris", 1456506, 1790, []string{"0"}},
+	{"Книга \"Go на практике\"", "ДМК Пресс", 1456607, 1350, []string{"0"}},
+}
+
+var (
+	deliveryServices = []string{"meest", "cdek", "boxberry", "wb", "dhl"}
+	providers        = []string{"wbpay", "sbp", "card", "applepay"}
+	banks            = []string{"alpha", "sber", "tinkoff", "vtb", "kaspi"}
+	entries          = []string{"WBIL", "WBRU", "WBKZ"}
+	emailDomains     = []string{"gmail.com", "mail.ru", "yandex.ru", "example.com"}
+)
+
+// OrderGenerator создает реалистичные случайные заказы: суммы сходятся
+// (total_price = price со скидкой, goods_total = сумма товаров,
+// amount = goods_total + delivery_cost + custom_fee). С одним seed
+// последовательность заказов повторяется, кроме дат: они отсчитываются от текущего времени.
+// Не безопасен для конкурентного использования.
+type OrderGenerator struct {
+	rand *rand.Rand
+}
+
+// NewOrderGenerator создает генератор заказов с заданным seed
+func NewOrderGenerator(seed uint64) *OrderGenerator {
+	return &OrderGenerator{rand: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+// Order возвращает следующий случайный заказ
+func (g *OrderGenerator) Order() *entities.Order {
+	m := markets[g.rand.IntN(len(markets))]
+	c := m.cities[g.rand.IntN(len(m.cities))]
+	name := m.names[g.rand.IntN(len(m.names))]
+
+	orderUID := g.hex(16) + "test"
+	trackNumber := "WB" + g.letters(12)
+	created := time.Now().UTC().Add(-time.Duration(g.rand.IntN(30*24*60)) * time.Minute).Truncate(time.Second)
+
+	items := make([]entities.Item, 1+g.rand.IntN(5))
+	goodsTotal := 0
+	for i := range items {
+		p := products[g.rand.IntN(len(products))]
+		sale := []int{0, 0, 10, 15, 20, 30, 50}[g.rand.IntN(7)]
+		totalPrice := p.price * (100 - sale) / 100
+		goodsTotal += totalPrice
+
+		items[i] = entities.Item{
+			ChrtID:      1000000 + g.rand.IntN(9000000),
+			TrackNumber: trackNumber,
+			Price:       p.price,
+			Rid:         g.hex(16) + "test",
+			Name:        p.name,
+			Sale:        sale,
+			Size:        p.sizes[g.rand.IntN(len(p.sizes))],
+			TotalPrice:  totalPrice,
+			NmID:        p.nmID,
+			Brand:       p.brand,
+			Status:      202,
+		}
+	}
+
+	deliveryCost := []int{0, 0, 150, 300, 500}[g.rand.IntN(5)]
+	customFee := 0
+	if g.rand.IntN(10) == 0 {
+		customFee = goodsTotal / 20
+	}
+
+	return &entities.Order{
+		OrderUID:    orderUID,
+		TrackNumber: trackNumber,
+		Entry:       entries[g.rand.IntN(len(entries))],
+		Delivery: entities.Delivery{
+			Name:    name,
+			Phone:   m.phone + g.digits(10),
+			Zip:     c.zip,
+			City:    c.name,
+			Address: fmt.Sprintf("ул. Тестовая %d", 1+g.rand.IntN(200)),
+			Region:  c.region,
+			Email:   fmt.Sprintf("user%d@%s", g.rand.IntN(100000), emailDomains[g.rand.IntN(len(emailDomains))]),
+		},
+		Payment: entities.Payment{
+			Transaction:  orderUID,
+			RequestID:    "",
+			Currency:     m.currency,
+			Provider:     providers[g.rand.IntN(len(providers))],
+			Amount:       goodsTotal + deliveryCost + customFee,
+			PaymentDt:    created.Add(time.Duration(g.rand.IntN(600)) * time.Second).Unix(),
+			Bank:         banks[g.rand.IntN(len(banks))],
+			DeliveryCost: deliveryCost,
+			GoodsTotal:   goodsTotal,
+			CustomFee:    customFee,
+		},
+		Items:             items,
+		Locale:            m.locale,
+		InternalSignature: "",
+		CustomerID:        fmt.Sprintf("customer-%d", g.rand.IntN(1000)),
+		DeliveryService:   deliveryServices[g.rand.IntN(len(deliveryServices))],
+		ShardKey:          fmt.Sprint(g.rand.IntN(10)),
+		SmID:              g.rand.IntN(100),
+		DateCreated:       created,
+		OofShard:          fmt.Sprint(1 + g.rand.IntN(2)),
+	}
+}
+
+// invalidPayloads - заготовки невалидных сообщений для проверки обработки ошибок
+var invalidPayloads = []struct {
+	name    string
+	payload func(g *OrderGenerator) []byte
+}{
+	{"malformed json", func(g *OrderGenerator) []byte {
+		return []byte(`{"order_uid": "` + g.hex(8) + `", "items": [`)
+	}},
+	{"missing order_uid", func(g *OrderGenerator) []byte {
+		return []byte(`{"track_number": "WB` + g.letters(12) + `", "items": []}`)
+	}},
+	{"wrong types", func(g *OrderGenerator) []byte {
+		return []byte(`{"order_uid": "` + g.hex(16) + `invalid", "payment": {"amount": "много"}, "items": {}}`)
+	}},
+	{"empty", func(g *OrderGenerator) []byte {
+		return []byte{}
+	}},
+}
+
+// Invalid возвращает вид и тело невалидного сообщения случайного вида
+func (g *OrderGenerator) Invalid() (string, []byte) {
+	kind := invalidPayloads[g.rand.IntN(len(invalidPayloads))]
+	return kind.name, kind.payload(g)
+}
+
+// ID возвращает случайный идентификатор, например ключ для невалидного сообщения
+func (g *OrderGenerator) ID() string {
+	return g.hex(16)
+}
+
+// Chance возвращает true с вероятностью p
+func (g *OrderGenerator) Chance(p float64) bool {
+	return g.rand.Float64() < p
+}
+
+func (g *OrderGenerator) hex(n int) string {
+	const alphabet = "0123456789abcdef"
+	return g.pick(alphabet, n)
+}
+
+func (g *OrderGenerator) letters(n int) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	return g.pick(alphabet, n)
+}
+
+func (g *OrderGenerator) digits(n int) string {
+	const alphabet = "0123456789"
+	return g.pick(alphabet, n)
+}
+
+func (g *OrderGenerator) pick(alphabet string, n int) string {
+	var b strings.Builder
+	b.Grow(n)
+	for range n {
+		b.WriteByte(alphabet[g.rand.IntN(len(alphabet))])
+	}
+	return b.String()
+}
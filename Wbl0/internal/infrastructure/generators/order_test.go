@@ -0,0 +1,100 @@
+package generators
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/pkg/schemas"
+)
+
+func TestOrderTotals(t *testing.T) {
+	validator, err := schemas.NewOrderValidator(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewOrderGenerator(42)
+	for i := range 500 {
+		order := gen.Order()
+
+		goodsTotal := 0
+		for _, item := range order.Items {
+			if want := item.Price * (100 - item.Sale) / 100; item.TotalPrice != want {
+				t.Errorf("order %d: item total_price = %d, want %d", i, item.TotalPrice, want)
+			}
+			if item.TrackNumber != order.TrackNumber {
+				t.Errorf("order %d: item track_number = %s, want %s", i, item.TrackNumber, order.TrackNumber)
+			}
+			goodsTotal += item.TotalPrice
+		}
+
+		payment := order.Payment
+		if len(order.Items) == 0 || payment.GoodsTotal != goodsTotal {
+			t.Errorf("order %d: goods_total = %d, items total %d (%d items)", i, payment.GoodsTotal, goodsTotal, len(order.Items))
+		}
+		if payment.Amount != payment.GoodsTotal+payment.DeliveryCost+payment.CustomFee {
+			t.Errorf("order %d: amount %d != goods_total %d + delivery_cost %d + custom_fee %d",
+				i, payment.Amount, payment.GoodsTotal, payment.DeliveryCost, payment.CustomFee)
+		}
+		if payment.Transaction != order.OrderUID || payment.PaymentDt < order.DateCreated.Unix() {
+			t.Errorf("order %d: payment %+v does not match the order", i, payment)
+		}
+
+		// Сгенерированные заказы проходят строгую проверку по схеме
+		payload, err := json.Marshal(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := validator.Validate("", payload); err != nil {
+			t.Errorf("order %d does not match the schema: %v", i, err)
+		}
+	}
+}
+
+// withoutDates убирает из заказа даты, отсчитываемые от текущего времени
+func withoutDates(order *entities.Order) *entities.Order {
+	order.Payment.PaymentDt -= order.DateCreated.Unix()
+	order.DateCreated = time.Time{}
+	return order
+}
+
+func TestSameSeedSameOrders(t *testing.T) {
+	first, second, other := NewOrderGenerator(7), NewOrderGenerator(7), NewOrderGenerator(8)
+
+	same := true
+	for i := range 50 {
+		a, b, c := withoutDates(first.Order()), withoutDates(second.Order()), withoutDates(other.Order())
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("order %d differs for the same seed:\n%+v\n%+v", i, a, b)
+		}
+		if !reflect.DeepEqual(a, c) {
+			same = false
+		}
+	}
+	if same {
+		t.Error("different seeds produced the same orders")
+	}
+}
+
+func TestInvalidPayloadsAreRejected(t *testing.T) {
+	validator, err := schemas.NewOrderValidator(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewOrderGenerator(1)
+	kinds := make(map[string]bool)
+	for range 100 {
+		kind, payload := gen.Invalid()
+		kinds[kind] = true
+		if _, err := validator.Validate("", payload); err == nil {
+			t.Errorf("%s payload passed validation: %s", kind, payload)
+		}
+	}
+	if len(kinds) != len(invalidPayloads) {
+		t.Errorf("generated kinds %v, want all %d", kinds, len(invalidPayloads))
+	}
+}
@@ -0,0 +1,200 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/exporters"
+)
+
+// csvReader читает заказы в формате CSV-выгрузки: строка на товар, строки
+// одного заказа идут подряд. Колонки ищутся по заголовку, их порядок не важен.
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+
+	// pending - первая строка следующего заказа, прочитанная заранее
+	pending     []string
+	pendingLine int
+}
+
+func newCSVReader(r io.Reader) (interfaces.OrderRecordReader, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range exporters.CSVHeader {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header has no column %s", name)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvReader) Next() (*dto.ImportRecord, error) {
+	if r.pending == nil {
+		if err := r.readPending(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := [][]string{r.pending}
+	line := r.pendingLine
+	for {
+		err := r.readPending()
+		if errors.Is(err, io.EOF) {
+			r.pending = nil
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.value(r.pending, "order_uid") != r.value(rows[0], "order_uid") {
+			break
+		}
+		rows = append(rows, r.pending)
+	}
+
+	var raw bytes.Buffer
+	writer := csv.NewWriter(&raw)
+	writer.WriteAll(rows)
+
+	record := &dto.ImportRecord{Line: line, Raw: bytes.TrimSpace(raw.Bytes())}
+	order, err := r.parseOrder(rows)
+	if err != nil {
+		record.Err = err
+		return record, nil
+	}
+
+	record.Payload, err = json.Marshal(order)
+	if err != nil {
+		record.Err = fmt.Errorf("failed to marshal order: %w", err)
+	}
+	return record, nil
+}
+
+// readPending читает следующую строку в pending
+func (r *csvReader) readPending() error {
+	row, err := r.reader.Read()
+	if err != nil {
+		return err
+	}
+	r.pending = row
+	r.pendingLine, _ = r.reader.FieldPos(0)
+	return nil
+}
+
+func (r *csvReader) value(row []string, column string) string {
+	return row[r.columns[column]]
+}
+
+// parseOrder собирает заказ из его строк: поля заказа берутся из первой строки,
+// товары - из всех строк с непустым item_chrt_id
+func (r *csvReader) parseOrder(rows [][]string) (*entities.Order, error) {
+	p := &rowParser{reader: r, row: rows[0]}
+
+	order := &entities.Order{
+		OrderUID:          p.string("order_uid"),
+		TrackNumber:       p.string("track_number"),
+		Entry:             p.string("entry"),
+		Locale:            p.string("locale"),
+		InternalSignature: p.string("internal_signature"),
+		CustomerID:        p.string("customer_id"),
+		DeliveryService:   p.string("delivery_service"),
+		ShardKey:          p.string("shardkey"),
+		SmID:              p.int("sm_id"),
+		DateCreated:       p.time("date_created"),
+		OofShard:          p.string("oof_shard"),
+		Delivery: entities.Delivery{
+			Name:    p.string("delivery_name"),
+			Phone:   p.string("delivery_phone"),
+			Zip:     p.string("delivery_zip"),
+			City:    p.string("delivery_city"),
+			Address: p.string("delivery_address"),
+			Region:  p.string("delivery_region"),
+			Email:   p.string("delivery_email"),
+		},
+		Payment: entities.Payment{
+			Transaction:  p.string("payment_transaction"),
+			RequestID:    p.string("payment_request_id"),
+			Currency:     p.string("payment_currency"),
+			Provider:     p.string("payment_provider"),
+			Amount:       p.int("payment_amount"),
+			PaymentDt:    int64(p.int("payment_dt")),
+			Bank:         p.string("payment_bank"),
+			DeliveryCost: p.int("payment_delivery_cost"),
+			GoodsTotal:   p.int("payment_goods_total"),
+			CustomFee:    p.int("payment_custom_fee"),
+		},
+		Items: []entities.Item{},
+	}
+
+	for _, row := range rows {
+		p.row = row
+		if p.string("item_chrt_id") == "" {
+			continue
+		}
+		order.Items = append(order.Items, entities.Item{
+			ChrtID:      p.int("item_chrt_id"),
+			TrackNumber: p.string("item_track_number"),
+			Price:       p.int("item_price"),
+			Rid:         p.string("item_rid"),
+			Name:        p.string("item_name"),
+			Sale:        p.int("item_sale"),
+			Size:        p.string("item_size"),
+			TotalPrice:  p.int("item_total_price"),
+			NmID:        p.int("item_nm_id"),
+			Brand:       p.string("item_brand"),
+			Status:      p.int("item_status"),
+		})
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return order, nil
+}
+
+// rowParser разбирает поля строки CSV, запоминая первую ошибку
+type rowParser struct {
+	reader *csvReader
+	row    []string
+	err    error
+}
+
+func (p *rowParser) string(column string) string {
+	return p.reader.value(p.row, column)
+}
+
+func (p *rowParser) int(column string) int {
+	value, err := strconv.Atoi(p.string(column))
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return value
+}
+
+func (p *rowParser) time(column string) time.Time {
+	value, err := time.Parse(time.RFC3339Nano, p.string(column))
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return value
+}
@@ -0,0 +1,81 @@
+package importers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// ndjsonReader читает по одному заказу в JSON на строку. Пустые строки пропускаются.
+type ndjsonReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func newNDJSONReader(r io.Reader) interfaces.OrderRecordReader {
+	return &ndjsonReader{reader: bufio.NewReaderSize(r, 64*1024)}
+}
+
+func (r *ndjsonReader) Next() (*dto.ImportRecord, error) {
+	for {
+		data, err := r.reader.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+		return &dto.ImportRecord{Line: r.line, Raw: data, Payload: data}, nil
+	}
+}
+
+// jsonArrayReader читает элементы JSON-массива заказов по одному, не загружая
+// массив целиком. Номер записи - номер элемента массива, начиная с 1.
+type jsonArrayReader struct {
+	decoder *json.Decoder
+	started bool
+	index   int
+}
+
+func newJSONArrayReader(r io.Reader) interfaces.OrderRecordReader {
+	return &jsonArrayReader{decoder: json.NewDecoder(r)}
+}
+
+func (r *jsonArrayReader) Next() (*dto.ImportRecord, error) {
+	if !r.started {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON array: %w", err)
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("expected JSON array of orders")
+		}
+		r.started = true
+	}
+
+	if !r.decoder.More() {
+		return nil, io.EOF
+	}
+
+	// Синтаксическая ошибка внутри массива не позволяет найти начало
+	// следующего элемента, поэтому она прерывает чтение
+	var element json.RawMessage
+	if err := r.decoder.Decode(&element); err != nil {
+		return nil, fmt.Errorf("failed to read array element %d: %w", r.index+1, err)
+	}
+	r.index++
+
+	return &dto.ImportRecord{Line: r.index, Raw: element, Payload: element}, nil
+}
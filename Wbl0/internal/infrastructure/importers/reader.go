@@ -0,0 +1,50 @@
+package importers
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// Форматы файлов импорта
+const (
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+)
+
+// Formats - поддерживаемые форматы импорта
+var Formats = []string{FormatNDJSON, FormatJSON, FormatCSV}
+
+// NewOrderReader создает чтение записей заказов из r в указанном формате:
+// ndjson - заказ на строку, json - массив заказов, csv - формат CSV-выгрузки
+func NewOrderReader(format string, r io.Reader) (interfaces.OrderRecordReader, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	case FormatJSON:
+		return newJSONArrayReader(r), nil
+	case FormatCSV:
+		return newCSVReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// DetectFormat определяет формат по расширению файла. Возвращает пустую строку,
+// если расширение незнакомо.
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	default:
+		return ""
+	}
+}
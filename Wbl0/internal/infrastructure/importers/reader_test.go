@@ -0,0 +1,214 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+	"WbServis/Wbl0/internal/infrastructure/exporters"
+	"WbServis/Wbl0/internal/infrastructure/generators"
+)
+
+// readAll читает все записи до io.EOF
+func readAll(t *testing.T, reader interfaces.OrderRecordReader) []*dto.ImportRecord {
+	t.Helper()
+
+	var records []*dto.ImportRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func newReader(t *testing.T, format, input string) interfaces.OrderRecordReader {
+	t.Helper()
+
+	reader, err := NewOrderReader(format, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	gen := generators.NewOrderGenerator(1)
+	var orders []*entities.Order
+	for range 20 {
+		orders = append(orders, gen.Order())
+	}
+	// Заказ без товаров выгружается одной строкой и читается с пустым списком
+	withoutItems := gen.Order()
+	withoutItems.Items = []entities.Item{}
+	orders = append(orders, withoutItems)
+
+	var buf bytes.Buffer
+	writer, err := exporters.NewOrderWriter(exporters.FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, order := range orders {
+		if err := writer.Write(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readAll(t, newReader(t, FormatCSV, buf.String()))
+	if len(records) != len(orders) {
+		t.Fatalf("read %d records, want %d", len(records), len(orders))
+	}
+
+	// Строки одного заказа собираются в одну запись; номер записи - строка
+	// первого товара, после заголовка
+	line := 2
+	for i, record := range records {
+		if record.Err != nil {
+			t.Fatalf("record %d: %v", i, record.Err)
+		}
+		want, _ := json.Marshal(orders[i])
+		if !bytes.Equal(record.Payload, want) {
+			t.Errorf("record %d = %s, want %s", i, record.Payload, want)
+		}
+		if record.Line != line {
+			t.Errorf("record %d line = %d, want %d", i, record.Line, line)
+		}
+		line += max(1, len(orders[i].Items))
+	}
+}
+
+func TestCSVReaderRejectsInvalidRows(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := exporters.NewOrderWriter(exporters.FormatCSV, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := generators.NewOrderGenerator(1)
+	valid, broken := gen.Order(), gen.Order()
+	writer.Write(broken)
+	writer.Write(valid)
+	writer.Close()
+
+	// Ломаем sm_id в строке первого заказа: запись отклоняется, чтение продолжается
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows[1:] {
+		if row[columnIndex(t, "order_uid")] == broken.OrderUID {
+			row[columnIndex(t, "sm_id")] = "many"
+		}
+	}
+	var input bytes.Buffer
+	csv.NewWriter(&input).WriteAll(rows)
+
+	records := readAll(t, newReader(t, FormatCSV, input.String()))
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	if records[0].Err == nil || !strings.Contains(records[0].Err.Error(), "sm_id") {
+		t.Errorf("broken record error = %v, want invalid sm_id", records[0].Err)
+	}
+	if len(records[0].Raw) == 0 {
+		t.Error("broken record has no raw text for the rejects file")
+	}
+	if records[1].Err != nil {
+		t.Errorf("valid record error = %v", records[1].Err)
+	}
+
+	if _, err := NewOrderReader(FormatCSV, strings.NewReader("order_uid,track_number\n")); err == nil {
+		t.Error("CSV without export columns accepted")
+	}
+}
+
+func columnIndex(t *testing.T, name string) int {
+	t.Helper()
+
+	for i, column := range exporters.CSVHeader {
+		if column == name {
+			return i
+		}
+	}
+	t.Fatalf("no column %s", name)
+	return 0
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"order_uid":"a"}
+
+  {"order_uid":"b"}
+not json
+{"order_uid":"c"}`
+
+	records := readAll(t, newReader(t, FormatNDJSON, input))
+
+	// Пустые строки пропускаются, но учитываются в номерах строк; проверка
+	// JSON - дело импорта, поэтому невалидная строка возвращается как есть
+	want := []struct {
+		line    int
+		payload string
+	}{
+		{1, `{"order_uid":"a"}`},
+		{3, `{"order_uid":"b"}`},
+		{4, `not json`},
+		{5, `{"order_uid":"c"}`},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("read %d records, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		if records[i].Line != w.line || string(records[i].Payload) != w.payload {
+			t.Errorf("record %d = line %d %q, want line %d %q", i, records[i].Line, records[i].Payload, w.line, w.payload)
+		}
+	}
+}
+
+func TestJSONArrayReader(t *testing.T) {
+	records := readAll(t, newReader(t, FormatJSON, `[{"order_uid":"a"}, {"order_uid":"b"}]`))
+	if len(records) != 2 || records[1].Line != 2 || string(records[1].Payload) != `{"order_uid":"b"}` {
+		t.Errorf("records = %+v", records)
+	}
+
+	if _, err := newReader(t, FormatJSON, `{"order_uid":"a"}`).Next(); err == nil {
+		t.Error("JSON object accepted instead of array")
+	}
+
+	// Синтаксическая ошибка прерывает чтение после уже прочитанных элементов
+	reader := newReader(t, FormatJSON, `[{"order_uid":"a"}, {"order_uid": ]`)
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("first element error = %v", err)
+	}
+	if _, err := reader.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() error = %v, want syntax error", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"orders.ndjson":    FormatNDJSON,
+		"orders.JSONL":     FormatNDJSON,
+		"dump/orders.json": FormatJSON,
+		"orders.csv":       FormatCSV,
+		"orders.parquet":   "",
+		"orders":           "",
+	}
+	for path, want := range tests {
+		if got := DetectFormat(path); got != want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// AnalyticsRepository выполняет аналитические запросы к заказам в PostgreSQL
+type AnalyticsRepository struct {
+	db      *sql.DB
+	rollups bool
+}
+
+// NewAnalyticsRepository создает новый экземпляр AnalyticsRepository.
+// Если rollups включен, запросы, которые можно ответить по дневным сводкам,
+// выполняются по ним, а не по исходным таблицам.
+func NewAnalyticsRepository(db *sql.DB, rollups bool) interfaces.AnalyticsRepository {
+	return &AnalyticsRepository{db: db, rollups: rollups}
+}
+
+// analyticsFrom - соединение таблиц, по полям которых фильтруются заказы
+const analyticsFrom = `
+	FROM orders o
+	JOIN payments p ON p.order_uid = o.order_uid
+	JOIN deliveries d ON d.order_uid = o.order_uid`
+
+// breakdownColumns - колонки, по которым разрешена разбивка
+var breakdownColumns = map[dto.BreakdownDimension]string{
+	dto.BreakdownDeliveryService: "o.delivery_service",
+	dto.BreakdownRegion:          "d.region",
+	dto.BreakdownProvider:        "p.provider",
+	dto.BreakdownBank:            "p.bank",
+}
+
+// analyticsWhere строит условие WHERE по фильтру. Параметры нумеруются с $1.
+func analyticsWhere(filter dto.AnalyticsFilter) (string, []interface{}) {
+	conditions := []string{"o.date_created >= $1", "o.date_created < $2"}
+	args := []interface{}{filter.From, filter.To}
+
+	for _, condition := range []struct {
+		column string
+		value  string
+	}{
+		{"p.currency", filter.Currency},
+		{"o.delivery_service", filter.DeliveryService},
+		{"d.region", filter.Region},
+		{"p.provider", filter.Provider},
+		{"p.bank", filter.Bank},
+	} {
+		if condition.value == "" {
+			continue
+		}
+		args = append(args, condition.value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", condition.column, len(args)))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// useRollups сообщает, можно ли ответить на запрос по дневным сводкам:
+// сводки фильтруются только по валюте, а период должен состоять из целых дней (UTC)
+func (r *AnalyticsRepository) useRollups(filter dto.AnalyticsFilter) bool {
+	return r.rollups &&
+		filter.DeliveryService == "" && filter.Region == "" && filter.Provider == "" && filter.Bank == "" &&
+		isMidnight(filter.From) && isMidnight(filter.To)
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Equal(t.Truncate(24 * time.Hour))
+}
+
+// rollupWhere строит условие WHERE для таблиц сводок. Параметры нумеруются с $1.
+func rollupWhere(filter dto.AnalyticsFilter) (string, []interface{}) {
+	where := " WHERE day >= $1::date AND day < $2::date"
+	args := []interface{}{filter.From.Format(time.DateOnly), filter.To.Format(time.DateOnly)}
+
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		where += fmt.Sprintf(" AND currency = $%d", len(args))
+	}
+	return where, args
+}
+
+// Revenue считает выручку по периодам и валютам
+func (r *AnalyticsRepository) Revenue(filter dto.AnalyticsFilter, interval dto.RevenueInterval) ([]dto.RevenuePoint, error) {
+	var (
+		query, where string
+		args         []interface{}
+	)
+	if r.useRollups(filter) {
+		where, args = rollupWhere(filter)
+		args = append(args, string(interval))
+		query = fmt.Sprintf(`
+			SELECT date_trunc($%d, day::timestamp) AS period, currency, SUM(orders), SUM(revenue)
+			FROM rollup_daily_orders%s
+			GROUP BY period, currency
+			ORDER BY period, currency
+		`, len(args), where)
+	} else {
+		where, args = analyticsWhere(filter)
+		args = append(args, string(interval))
+		query = fmt.Sprintf(`
+			SELECT date_trunc($%d, o.date_created) AS period, p.currency, COUNT(*), SUM(p.amount)
+			%s%s
+			GROUP BY period, p.currency
+			ORDER BY period, p.currency
+		`, len(args), analyticsFrom, where)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue: %w", err)
+	}
+	defer rows.Close()
+
+	points := []dto.RevenuePoint{}
+	for rows.Next() {
+		var point dto.RevenuePoint
+		if err := rows.Scan(&point.Period, &point.Currency, &point.Orders, &point.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read revenue: %w", err)
+	}
+
+	return points, nil
+}
+
+// TopBrands строит рейтинг брендов
+func (r *AnalyticsRepository) TopBrands(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) ([]dto.TopEntry, error) {
+	brand := func(entry *dto.TopEntry) interface{} {
+		return &entry.Brand
+	}
+
+	if r.useRollups(filter) {
+		where, args := rollupWhere(filter)
+		args = append(args, limit)
+		return r.queryTop(fmt.Sprintf(`
+			SELECT brand, currency, SUM(quantity) AS quantity, SUM(revenue) AS revenue
+			FROM rollup_daily_brands%s
+			GROUP BY brand, currency
+			ORDER BY %s, brand
+			LIMIT $%d
+		`, where, topOrder(metric), len(args)), args, brand)
+	}
+	return r.top("i.brand", filter, metric, limit, brand)
+}
+
+// TopProducts строит рейтинг товаров по nm_id
+func (r *AnalyticsRepository) TopProducts(filter dto.AnalyticsFilter, metric dto.TopMetric, limit int) ([]dto.TopEntry, error) {
+	return r.top("i.nm_id", filter, metric, limit, func(entry *dto.TopEntry) interface{} {
+		return &entry.NmID
+	})
+}
+
+// top строит рейтинг товаров, сгруппированных по column и валюте заказа.
+// Количество - число позиций items, выручка - сумма их total_price.
+func (r *AnalyticsRepository) top(column string, filter dto.AnalyticsFilter, metric dto.TopMetric, limit int,
+	key func(*dto.TopEntry) interface{}) ([]dto.TopEntry, error) {
+	where, args := analyticsWhere(filter)
+	args = append(args, limit)
+
+	return r.queryTop(fmt.Sprintf(`
+		SELECT %s, p.currency, COUNT(*) AS quantity, SUM(i.total_price) AS revenue
+		%s
+		JOIN items i ON i.order_uid = o.order_uid%s
+		GROUP BY %s, p.currency
+		ORDER BY %s, %s
+		LIMIT $%d
+	`, column, analyticsFrom, where, column, topOrder(metric), column, len(args)), args, key)
+}
+
+// topOrder возвращает сортировку рейтинга по показателю
+func topOrder(metric dto.TopMetric) string {
+	if metric == dto.TopByRevenue {
+		return "revenue DESC, quantity DESC"
+	}
+	return "quantity DESC, revenue DESC"
+}
+
+// queryTop выполняет запрос рейтинга. key возвращает поле позиции для ключа рейтинга.
+func (r *AnalyticsRepository) queryTop(query string, args []interface{}, key func(*dto.TopEntry) interface{}) ([]dto.TopEntry, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []dto.TopEntry{}
+	for rows.Next() {
+		var entry dto.TopEntry
+		if err := rows.Scan(key(&entry), &entry.Currency, &entry.Quantity, &entry.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan top entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Basket считает средний размер корзины по валютам
+func (r *AnalyticsRepository) Basket(filter dto.AnalyticsFilter) ([]dto.BasketStats, error) {
+	var (
+		query, where string
+		args         []interface{}
+	)
+	if r.useRollups(filter) {
+		where, args = rollupWhere(filter)
+		query = fmt.Sprintf(`
+			SELECT currency, SUM(orders),
+				   SUM(items)::float8 / SUM(orders),
+				   SUM(revenue)::float8 / SUM(orders),
+				   SUM(goods_total)::float8 / SUM(orders)
+			FROM rollup_daily_orders%s
+			GROUP BY currency
+			ORDER BY currency
+		`, where)
+	} else {
+		where, args = analyticsWhere(filter)
+		query = fmt.Sprintf(`
+			SELECT p.currency, COUNT(*),
+				   AVG((SELECT COUNT(*) FROM items i WHERE i.order_uid = o.order_uid)),
+				   AVG(p.amount), AVG(p.goods_total)
+			%s%s
+			GROUP BY p.currency
+			ORDER BY p.currency
+		`, analyticsFrom, where)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query basket stats: %w", err)
+	}
+	defer rows.Close()
+
+	baskets := []dto.BasketStats{}
+	for rows.Next() {
+		var basket dto.BasketStats
+		if err := rows.Scan(&basket.Currency, &basket.Orders, &basket.AvgItems,
+			&basket.AvgAmount, &basket.AvgGoodsTotal); err != nil {
+			return nil, fmt.Errorf("failed to scan basket stats: %w", err)
+		}
+		baskets = append(baskets, basket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read basket stats: %w", err)
+	}
+
+	return baskets, nil
+}
+
+// Breakdown считает число заказов и выручку по значениям поля
+func (r *AnalyticsRepository) Breakdown(filter dto.AnalyticsFilter, dimension dto.BreakdownDimension) ([]dto.BreakdownEntry, error) {
+	column, ok := breakdownColumns[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown breakdown dimension %s", dimension)
+	}
+
+	var (
+		query, where string
+		args         []interface{}
+	)
+	if dimension == dto.BreakdownDeliveryService && r.useRollups(filter) {
+		where, args = rollupWhere(filter)
+		query = fmt.Sprintf(`
+			SELECT delivery_service, currency, SUM(orders) AS orders, SUM(revenue)
+			FROM rollup_daily_delivery_services%s
+			GROUP BY delivery_service, currency
+			ORDER BY orders DESC, delivery_service, currency
+		`, where)
+	} else {
+		where, args = analyticsWhere(filter)
+		query = fmt.Sprintf(`
+			SELECT %s, p.currency, COUNT(*) AS orders, SUM(p.amount)
+			%s%s
+			GROUP BY %s, p.currency
+			ORDER BY orders DESC, %s, p.currency
+		`, column, analyticsFrom, where, column, column)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query breakdown by %s: %w", dimension, err)
+	}
+	defer rows.Close()
+
+	entries := []dto.BreakdownEntry{}
+	for rows.Next() {
+		var entry dto.BreakdownEntry
+		if err := rows.Scan(&entry.Value, &entry.Currency, &entry.Orders, &entry.Revenue); err != nil {
+			return nil, fmt.Errorf("failed to scan breakdown entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breakdown: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Discount считает статистику скидок по товарам заказов
+func (r *AnalyticsRepository) Discount(filter dto.AnalyticsFilter) (*dto.DiscountStats, error) {
+	where, args := analyticsWhere(filter)
+
+	var stats dto.DiscountStats
+	err := r.db.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*),
+			   COUNT(*) FILTER (WHERE i.sale > 0),
+			   COALESCE(AVG(i.sale), 0),
+			   COALESCE(AVG(i.sale) FILTER (WHERE i.sale > 0), 0),
+			   COALESCE(MAX(i.sale), 0)
+		%s
+		JOIN items i ON i.order_uid = o.order_uid%s
+	`, analyticsFrom, where), args...).Scan(&stats.Items, &stats.DiscountedItems,
+		&stats.AvgSale, &stats.AvgSaleDiscount, &stats.MaxSale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query discount stats: %w", err)
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// DataKeyRepository реализует хранение ключей данных в PostgreSQL
+type DataKeyRepository struct {
+	db *sql.DB
+}
+
+// NewDataKeyRepository создает новый экземпляр DataKeyRepository
+func NewDataKeyRepository(db *sql.DB) interfaces.DataKeyRepository {
+	return &DataKeyRepository{db: db}
+}
+
+// GetAll получает все ключи по порядку ID
+func (r *DataKeyRepository) GetAll() ([]dto.DataKey, error) {
+	rows, err := r.db.Query(`
+		SELECT id, purpose, wrapped_key, master_key_id, created_at FROM data_keys ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []dto.DataKey
+	for rows.Next() {
+		var key dto.DataKey
+		if err := rows.Scan(&key.ID, &key.Purpose, &key.WrappedKey, &key.MasterKeyID, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read data keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// GetByID получает ключ по ID
+func (r *DataKeyRepository) GetByID(id int) (*dto.DataKey, error) {
+	var key dto.DataKey
+	err := r.db.QueryRow(`
+		SELECT id, purpose, wrapped_key, master_key_id, created_at FROM data_keys WHERE id = $1
+	`, id).Scan(&key.ID, &key.Purpose, &key.WrappedKey, &key.MasterKeyID, &key.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data key %d: %w", id, err)
+	}
+	return &key, nil
+}
+
+// Create сохраняет ключ. Уникальный индекс по ключу слепого индекса не дает
+// двум экземплярам, запущенным одновременно, создать разные ключи.
+func (r *DataKeyRepository) Create(key dto.DataKey) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+		INSERT INTO data_keys (purpose, wrapped_key, master_key_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, key.Purpose, key.WrappedKey, key.MasterKeyID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to create data key: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateWrapped заменяет зашифрованные ключи одной транзакцией
+func (r *DataKeyRepository) UpdateWrapped(keys []dto.DataKey) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		_, err := tx.Exec(`
+			UPDATE data_keys SET wrapped_key = $2, master_key_id = $3 WHERE id = $1
+		`, key.ID, key.WrappedKey, key.MasterKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to update data key %d: %w", key.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// sealedDelivery - значения шифруемых столбцов deliveries для записи в БД
+type sealedDelivery struct {
+	name, phone, address, email string
+	// keyID, phoneHash и emailHash - NULL для строк без шифрования
+	keyID, phoneHash, emailHash interface{}
+}
+
+// sealDelivery шифрует имя, телефон, адрес и email получателя активным ключом.
+// Без c (шифрование не настроено) значения пишутся открытым текстом.
+func sealDelivery(c interfaces.PIICipher, orderUID string, delivery *entities.Delivery) (sealedDelivery, error) {
+	if c == nil {
+		return sealedDelivery{
+			name: delivery.Name, phone: delivery.Phone, address: delivery.Address, email: delivery.Email,
+		}, nil
+	}
+
+	keyID := c.ActiveKeyID()
+	sealed := sealedDelivery{
+		keyID:     keyID,
+		phoneHash: nullIfEmpty(c.BlindIndex(string(dto.LookupPhone), delivery.Phone)),
+		emailHash: nullIfEmpty(c.BlindIndex(string(dto.LookupEmail), delivery.Email)),
+	}
+
+	fields := []struct {
+		name  string
+		value string
+		dst   *string
+	}{
+		{"name", delivery.Name, &sealed.name},
+		{"phone", delivery.Phone, &sealed.phone},
+		{"address", delivery.Address, &sealed.address},
+		{"email", delivery.Email, &sealed.email},
+	}
+	for _, field := range fields {
+		ciphertext, err := c.Encrypt(keyID, field.value, deliveryAAD(orderUID, field.name))
+		if err != nil {
+			return sealedDelivery{}, fmt.Errorf("failed to encrypt delivery %s of order %s: %w", field.name, orderUID, err)
+		}
+		*field.dst = ciphertext
+	}
+
+	return sealed, nil
+}
+
+// openDelivery расшифровывает поля доставки, прочитанные из строки с ключом keyID.
+// Строки с NULL в key_id сохранены до включения шифрования и читаются как есть.
+func openDelivery(c interfaces.PIICipher, orderUID string, keyID sql.NullInt64, delivery *entities.Delivery) error {
+	if !keyID.Valid {
+		return nil
+	}
+	if c == nil {
+		return fmt.Errorf("delivery of order %s is encrypted, but no master key is configured", orderUID)
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"name", &delivery.Name},
+		{"phone", &delivery.Phone},
+		{"address", &delivery.Address},
+		{"email", &delivery.Email},
+	}
+	for _, field := range fields {
+		plaintext, err := c.Decrypt(int(keyID.Int64), *field.value, deliveryAAD(orderUID, field.name))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt delivery %s of order %s: %w", field.name, orderUID, err)
+		}
+		*field.value = plaintext
+	}
+
+	return nil
+}
+
+// deliveryAAD привязывает шифртекст к заказу и столбцу
+func deliveryAAD(orderUID, field string) string {
+	return "deliveries/" + orderUID + "/" + field
+}
+
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// DeliveryEncryptor перешифровывает сохраненные данные получателя в PostgreSQL
+type DeliveryEncryptor struct {
+	db     *sql.DB
+	cipher interfaces.PIICipher
+}
+
+// NewDeliveryEncryptor создает новый экземпляр DeliveryEncryptor
+func NewDeliveryEncryptor(db *sql.DB, cipher interfaces.PIICipher) interfaces.DeliveryEncryptor {
+	return &DeliveryEncryptor{db: db, cipher: cipher}
+}
+
+// EncryptPending перешифровывает строки пачками в отдельных транзакциях. Строки
+// пачки блокируются (занятые сохранением пропускаются), поэтому перешифрование
+// можно запускать при работающем сервисе. updated_at не меняется: данные те же.
+func (e *DeliveryEncryptor) EncryptPending(batchSize int) (int, error) {
+	total := 0
+	for {
+		count, err := e.encryptBatch(batchSize)
+		total += count
+		if err != nil {
+			return total, err
+		}
+		if count == 0 {
+			return total, nil
+		}
+	}
+}
+
+func (e *DeliveryEncryptor) CountPlaintext() (int, error) {
+	var count int
+	if err := e.db.QueryRow(`SELECT COUNT(*) FROM deliveries WHERE key_id IS NULL`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count plaintext deliveries: %w", err)
+	}
+	return count, nil
+}
+
+func (e *DeliveryEncryptor) encryptBatch(batchSize int) (int, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT order_uid, key_id, name, phone, address, email
+		FROM deliveries
+		WHERE key_id IS DISTINCT FROM $1
+		ORDER BY order_uid
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, e.cipher.ActiveKeyID(), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deliveries to encrypt: %w", err)
+	}
+
+	type pendingDelivery struct {
+		orderUID string
+		delivery entities.Delivery
+	}
+	var pending []pendingDelivery
+	for rows.Next() {
+		var (
+			p     pendingDelivery
+			keyID sql.NullInt64
+		)
+		if err := rows.Scan(&p.orderUID, &keyID, &p.delivery.Name, &p.delivery.Phone,
+			&p.delivery.Address, &p.delivery.Email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if err := openDelivery(e.cipher, p.orderUID, keyID, &p.delivery); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read deliveries: %w", err)
+	}
+
+	for _, p := range pending {
+		sealed, err := sealDelivery(e.cipher, p.orderUID, &p.delivery)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`
+			UPDATE deliveries
+			SET name = $2, phone = $3, address = $4, email = $5,
+				key_id = $6, phone_hash = $7, email_hash = $8
+			WHERE order_uid = $1
+		`, p.orderUID, sealed.name, sealed.phone, sealed.address, sealed.email,
+			sealed.keyID, sealed.phoneHash, sealed.emailHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update delivery of order %s: %w", p.orderUID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit encrypted deliveries: %w", err)
+	}
+	return len(pending), nil
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/lib/pq"
+)
+
+// OrderChangesChannel - канал LISTEN/NOTIFY, в который SaveBatch сообщает об измененных заказах
+const OrderChangesChannel = "order_changes"
+
+// orderChangeSeparator отделяет идентификатор экземпляра от order_uid в уведомлении
+const orderChangeSeparator = "|"
+
+// OrderChangeListener получает уведомления PostgreSQL об изменении заказов
+// другими экземплярами сервиса и вытесняет эти заказы из кэша
+type OrderChangeListener struct {
+	listener   *pq.Listener
+	instanceID string
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewOrderChangeListener подключается к PostgreSQL отдельным соединением
+// и подписывается на OrderChangesChannel
+func NewOrderChangeListener(dbURL, instanceID string) (interfaces.OrderChangeListener, error) {
+	l := &OrderChangeListener{
+		instanceID: instanceID,
+		done:       make(chan struct{}),
+	}
+
+	l.listener = pq.NewListener(dbURL, time.Second, time.Minute, l.onEvent)
+	if err := l.listener.Listen(OrderChangesChannel); err != nil {
+		l.listener.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Listen начинает передавать изменения в cache
+func (l *OrderChangeListener) Listen(cache interfaces.OrderCacheInvalidator) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		for {
+			select {
+			case notification := <-l.listener.Notify:
+				// nil приходит после переподключения - уведомления за время разрыва
+				// потеряны, поэтому кэш сбрасывается целиком
+				if notification == nil {
+					log.Println("Order change listener reconnected, evicting whole cache")
+					cache.EvictAll()
+					continue
+				}
+
+				instanceID, orderUID, ok := strings.Cut(notification.Extra, orderChangeSeparator)
+				if !ok || instanceID == l.instanceID {
+					continue
+				}
+				cache.EvictOrders([]string{orderUID})
+
+			case <-time.After(90 * time.Second):
+				// Проверяем соединение, если уведомлений давно не было
+				go l.listener.Ping()
+
+			case <-l.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close останавливает прослушивание и закрывает соединение
+func (l *OrderChangeListener) Close() error {
+	close(l.done)
+	l.wg.Wait()
+	return l.listener.Close()
+}
+
+func (l *OrderChangeListener) onEvent(event pq.ListenerEventType, err error) {
+	if err != nil {
+		log.Printf("Order change listener error: %v", err)
+	}
+}
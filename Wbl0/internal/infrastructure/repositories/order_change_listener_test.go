@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/domain/entities"
+
+	"github.com/lib/pq"
+)
+
+// recordingInvalidator запоминает вытесненные заказы и сбросы кэша
+type recordingInvalidator struct {
+	mu       sync.Mutex
+	evicted  []string
+	evictAll int
+	changed  chan struct{}
+}
+
+func newRecordingInvalidator() *recordingInvalidator {
+	return &recordingInvalidator{changed: make(chan struct{}, 100)}
+}
+
+func (c *recordingInvalidator) EvictOrders(orderUIDs []string) {
+	c.mu.Lock()
+	c.evicted = append(c.evicted, orderUIDs...)
+	c.mu.Unlock()
+	c.changed <- struct{}{}
+}
+
+func (c *recordingInvalidator) EvictAll() {
+	c.mu.Lock()
+	c.evictAll++
+	c.mu.Unlock()
+	c.changed <- struct{}{}
+}
+
+func (c *recordingInvalidator) wait(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-c.changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cache was not invalidated")
+	}
+}
+
+func (c *recordingInvalidator) state() ([]string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.evicted), c.evictAll
+}
+
+// newTestListener возвращает слушателя, уведомления которому передаются через
+// notify вместо соединения с PostgreSQL
+func newTestListener(t *testing.T, instanceID string) (*OrderChangeListener, chan *pq.Notification) {
+	t.Helper()
+
+	notify := make(chan *pq.Notification)
+	l := &OrderChangeListener{
+		listener:   &pq.Listener{Notify: notify},
+		instanceID: instanceID,
+		done:       make(chan struct{}),
+	}
+	// Close закрыл бы соединение, которого нет: останавливаем только цикл
+	t.Cleanup(func() {
+		close(l.done)
+		l.wg.Wait()
+	})
+	return l, notify
+}
+
+func TestOrderChangeListenerEvictsOtherInstanceOrders(t *testing.T) {
+	l, notify := newTestListener(t, "instance-b")
+	cache := newRecordingInvalidator()
+	l.Listen(cache)
+
+	// Свои уведомления и уведомления без идентификатора экземпляра пропускаются:
+	// канал небуферизован, поэтому к отправке следующего предыдущее уже разобрано
+	notify <- &pq.Notification{Channel: OrderChangesChannel, Extra: "instance-b|order-own"}
+	notify <- &pq.Notification{Channel: OrderChangesChannel, Extra: "order-malformed"}
+	notify <- &pq.Notification{Channel: OrderChangesChannel, Extra: "instance-a|order-1"}
+	cache.wait(t)
+
+	evicted, evictAll := cache.state()
+	if !slices.Equal(evicted, []string{"order-1"}) || evictAll != 0 {
+		t.Errorf("evicted %v, evict all %d times; want only order-1", evicted, evictAll)
+	}
+}
+
+func TestOrderChangeListenerEvictsAllAfterReconnect(t *testing.T) {
+	l, notify := newTestListener(t, "instance-b")
+	cache := newRecordingInvalidator()
+	l.Listen(cache)
+
+	// nil - соединение восстановлено, уведомления за время разрыва потеряны
+	notify <- nil
+	cache.wait(t)
+
+	evicted, evictAll := cache.state()
+	if evictAll != 1 || len(evicted) != 0 {
+		t.Errorf("evicted %v, evict all %d times; want whole cache evicted once", evicted, evictAll)
+	}
+}
+
+func TestOrderChangeListenerWithDatabase(t *testing.T) {
+	db := newTestDB(t)
+	l, err := NewOrderChangeListener(os.Getenv("TEST_DATABASE_URL"), "instance-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	cache := newRecordingInvalidator()
+	l.Listen(cache)
+
+	rng := rand.New(rand.NewSource(1))
+	// Заказ, сохраненный этим же экземпляром, уже обновлен в его кэше
+	own := NewOrderRepository(db, "instance-b", nil)
+	if err := own.SaveBatch([]*entities.Order{randomOrder(rng, "order-own")}); err != nil {
+		t.Fatal(err)
+	}
+	other := NewOrderRepository(db, "instance-a", nil)
+	if err := other.SaveBatch([]*entities.Order{randomOrder(rng, "order-1")}); err != nil {
+		t.Fatal(err)
+	}
+	cache.wait(t)
+
+	// Уведомления приходят в порядке коммитов, так что свое уже было бы обработано
+	evicted, _ := cache.state()
+	if !slices.Equal(evicted, []string{"order-1"}) {
+		t.Errorf("evicted %v, want only order-1", evicted)
+	}
+}
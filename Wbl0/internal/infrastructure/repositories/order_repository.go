@@ -4,35 +4,95 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"WbServis/Wbl0/internal/application/dto"
 	"WbServis/Wbl0/internal/application/interfaces"
 	"WbServis/Wbl0/internal/domain/entities"
+
+	"github.com/lib/pq"
 )
 
 // OrderRepository реализует интерфейс для работы с заказами в PostgreSQL
 type OrderRepository struct {
-	db *sql.DB
+	db         *sql.DB
+	instanceID string
+	cipher     interfaces.PIICipher
 }
 
-// NewOrderRepository создает новый экземпляр OrderRepository
-func NewOrderRepository(db *sql.DB) interfaces.OrderRepository {
-	return &OrderRepository{db: db}
+// NewOrderRepository создает новый экземпляр OrderRepository.
+// instanceID помечает уведомления об изменении заказов, чтобы экземпляр
+// сервиса мог пропускать собственные изменения. cipher шифрует имя, телефон,
+// адрес и email получателя; nil - данные хранятся открытым текстом.
+func NewOrderRepository(db *sql.DB, instanceID string, cipher interfaces.PIICipher) interfaces.OrderRepository {
+	return &OrderRepository{db: db, instanceID: instanceID, cipher: cipher}
 }
 
+// maxQueryParams - предельное число параметров в одном запросе PostgreSQL
+const maxQueryParams = 65535
+
+// loadBatchSize - количество заказов, загружаемых одним набором запросов
+const loadBatchSize = 1000
+
 // Save сохраняет заказ в базу данных
 func (r *OrderRepository) Save(order *entities.Order) error {
+	return r.SaveBatch([]*entities.Order{order})
+}
+
+// SaveBatch сохраняет пачку заказов в одной транзакции многострочными вставками
+func (r *OrderRepository) SaveBatch(orders []*entities.Order) error {
+	orders = uniqueOrders(orders)
+	if len(orders) == 0 {
+		return nil
+	}
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Сохраняем основной заказ
-	_, err = tx.Exec(`
+	orderRows := make([][]interface{}, 0, len(orders))
+	deliveryRows := make([][]interface{}, 0, len(orders))
+	paymentRows := make([][]interface{}, 0, len(orders))
+	var itemRows [][]interface{}
+	orderUIDs := make([]string, 0, len(orders))
+
+	for _, order := range orders {
+		orderUIDs = append(orderUIDs, order.OrderUID)
+		orderRows = append(orderRows, []interface{}{
+			order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard,
+		})
+		delivery, err := sealDelivery(r.cipher, order.OrderUID, &order.Delivery)
+		if err != nil {
+			return err
+		}
+		deliveryRows = append(deliveryRows, []interface{}{
+			order.OrderUID, delivery.name, delivery.phone, order.Delivery.Zip,
+			order.Delivery.City, delivery.address, order.Delivery.Region, delivery.email,
+			delivery.keyID, delivery.phoneHash, delivery.emailHash,
+		})
+		paymentRows = append(paymentRows, []interface{}{
+			order.OrderUID, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
+			order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank,
+			order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee,
+		})
+		for _, item := range order.Items {
+			itemRows = append(itemRows, []interface{}{
+				order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+				item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status,
+			})
+		}
+	}
+
+	// Сохраняем основные заказы
+	err = execMultiInsert(tx, `
 		INSERT INTO orders (
 			order_uid, track_number, entry, locale, internal_signature,
 			customer_id, delivery_service, shard_key, sm_id, date_created, oof_shard
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		)`, `
 		ON CONFLICT (order_uid) DO UPDATE SET
 			track_number = EXCLUDED.track_number,
 			entry = EXCLUDED.entry,
@@ -45,18 +105,17 @@ func (r *OrderRepository) Save(order *entities.Order) error {
 			date_created = EXCLUDED.date_created,
 			oof_shard = EXCLUDED.oof_shard,
 			updated_at = CURRENT_TIMESTAMP
-	`,
-		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
-		order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard)
+	`, orderRows)
 	if err != nil {
-		return fmt.Errorf("failed to insert order: %w", err)
+		return fmt.Errorf("failed to insert orders: %w", err)
 	}
 
 	// Сохраняем информацию о доставке
-	_, err = tx.Exec(`
+	err = execMultiInsert(tx, `
 		INSERT INTO deliveries (
-			order_uid, name, phone, zip, city, address, region, email
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			order_uid, name, phone, zip, city, address, region, email,
+			key_id, phone_hash, email_hash
+		)`, `
 		ON CONFLICT (order_uid) DO UPDATE SET
 			name = EXCLUDED.name,
 			phone = EXCLUDED.phone,
@@ -65,20 +124,21 @@ func (r *OrderRepository) Save(order *entities.Order) error {
 			address = EXCLUDED.address,
 			region = EXCLUDED.region,
 			email = EXCLUDED.email,
+			key_id = EXCLUDED.key_id,
+			phone_hash = EXCLUDED.phone_hash,
+			email_hash = EXCLUDED.email_hash,
 			updated_at = CURRENT_TIMESTAMP
-	`,
-		order.OrderUID, order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip,
-		order.Delivery.City, order.Delivery.Address, order.Delivery.Region, order.Delivery.Email)
+	`, deliveryRows)
 	if err != nil {
-		return fmt.Errorf("failed to insert delivery: %w", err)
+		return fmt.Errorf("failed to insert deliveries: %w", err)
 	}
 
 	// Сохраняем информацию об оплате
-	_, err = tx.Exec(`
+	err = execMultiInsert(tx, `
 		INSERT INTO payments (
 			order_uid, transaction, request_id, currency, provider,
 			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		)`, `
 		ON CONFLICT (order_uid) DO UPDATE SET
 			transaction = EXCLUDED.transaction,
 			request_id = EXCLUDED.request_id,
@@ -91,38 +151,97 @@ func (r *OrderRepository) Save(order *entities.Order) error {
 			goods_total = EXCLUDED.goods_total,
 			custom_fee = EXCLUDED.custom_fee,
 			updated_at = CURRENT_TIMESTAMP
-	`,
-		order.OrderUID, order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency,
-		order.Payment.Provider, order.Payment.Amount, order.Payment.PaymentDt, order.Payment.Bank,
-		order.Payment.DeliveryCost, order.Payment.GoodsTotal, order.Payment.CustomFee)
+	`, paymentRows)
 	if err != nil {
-		return fmt.Errorf("failed to insert payment: %w", err)
+		return fmt.Errorf("failed to insert payments: %w", err)
 	}
 
 	// Удаляем старые товары и добавляем новые
-	_, err = tx.Exec("DELETE FROM items WHERE order_uid = $1", order.OrderUID)
+	_, err = tx.Exec("DELETE FROM items WHERE order_uid = ANY($1)", pq.Array(orderUIDs))
 	if err != nil {
 		return fmt.Errorf("failed to delete old items: %w", err)
 	}
 
 	// Сохраняем товары
-	for _, item := range order.Items {
-		_, err = tx.Exec(`
-			INSERT INTO items (
-				order_uid, chrt_id, track_number, price, rid, name,
-				sale, size, total_price, nm_id, brand, status
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		`,
-			order.OrderUID, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
-			item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
-		if err != nil {
-			return fmt.Errorf("failed to insert item: %w", err)
-		}
+	err = execMultiInsert(tx, `
+		INSERT INTO items (
+			order_uid, chrt_id, track_number, price, rid, name,
+			sale, size, total_price, nm_id, brand, status
+		)`, "", itemRows)
+	if err != nil {
+		return fmt.Errorf("failed to insert items: %w", err)
+	}
+
+	// Уведомления доставляются слушателям только после коммита транзакции
+	_, err = tx.Exec("SELECT pg_notify($1, $2::text || uid) FROM unnest($3::text[]) AS uid",
+		OrderChangesChannel, r.instanceID+orderChangeSeparator, pq.Array(orderUIDs))
+	if err != nil {
+		return fmt.Errorf("failed to notify order changes: %w", err)
 	}
 
 	return tx.Commit()
 }
 
+// uniqueOrders убирает повторы заказов в пачке, оставляя последнюю версию каждого.
+// Один INSERT ... ON CONFLICT не может обновить одну строку дважды.
+func uniqueOrders(orders []*entities.Order) []*entities.Order {
+	last := make(map[string]int, len(orders))
+	for i, order := range orders {
+		last[order.OrderUID] = i
+	}
+	if len(last) == len(orders) {
+		return orders
+	}
+
+	unique := make([]*entities.Order, 0, len(last))
+	for i, order := range orders {
+		if last[order.OrderUID] == i {
+			unique = append(unique, order)
+		}
+	}
+	return unique
+}
+
+// execMultiInsert выполняет многострочный INSERT, разбивая строки на запросы
+// так, чтобы не превысить лимит параметров PostgreSQL
+func execMultiInsert(tx *sql.Tx, insert, suffix string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	rowsPerQuery := maxQueryParams / len(rows[0])
+	for start := 0; start < len(rows); start += rowsPerQuery {
+		chunk := rows[start:min(start+rowsPerQuery, len(rows))]
+
+		var query strings.Builder
+		args := make([]interface{}, 0, len(chunk)*len(rows[0]))
+
+		query.WriteString(insert)
+		query.WriteString(" VALUES ")
+		for i, row := range chunk {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteByte('(')
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteByte(')')
+		}
+		query.WriteString(suffix)
+
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetByID получает заказ по ID
 func (r *OrderRepository) GetByID(orderUID string) (*entities.Order, error) {
 	// Получаем основной заказ
@@ -142,15 +261,19 @@ func (r *OrderRepository) GetByID(orderUID string) (*entities.Order, error) {
 	}
 
 	// Получаем информацию о доставке
+	var keyID sql.NullInt64
 	err = r.db.QueryRow(`
-		SELECT name, phone, zip, city, address, region, email
+		SELECT name, phone, zip, city, address, region, email, key_id
 		FROM deliveries WHERE order_uid = $1
 	`, orderUID).Scan(
 		&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
-		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email)
+		&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email, &keyID)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to get delivery: %w", err)
 	}
+	if err := openDelivery(r.cipher, orderUID, keyID, &order.Delivery); err != nil {
+		return nil, err
+	}
 
 	// Получаем информацию об оплате
 	err = r.db.QueryRow(`
@@ -219,6 +342,527 @@ func (r *OrderRepository) GetAll() ([]*entities.Order, error) {
 	return orders, nil
 }
 
+// GetUpdatedSince получает заказы, измененные позже since
+func (r *OrderRepository) GetUpdatedSince(since time.Time) ([]*entities.Order, error) {
+	rows, err := r.db.Query(`
+		SELECT order_uid FROM orders WHERE updated_at > $1 ORDER BY updated_at
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated order UIDs: %w", err)
+	}
+
+	var orderUIDs []string
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order UID: %w", err)
+		}
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read order UIDs: %w", err)
+	}
+
+	var orders []*entities.Order
+	for start := 0; start < len(orderUIDs); start += loadBatchSize {
+		chunk, err := r.getByIDs(orderUIDs[start:min(start+loadBatchSize, len(orderUIDs))])
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, chunk...)
+	}
+	return orders, nil
+}
+
+// Stream читает отобранные заказы страницами по loadBatchSize с курсором
+// по (date_created, order_uid): в памяти одновременно не больше одной страницы,
+// а длинная выгрузка не держит открытой транзакцию
+func (r *OrderRepository) Stream(filter dto.ExportFilter, fn func(order *entities.Order) error) error {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if !filter.From.IsZero() {
+		addCondition("date_created >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("date_created < $%d", filter.To)
+	}
+	if filter.CustomerID != "" {
+		addCondition("customer_id = $%d", filter.CustomerID)
+	}
+	if filter.DeliveryService != "" {
+		addCondition("delivery_service = $%d", filter.DeliveryService)
+	}
+
+	var (
+		lastDate time.Time
+		lastUID  string
+	)
+	for page := 0; ; page++ {
+		pageConditions, pageArgs := conditions, args
+		if page > 0 {
+			pageArgs = append(pageArgs[:len(pageArgs):len(pageArgs)], lastDate, lastUID)
+			pageConditions = append(pageConditions[:len(pageConditions):len(pageConditions)],
+				fmt.Sprintf("(date_created, order_uid) > ($%d, $%d)", len(pageArgs)-1, len(pageArgs)))
+		}
+
+		where := ""
+		if len(pageConditions) > 0 {
+			where = "WHERE " + strings.Join(pageConditions, " AND ")
+		}
+
+		rows, err := r.db.Query(fmt.Sprintf(`
+			SELECT order_uid, date_created FROM orders %s
+			ORDER BY date_created, order_uid
+			LIMIT %d
+		`, where, loadBatchSize), pageArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to get order UIDs: %w", err)
+		}
+
+		var orderUIDs []string
+		for rows.Next() {
+			if err := rows.Scan(&lastUID, &lastDate); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan order UID: %w", err)
+			}
+			orderUIDs = append(orderUIDs, lastUID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read order UIDs: %w", err)
+		}
+
+		orders, err := r.getByIDs(orderUIDs)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+
+		if len(orderUIDs) < loadBatchSize {
+			return nil
+		}
+	}
+}
+
+// lookupQueries - запросы order_uid по полям поиска
+var lookupQueries = map[dto.LookupField]string{
+	dto.LookupTrackNumber: `SELECT order_uid FROM orders WHERE track_number = $1`,
+	dto.LookupTransaction: `SELECT order_uid FROM payments WHERE transaction = $1`,
+	dto.LookupRid:         `SELECT DISTINCT order_uid FROM items WHERE rid = $1`,
+	// Зашифрованные строки ищутся по слепому индексу ($2), строки, сохраненные
+	// до включения шифрования, - по значению
+	dto.LookupPhone: `SELECT order_uid FROM deliveries WHERE phone_hash = $2 OR (key_id IS NULL AND phone = $1)`,
+	dto.LookupEmail: `SELECT order_uid FROM deliveries WHERE email_hash = $2 OR (key_id IS NULL AND email = $1)`,
+}
+
+// FindBy получает заказы по трек-номеру, транзакции, RID товара, телефону или email
+func (r *OrderRepository) FindBy(field dto.LookupField, value string) ([]*entities.Order, error) {
+	query, ok := lookupQueries[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown lookup field %s", field)
+	}
+
+	args := []interface{}{value}
+	if field == dto.LookupPhone || field == dto.LookupEmail {
+		args = append(args, r.blindIndex(field, value))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find orders by %s: %w", field, err)
+	}
+
+	var orderUIDs []string
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order UID: %w", err)
+		}
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read order UIDs: %w", err)
+	}
+
+	return r.getByIDs(orderUIDs)
+}
+
+// Search ищет заказы полнотекстово по данным получателя и товаров и нечетко
+// по телефону и email. Ранг полнотекстового совпадения - ts_rank, нечеткого -
+// сходство триграмм; у заказа берется лучший ранг среди совпадений.
+// У зашифрованных строк имя и адрес не ищутся, а телефон и email совпадают
+// только точно, по слепому индексу, с рангом 1. Общее число найденных
+// считается отдельно от страницы, поэтому оно есть и за последней страницей.
+func (r *OrderRepository) Search(query string, limit, offset int) ([]dto.OrderSearchResult, int, error) {
+	rows, err := r.db.Query(`
+		WITH matches AS (
+			SELECT d.order_uid,
+				   ts_rank(to_tsvector('simple', d.name || ' ' || d.address || ' ' || d.city), q) AS rank
+			FROM deliveries d, plainto_tsquery('simple', $1) q
+			WHERE to_tsvector('simple', d.name || ' ' || d.address || ' ' || d.city) @@ q
+			  AND d.key_id IS NULL
+			UNION ALL
+			SELECT d.order_uid, ts_rank(to_tsvector('simple', d.city), q)
+			FROM deliveries d, plainto_tsquery('simple', $1) q
+			WHERE to_tsvector('simple', d.city) @@ q
+			  AND d.key_id IS NOT NULL
+			UNION ALL
+			SELECT i.order_uid,
+				   ts_rank(to_tsvector('simple', i.name || ' ' || i.brand), q)
+			FROM items i, plainto_tsquery('simple', $1) q
+			WHERE to_tsvector('simple', i.name || ' ' || i.brand) @@ q
+			UNION ALL
+			SELECT d.order_uid,
+				   GREATEST(similarity(d.phone, $1), similarity(d.email, $1),
+							CASE WHEN d.phone ILIKE $2 OR d.email ILIKE $2 THEN 0.5 ELSE 0 END)
+			FROM deliveries d
+			WHERE (d.phone % $1 OR d.email % $1 OR d.phone ILIKE $2 OR d.email ILIKE $2)
+			  AND d.key_id IS NULL
+			UNION ALL
+			SELECT d.order_uid, 1
+			FROM deliveries d
+			WHERE d.phone_hash = $5 OR d.email_hash = $6
+		),
+		ranked AS (
+			SELECT order_uid, MAX(rank) AS rank
+			FROM matches
+			GROUP BY order_uid
+		),
+		total AS (
+			SELECT COUNT(*) AS total FROM ranked
+		)
+		SELECT t.total, page.order_uid, page.rank
+		FROM total t
+		LEFT JOIN LATERAL (
+			SELECT order_uid, rank
+			FROM ranked
+			ORDER BY rank DESC, order_uid
+			LIMIT $3 OFFSET $4
+		) page ON true
+	`, query, "%"+escapeLike(query)+"%", limit, offset,
+		r.blindIndex(dto.LookupPhone, query), r.blindIndex(dto.LookupEmail, query))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search orders: %w", err)
+	}
+
+	var (
+		total     int
+		orderUIDs []string
+		ranks     = make(map[string]float64)
+	)
+	for rows.Next() {
+		var (
+			orderUID sql.NullString
+			rank     sql.NullFloat64
+		)
+		if err := rows.Scan(&total, &orderUID, &rank); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		// За последней страницей строка одна: только total без заказа
+		if !orderUID.Valid {
+			continue
+		}
+		orderUIDs = append(orderUIDs, orderUID.String)
+		ranks[orderUID.String] = rank.Float64
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	orders, err := r.getByIDs(orderUIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]dto.OrderSearchResult, 0, len(orders))
+	for _, order := range orders {
+		results = append(results, dto.OrderSearchResult{
+			Rank:  ranks[order.OrderUID],
+			Order: order,
+		})
+	}
+	return results, total, nil
+}
+
+// SearchCoverage сообщает, какие поля Search не охватывает у зашифрованных заказов.
+// nil - шифрование не настроено, и поиск охватывает все поля.
+func (r *OrderRepository) SearchCoverage() *dto.SearchCoverage {
+	if r.cipher == nil {
+		return nil
+	}
+	return &dto.SearchCoverage{
+		NotSearched: []string{"delivery.name", "delivery.address"},
+		ExactOnly:   []string{"delivery.phone", "delivery.email"},
+	}
+}
+
+// blindIndex возвращает слепой индекс значения или NULL, если шифрование
+// не настроено или значение пустое
+func (r *OrderRepository) blindIndex(field dto.LookupField, value string) interface{} {
+	if r.cipher == nil {
+		return nil
+	}
+	return nullIfEmpty(r.cipher.BlindIndex(string(field), value))
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// GetCustomerOrders получает страницу кратких сведений о заказах покупателя одним запросом
+func (r *OrderRepository) GetCustomerOrders(customerID string, limit, offset int) ([]dto.CustomerOrderSummary, error) {
+	rows, err := r.db.Query(`
+		SELECT o.order_uid, o.date_created, p.amount, p.currency, d.city,
+			   (SELECT COUNT(*) FROM items i WHERE i.order_uid = o.order_uid)
+		FROM orders o
+		JOIN payments p ON p.order_uid = o.order_uid
+		JOIN deliveries d ON d.order_uid = o.order_uid
+		WHERE o.customer_id = $1
+		ORDER BY o.date_created DESC, o.order_uid
+		LIMIT $2 OFFSET $3
+	`, customerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []dto.CustomerOrderSummary{}
+	for rows.Next() {
+		var order dto.CustomerOrderSummary
+		if err := rows.Scan(&order.OrderUID, &order.DateCreated, &order.Amount, &order.Currency,
+			&order.DeliveryCity, &order.ItemCount); err != nil {
+			return nil, fmt.Errorf("failed to scan customer order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read customer orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetCustomerStats считает сводку по заказам покупателя с разбивкой сумм по валютам
+func (r *OrderRepository) GetCustomerStats(customerID string) (*dto.CustomerOrderStats, error) {
+	rows, err := r.db.Query(`
+		SELECT p.currency, COUNT(*), SUM(p.amount), MIN(o.date_created), MAX(o.date_created)
+		FROM orders o
+		JOIN payments p ON p.order_uid = o.order_uid
+		WHERE o.customer_id = $1
+		GROUP BY p.currency
+		ORDER BY p.currency
+	`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &dto.CustomerOrderStats{TotalSpent: []dto.CurrencyTotal{}}
+	for rows.Next() {
+		var (
+			total       dto.CurrencyTotal
+			first, last time.Time
+		)
+		if err := rows.Scan(&total.Currency, &total.Orders, &total.Amount, &first, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan customer stats: %w", err)
+		}
+
+		stats.OrderCount += total.Orders
+		stats.TotalSpent = append(stats.TotalSpent, total)
+		if stats.FirstOrder == nil || first.Before(*stats.FirstOrder) {
+			stats.FirstOrder = &first
+		}
+		if stats.LastOrder == nil || last.After(*stats.LastOrder) {
+			stats.LastOrder = &last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read customer stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// LatestUpdate возвращает время последнего изменения заказов
+func (r *OrderRepository) LatestUpdate() (time.Time, error) {
+	var latest time.Time
+	err := r.db.QueryRow(`
+		SELECT COALESCE(MAX(updated_at), 'epoch'::timestamp) FROM orders
+	`).Scan(&latest)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get latest order update: %w", err)
+	}
+	return latest, nil
+}
+
+// OldestOpenTransaction возвращает время начала самой старой транзакции в базе.
+// updated_at хранится без часового пояса, поэтому время приводится так же.
+// Транзакции других ролей видны только с правами pg_read_all_stats.
+func (r *OrderRepository) OldestOpenTransaction() (time.Time, error) {
+	var oldest time.Time
+	err := r.db.QueryRow(`
+		SELECT COALESCE(MIN(xact_start), now())::timestamp
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND xact_start IS NOT NULL
+	`).Scan(&oldest)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get oldest open transaction: %w", err)
+	}
+	return oldest, nil
+}
+
+// getByIDs загружает заказы по списку UID четырьмя запросами вместо четырех на заказ
+func (r *OrderRepository) getByIDs(orderUIDs []string) ([]*entities.Order, error) {
+	if len(orderUIDs) == 0 {
+		return nil, nil
+	}
+
+	orders := make([]*entities.Order, 0, len(orderUIDs))
+	byUID := make(map[string]*entities.Order, len(orderUIDs))
+
+	rows, err := r.db.Query(`
+		SELECT order_uid, track_number, entry, locale, internal_signature,
+			   customer_id, delivery_service, shard_key, sm_id, date_created, oof_shard
+		FROM orders WHERE order_uid = ANY($1)
+	`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	for rows.Next() {
+		var order entities.Order
+		err := rows.Scan(
+			&order.OrderUID, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+			&order.CustomerID, &order.DeliveryService, &order.ShardKey, &order.SmID, &order.DateCreated, &order.OofShard)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		byUID[order.OrderUID] = &order
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+
+	// Получаем информацию о доставке
+	rows, err = r.db.Query(`
+		SELECT order_uid, name, phone, zip, city, address, region, email, key_id
+		FROM deliveries WHERE order_uid = ANY($1)
+	`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	for rows.Next() {
+		var (
+			orderUID string
+			delivery entities.Delivery
+			keyID    sql.NullInt64
+		)
+		err := rows.Scan(&orderUID, &delivery.Name, &delivery.Phone, &delivery.Zip,
+			&delivery.City, &delivery.Address, &delivery.Region, &delivery.Email, &keyID)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if err := openDelivery(r.cipher, orderUID, keyID, &delivery); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if order, ok := byUID[orderUID]; ok {
+			order.Delivery = delivery
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read deliveries: %w", err)
+	}
+
+	// Получаем информацию об оплате
+	rows, err = r.db.Query(`
+		SELECT order_uid, transaction, request_id, currency, provider, amount,
+			   payment_dt, bank, delivery_cost, goods_total, custom_fee
+		FROM payments WHERE order_uid = ANY($1)
+	`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	for rows.Next() {
+		var (
+			orderUID string
+			payment  entities.Payment
+		)
+		err := rows.Scan(&orderUID, &payment.Transaction, &payment.RequestID, &payment.Currency,
+			&payment.Provider, &payment.Amount, &payment.PaymentDt,
+			&payment.Bank, &payment.DeliveryCost, &payment.GoodsTotal, &payment.CustomFee)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		if order, ok := byUID[orderUID]; ok {
+			order.Payment = payment
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read payments: %w", err)
+	}
+
+	// Получаем товары
+	rows, err = r.db.Query(`
+		SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+		FROM items WHERE order_uid = ANY($1) ORDER BY id
+	`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+	for rows.Next() {
+		var (
+			orderUID string
+			item     entities.Item
+		)
+		err := rows.Scan(&orderUID,
+			&item.ChrtID, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
+			&item.Sale, &item.Size, &item.TotalPrice, &item.NmID, &item.Brand, &item.Status)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if order, ok := byUID[orderUID]; ok {
+			order.Items = append(order.Items, item)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	// Сохраняем порядок запрошенных UID
+	for _, orderUID := range orderUIDs {
+		if order, ok := byUID[orderUID]; ok {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
 // Close закрывает соединение с базой данных
 func (r *OrderRepository) Close() error {
 	return r.db.Close()
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+func TestSearchTotalOnEveryPage(t *testing.T) {
+	db := newTestDB(t)
+	repository := NewOrderRepository(db, "test", nil)
+
+	rng := rand.New(rand.NewSource(1))
+	var orders []*entities.Order
+	for i := range 5 {
+		orders = append(orders, randomOrder(rng, fmt.Sprintf("order-%d", i)))
+	}
+	if err := repository.SaveBatch(orders); err != nil {
+		t.Fatalf("SaveBatch() error = %v", err)
+	}
+
+	tests := []struct {
+		offset      int
+		wantResults int
+	}{
+		{offset: 0, wantResults: 2},
+		{offset: 4, wantResults: 1},
+		// За последней страницей заказов нет, но total прежний
+		{offset: 5, wantResults: 0},
+		{offset: 100, wantResults: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("offset %d", tt.offset), func(t *testing.T) {
+			results, total, err := repository.Search("Testov", 2, tt.offset)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if total != 5 {
+				t.Errorf("total = %d, want 5", total)
+			}
+			if len(results) != tt.wantResults {
+				t.Errorf("got %d results, want %d", len(results), tt.wantResults)
+			}
+		})
+	}
+
+	// Ничего не найдено - total 0 и пустая страница
+	results, total, err := repository.Search("nothing-matches", 2, 0)
+	if err != nil || total != 0 || len(results) != 0 {
+		t.Errorf("Search() = %d results, total %d, %v; want none", len(results), total, err)
+	}
+}
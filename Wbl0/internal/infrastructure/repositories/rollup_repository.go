@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+
+	"github.com/lib/pq"
+)
+
+// rollupLockID - ключ advisory-блокировки пересчета сводок: несколько экземпляров
+// сервиса не должны пересчитывать один и тот же день одновременно
+const rollupLockID = 4300431
+
+// RollupRepository реализует пересчет дневных сводок в PostgreSQL
+type RollupRepository struct {
+	db *sql.DB
+}
+
+// NewRollupRepository создает новый экземпляр RollupRepository
+func NewRollupRepository(db *sql.DB) interfaces.RollupRepository {
+	return &RollupRepository{db: db}
+}
+
+// rollupQueries пересчитывают сводки за дни из $1 (date[]) по исходным таблицам
+var rollupQueries = []struct {
+	name  string
+	query string
+}{
+	{"orders", `
+		INSERT INTO rollup_daily_orders (day, currency, orders, items, revenue, goods_total)
+		SELECT d.day, p.currency, COUNT(*),
+			   SUM((SELECT COUNT(*) FROM items i WHERE i.order_uid = o.order_uid)),
+			   SUM(p.amount), SUM(p.goods_total)
+		FROM unnest($1::date[]) AS d(day)
+		JOIN orders o ON o.date_created >= d.day AND o.date_created < d.day + 1
+		JOIN payments p ON p.order_uid = o.order_uid
+		GROUP BY d.day, p.currency
+	`},
+	{"brands", `
+		INSERT INTO rollup_daily_brands (day, brand, currency, quantity, revenue)
+		SELECT d.day, i.brand, p.currency, COUNT(*), SUM(i.total_price)
+		FROM unnest($1::date[]) AS d(day)
+		JOIN orders o ON o.date_created >= d.day AND o.date_created < d.day + 1
+		JOIN payments p ON p.order_uid = o.order_uid
+		JOIN items i ON i.order_uid = o.order_uid
+		GROUP BY d.day, i.brand, p.currency
+	`},
+	{"delivery services", `
+		INSERT INTO rollup_daily_delivery_services (day, delivery_service, currency, orders, revenue)
+		SELECT d.day, o.delivery_service, p.currency, COUNT(*), SUM(p.amount)
+		FROM unnest($1::date[]) AS d(day)
+		JOIN orders o ON o.date_created >= d.day AND o.date_created < d.day + 1
+		JOIN payments p ON p.order_uid = o.order_uid
+		GROUP BY d.day, o.delivery_service, p.currency
+	`},
+}
+
+// RefreshDirty пересчитывает сводки за отмеченные дни одной транзакцией.
+// Если пересчет уже идет в другом экземпляре, возвращает 0.
+func (r *RollupRepository) RefreshDirty(limit int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow("SELECT pg_try_advisory_xact_lock($1)", rollupLockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to acquire rollup lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, day::text FROM rollup_dirty_days ORDER BY id LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get dirty days: %w", err)
+	}
+
+	var (
+		ids  []int64
+		days []string
+		seen = make(map[string]bool)
+	)
+	for rows.Next() {
+		var (
+			id  int64
+			day string
+		)
+		if err := rows.Scan(&id, &day); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan dirty day: %w", err)
+		}
+		ids = append(ids, id)
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read dirty days: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, table := range []string{"rollup_daily_orders", "rollup_daily_brands", "rollup_daily_delivery_services"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE day = ANY($1::date[])", pq.Array(days)); err != nil {
+			return 0, fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	for _, rollup := range rollupQueries {
+		if _, err := tx.Exec(rollup.query, pq.Array(days)); err != nil {
+			return 0, fmt.Errorf("failed to rebuild %s rollup: %w", rollup.name, err)
+		}
+	}
+
+	// Удаляем только прочитанные отметки: отметки, добавленные во время
+	// пересчета, обработает следующий проход
+	if _, err := tx.Exec("DELETE FROM rollup_dirty_days WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to clear dirty days: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit rollups: %w", err)
+	}
+	return len(ids), nil
+}
+
+// MarkDays отмечает для пересчета дни периода с заказами, а также дни, по которым
+// остались сводки, чтобы удалить сводки за дни без заказов
+func (r *RollupRepository) MarkDays(from, to time.Time) (int, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO rollup_dirty_days (day)
+		SELECT day FROM (
+			SELECT DISTINCT date_created::date AS day FROM orders
+			UNION
+			SELECT day FROM rollup_daily_orders
+		) days
+		WHERE ($1::date IS NULL OR day >= $1::date) AND ($2::date IS NULL OR day < $2::date)
+		ORDER BY day
+	`, nullTime(from), nullTime(to))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark rollup days: %w", err)
+	}
+
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count marked days: %w", err)
+	}
+	return int(marked), nil
+}
+
+// nullTime превращает нулевое время в NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/domain/entities"
+
+	_ "github.com/lib/pq"
+)
+
+// newTestDB открывает базу из TEST_DATABASE_URL и применяет миграции в отдельной схеме.
+// Используется одно соединение, чтобы search_path и часовой пояс действовали на все запросы.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	schema := fmt.Sprintf("rollup_test_%d", time.Now().UnixNano())
+	for _, query := range []string{
+		"CREATE SCHEMA " + schema,
+		"SET search_path TO " + schema + ", public",
+		"SET TIME ZONE 'UTC'",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	migrations, err := filepath.Glob("../../../db/migrations/*.sql")
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("migrations not found: %v", err)
+	}
+	slices.Sort(migrations)
+	for _, path := range migrations {
+		migration, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("failed to apply %s: %v", filepath.Base(path), err)
+		}
+	}
+	return db
+}
+
+// randomOrder создает заказ со случайными днем, валютой, службой доставки и товарами
+func randomOrder(rng *rand.Rand, orderUID string) *entities.Order {
+	currencies := []string{"RUB", "USD"}
+	brands := []string{"Vivienne Sabo", "Nike", "Adidas", "Puma"}
+	services := []string{"meest", "cdek", "boxberry"}
+
+	order := &entities.Order{
+		OrderUID:        orderUID,
+		TrackNumber:     "TRACK" + orderUID,
+		Entry:           "WBIL",
+		Delivery:        entities.Delivery{Name: "Test Testov", Phone: "+79161234567", Email: "test@gmail.com"},
+		Locale:          "en",
+		CustomerID:      fmt.Sprintf("customer-%d", rng.Intn(5)),
+		DeliveryService: services[rng.Intn(len(services))],
+		SmID:            99,
+		DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).
+			Add(time.Duration(rng.Intn(10*24*60)) * time.Minute),
+	}
+	for i := range 1 + rng.Intn(3) {
+		price := 100 + rng.Intn(900)
+		order.Items = append(order.Items, entities.Item{
+			ChrtID:      i + 1,
+			TrackNumber: order.TrackNumber,
+			Price:       price,
+			Rid:         fmt.Sprintf("%s-%d", orderUID, i),
+			Name:        "Item",
+			TotalPrice:  price,
+			Brand:       brands[rng.Intn(len(brands))],
+			Status:      202,
+		})
+		order.Payment.GoodsTotal += price
+	}
+	order.Payment.Transaction = orderUID
+	order.Payment.Currency = currencies[rng.Intn(len(currencies))]
+	order.Payment.Provider = "wbpay"
+	order.Payment.DeliveryCost = rng.Intn(1000)
+	order.Payment.Amount = order.Payment.GoodsTotal + order.Payment.DeliveryCost
+	return order
+}
+
+// expectedRollups считает сводки по заказам в памяти в том же виде, что rollupRows
+func expectedRollups(orders map[string]*entities.Order) map[string][]string {
+	type totals struct{ count, items, revenue, goods int }
+	dailyOrders := make(map[string]*totals)
+	brands := make(map[string]*totals)
+	services := make(map[string]*totals)
+	add := func(m map[string]*totals, key string) *totals {
+		if m[key] == nil {
+			m[key] = &totals{}
+		}
+		return m[key]
+	}
+
+	for _, order := range orders {
+		day := order.DateCreated.UTC().Format("2006-01-02")
+		currency := order.Payment.Currency
+
+		t := add(dailyOrders, day+" "+currency)
+		t.count++
+		t.items += len(order.Items)
+		t.revenue += order.Payment.Amount
+		t.goods += order.Payment.GoodsTotal
+
+		t = add(services, day+" "+order.DeliveryService+" "+currency)
+		t.count++
+		t.revenue += order.Payment.Amount
+
+		for _, item := range order.Items {
+			t = add(brands, day+" "+item.Brand+" "+currency)
+			t.count++
+			t.revenue += item.TotalPrice
+		}
+	}
+
+	result := map[string][]string{"orders": {}, "brands": {}, "services": {}}
+	for key, t := range dailyOrders {
+		result["orders"] = append(result["orders"], fmt.Sprintf("%s %d %d %d %d", key, t.count, t.items, t.revenue, t.goods))
+	}
+	for key, t := range brands {
+		result["brands"] = append(result["brands"], fmt.Sprintf("%s %d %d", key, t.count, t.revenue))
+	}
+	for key, t := range services {
+		result["services"] = append(result["services"], fmt.Sprintf("%s %d %d", key, t.count, t.revenue))
+	}
+	for _, rows := range result {
+		slices.Sort(rows)
+	}
+	return result
+}
+
+// rollupRows читает сводки из базы построчно
+func rollupRows(t *testing.T, db *sql.DB) map[string][]string {
+	t.Helper()
+
+	queries := map[string]string{
+		"orders": `SELECT concat_ws(' ', day::text, currency, orders, items, revenue, goods_total)
+			FROM rollup_daily_orders`,
+		"brands": `SELECT concat_ws(' ', day::text, brand, currency, quantity, revenue)
+			FROM rollup_daily_brands`,
+		"services": `SELECT concat_ws(' ', day::text, delivery_service, currency, orders, revenue)
+			FROM rollup_daily_delivery_services`,
+	}
+
+	result := make(map[string][]string)
+	for name, query := range queries {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := []string{}
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				t.Fatal(err)
+			}
+			list = append(list, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(list)
+		result[name] = list
+	}
+	return result
+}
+
+// refreshAll пересчитывает все отмеченные дни
+func refreshAll(t *testing.T, rollups *RollupRepository) {
+	t.Helper()
+
+	for {
+		refreshed, err := rollups.RefreshDirty(7)
+		if err != nil {
+			t.Fatalf("RefreshDirty() error = %v", err)
+		}
+		if refreshed == 0 {
+			return
+		}
+	}
+}
+
+func TestRollupsMatchRawTables(t *testing.T) {
+	db := newTestDB(t)
+	orderRepository := NewOrderRepository(db, "test", nil)
+	rollups := NewRollupRepository(db).(*RollupRepository)
+
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewSource(seed))
+	orders := make(map[string]*entities.Order)
+
+	for round := range 5 {
+		// Новые заказы и повторная запись существующих со сменой дня, валюты и товаров
+		var batch []*entities.Order
+		for range 40 {
+			orderUID := fmt.Sprintf("order-%d", rng.Intn(120))
+			order := randomOrder(rng, orderUID)
+			orders[orderUID] = order
+			batch = append(batch, order)
+		}
+		if err := orderRepository.SaveBatch(batch); err != nil {
+			t.Fatalf("SaveBatch() error = %v", err)
+		}
+
+		// Удаление заказа отмечает его день, чтобы сводка за день без заказов исчезла
+		for orderUID := range orders {
+			if rng.Intn(10) == 0 {
+				if _, err := db.Exec("DELETE FROM orders WHERE order_uid = $1", orderUID); err != nil {
+					t.Fatal(err)
+				}
+				delete(orders, orderUID)
+			}
+		}
+
+		refreshAll(t, rollups)
+		if got, want := rollupRows(t, db), expectedRollups(orders); !reflect.DeepEqual(got, want) {
+			t.Fatalf("seed %d, round %d: rollups differ from raw tables\ngot  %v\nwant %v", seed, round, got, want)
+		}
+	}
+
+	var dirty int
+	if err := db.QueryRow("SELECT COUNT(*) FROM rollup_dirty_days").Scan(&dirty); err != nil {
+		t.Fatal(err)
+	}
+	if dirty != 0 {
+		t.Errorf("%d dirty days left after refresh", dirty)
+	}
+}
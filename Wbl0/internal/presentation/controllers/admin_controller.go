@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// AdminController обрабатывает административные запросы управления потребителем
+type AdminController struct {
+	consumerAdmin interfaces.ConsumerAdmin
+}
+
+func NewAdminController(consumerAdmin interfaces.ConsumerAdmin) *AdminController {
+	return &AdminController{
+		consumerAdmin: consumerAdmin,
+	}
+}
+
+// ConsumerStatus возвращает состояние потребителя: паузу, закоммиченные офсеты и high-water mark
+func (c *AdminController) ConsumerStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	offsets, err := c.consumerAdmin.Offsets()
+	if err != nil {
+		log.Printf("Failed to get consumer offsets: %v", err)
+		writeError(w, http.StatusBadGateway, "failed to get consumer offsets")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.ConsumerStatusResponse{
+		Paused:     c.consumerAdmin.Paused(),
+		Partitions: offsets,
+	})
+}
+
+// ResetOffsets сбрасывает офсеты группы на earliest, latest, конкретный офсет или время.
+// В ответе у партиций, назначенных другим экземплярам, applied = false.
+func (c *AdminController) ResetOffsets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var request dto.OffsetResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	targets, err := c.consumerAdmin.ResetOffsets(request)
+	if err != nil {
+		log.Printf("Failed to reset consumer offsets: %v", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// PauseConsumer приостанавливает потребление сообщений
+func (c *AdminController) PauseConsumer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	c.consumerAdmin.Pause()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// ResumeConsumer возобновляет потребление сообщений
+func (c *AdminController) ResumeConsumer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	c.consumerAdmin.Resume()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// Параметры аналитических запросов по умолчанию
+const (
+	defaultAnalyticsPeriod = 30 * 24 * time.Hour
+	defaultTopLimit        = 10
+	maxTopLimit            = 100
+)
+
+// AnalyticsController обрабатывает аналитические запросы по заказам
+type AnalyticsController struct {
+	analyticsService interfaces.AnalyticsService
+}
+
+func NewAnalyticsController(analyticsService interfaces.AnalyticsService) *AnalyticsController {
+	return &AnalyticsController{
+		analyticsService: analyticsService,
+	}
+}
+
+// Revenue возвращает выручку по периодам и валютам. Параметр interval: day, week или month.
+func (c *AnalyticsController) Revenue(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	interval := dto.RevenueInterval(r.URL.Query().Get("interval"))
+	switch interval {
+	case "":
+		interval = dto.RevenueByDay
+	case dto.RevenueByDay, dto.RevenueByWeek, dto.RevenueByMonth:
+	default:
+		writeError(w, http.StatusBadRequest, "interval must be day, week or month")
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.Revenue(filter, interval)
+	})
+}
+
+// TopBrands возвращает рейтинг брендов. Параметры: by (quantity или revenue), limit.
+func (c *AnalyticsController) TopBrands(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	metric, limit, err := parseTopParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.TopBrands(filter, metric, limit)
+	})
+}
+
+// TopProducts возвращает рейтинг товаров по nm_id. Параметры: by (quantity или revenue), limit.
+func (c *AnalyticsController) TopProducts(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	metric, limit, err := parseTopParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.TopProducts(filter, metric, limit)
+	})
+}
+
+// Basket возвращает средний размер корзины по валютам
+func (c *AnalyticsController) Basket(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.Basket(filter)
+	})
+}
+
+// Breakdown возвращает разбивку заказов по полю из пути: /analytics/breakdown/{dimension}
+func (c *AnalyticsController) Breakdown(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	dimension := dto.BreakdownDimension(strings.TrimPrefix(r.URL.Path, "/analytics/breakdown/"))
+	switch dimension {
+	case dto.BreakdownDeliveryService, dto.BreakdownRegion, dto.BreakdownProvider, dto.BreakdownBank:
+	default:
+		writeError(w, http.StatusNotFound, "dimension must be delivery_service, region, provider or bank")
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.Breakdown(filter, dimension)
+	})
+}
+
+// Discount возвращает статистику скидок по товарам
+func (c *AnalyticsController) Discount(w http.ResponseWriter, r *http.Request) {
+	filter, ok := c.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c.respond(w, func() (*dto.AnalyticsResponse, error) {
+		return c.analyticsService.Discount(filter)
+	})
+}
+
+// parseRequest проверяет метод и читает фильтр. При ошибке сам пишет ответ и возвращает false.
+func (c *AnalyticsController) parseRequest(w http.ResponseWriter, r *http.Request) (dto.AnalyticsFilter, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return dto.AnalyticsFilter{}, false
+	}
+
+	filter, err := parseAnalyticsFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return dto.AnalyticsFilter{}, false
+	}
+	return filter, true
+}
+
+func (c *AnalyticsController) respond(w http.ResponseWriter, query func() (*dto.AnalyticsResponse, error)) {
+	response, err := query()
+	if err != nil {
+		log.Printf("Failed to compute analytics: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseAnalyticsFilter читает период (from, to) и фильтры запроса.
+// По умолчанию to - начало следующих суток (UTC), from - на 30 дней раньше to:
+// границы не меняются в течение суток, поэтому результаты можно кэшировать.
+func parseAnalyticsFilter(r *http.Request) (dto.AnalyticsFilter, error) {
+	query := r.URL.Query()
+
+	to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if value := query.Get("to"); value != "" {
+		parsed, err := parseAnalyticsTime(value)
+		if err != nil {
+			return dto.AnalyticsFilter{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultAnalyticsPeriod)
+	if value := query.Get("from"); value != "" {
+		parsed, err := parseAnalyticsTime(value)
+		if err != nil {
+			return dto.AnalyticsFilter{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return dto.AnalyticsFilter{}, fmt.Errorf("from must be before to")
+	}
+
+	return dto.AnalyticsFilter{
+		From:            from,
+		To:              to,
+		Currency:        query.Get("currency"),
+		DeliveryService: query.Get("delivery_service"),
+		Region:          query.Get("region"),
+		Provider:        query.Get("provider"),
+		Bank:            query.Get("bank"),
+	}, nil
+}
+
+// parseAnalyticsTime разбирает дату (2006-01-02) или время в RFC 3339
+func parseAnalyticsTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.DateOnly, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339 time")
+	}
+	return parsed.UTC(), nil
+}
+
+// parseTopParams читает показатель рейтинга (by) и его размер (limit)
+func parseTopParams(r *http.Request) (dto.TopMetric, int, error) {
+	metric := dto.TopMetric(r.URL.Query().Get("by"))
+	switch metric {
+	case "":
+		metric = dto.TopByQuantity
+	case dto.TopByQuantity, dto.TopByRevenue:
+	default:
+		return "", 0, fmt.Errorf("by must be quantity or revenue")
+	}
+
+	limit := defaultTopLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxTopLimit {
+			return "", 0, fmt.Errorf("limit must be between 1 and %d", maxTopLimit)
+		}
+		limit = parsed
+	}
+
+	return metric, limit, nil
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// APIKeyHeader - заголовок со статическим API-ключом
+const APIKeyHeader = "X-API-Key"
+
+type principalKey struct{}
+
+// AuthMiddleware проверяет учетные данные и области доступа запросов
+type AuthMiddleware struct {
+	authenticator interfaces.Authenticator
+}
+
+// NewAuthMiddleware создает проверку доступа. authenticator может быть nil,
+// тогда аутентификация отключена и все запросы пропускаются.
+func NewAuthMiddleware(authenticator interfaces.Authenticator) *AuthMiddleware {
+	return &AuthMiddleware{authenticator: authenticator}
+}
+
+// Require пропускает запрос к next, только если клиент предъявил API-ключ
+// (заголовок X-API-Key) или JWT (Authorization: Bearer) с областью scope.
+// Без учетных данных или с невалидными отвечает 401, без области - 403.
+func (m *AuthMiddleware) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if m.authenticator == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := m.authenticate(r)
+		if err != nil {
+			log.Printf("Rejected request to %s from %s: %v", r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="order-service", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+		if principal == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="order-service"`)
+			writeError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="order-service", error="insufficient_scope", scope=%q`, scope))
+			writeError(w, http.StatusForbidden, fmt.Sprintf("Scope %s is required", scope))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	}
+}
+
+// authenticate возвращает клиента по учетным данным запроса или nil, если их нет
+func (m *AuthMiddleware) authenticate(r *http.Request) (*dto.Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return m.authenticator.AuthenticateAPIKey(key)
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return nil, nil
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, fmt.Errorf("unsupported authorization scheme")
+	}
+	return m.authenticator.AuthenticateToken(strings.TrimSpace(token))
+}
+
+// principalFrom возвращает клиента, аутентифицированного AuthMiddleware,
+// или nil, если аутентификация отключена
+func principalFrom(r *http.Request) *dto.Principal {
+	principal, _ := r.Context().Value(principalKey{}).(*dto.Principal)
+	return principal
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// fakeAuthenticator принимает заданные API-ключи и токены
+type fakeAuthenticator struct {
+	principals map[string]*dto.Principal
+}
+
+func (a *fakeAuthenticator) AuthenticateAPIKey(key string) (*dto.Principal, error) {
+	return a.lookup(key)
+}
+
+func (a *fakeAuthenticator) AuthenticateToken(token string) (*dto.Principal, error) {
+	return a.lookup(token)
+}
+
+func (a *fakeAuthenticator) lookup(credential string) (*dto.Principal, error) {
+	principal, ok := a.principals[credential]
+	if !ok {
+		return nil, errors.New("invalid credentials")
+	}
+	return principal, nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticator := &fakeAuthenticator{principals: map[string]*dto.Principal{
+		"reader-key":   {Subject: "reader", Method: dto.AuthMethodAPIKey, Scopes: []string{dto.ScopeOrdersRead}},
+		"admin-key":    {Subject: "admin", Method: dto.AuthMethodAPIKey, Scopes: []string{dto.ScopeAdmin}},
+		"reader-token": {Subject: "dashboard", Method: dto.AuthMethodJWT, Scopes: []string{dto.ScopeOrdersRead, dto.ScopeOrdersPII}},
+	}}
+	middleware := NewAuthMiddleware(authenticator)
+
+	tests := []struct {
+		name          string
+		scope         string
+		headers       map[string]string
+		wantCode      int
+		wantError     string
+		wantChallenge string
+	}{
+		{"api key", dto.ScopeOrdersRead, map[string]string{APIKeyHeader: "reader-key"}, http.StatusOK, "", ""},
+		{"bearer token", dto.ScopeOrdersRead, map[string]string{"Authorization": "Bearer reader-token"}, http.StatusOK, "", ""},
+		{"lowercase bearer", dto.ScopeOrdersRead, map[string]string{"Authorization": "bearer reader-token"}, http.StatusOK, "", ""},
+		{"no credentials", dto.ScopeOrdersRead, nil, http.StatusUnauthorized, "Authentication required", `Bearer realm="order-service"`},
+		{"unknown api key", dto.ScopeOrdersRead, map[string]string{APIKeyHeader: "unknown"}, http.StatusUnauthorized, "Invalid credentials", `error="invalid_token"`},
+		{"invalid token", dto.ScopeOrdersRead, map[string]string{"Authorization": "Bearer forged"}, http.StatusUnauthorized, "Invalid credentials", `error="invalid_token"`},
+		{"basic auth", dto.ScopeOrdersRead, map[string]string{"Authorization": "Basic cmVhZGVyOmtleQ=="}, http.StatusUnauthorized, "Invalid credentials", `error="invalid_token"`},
+		{"empty bearer", dto.ScopeOrdersRead, map[string]string{"Authorization": "Bearer "}, http.StatusUnauthorized, "Invalid credentials", `error="invalid_token"`},
+		{"missing scope", dto.ScopeOrdersPII, map[string]string{APIKeyHeader: "reader-key"}, http.StatusForbidden, "Scope orders:pii is required", `error="insufficient_scope"`},
+		{"admin route with read key", dto.ScopeAdmin, map[string]string{APIKeyHeader: "reader-key"}, http.StatusForbidden, "Scope admin is required", `scope="admin"`},
+		{"admin route with read token", dto.ScopeAdmin, map[string]string{"Authorization": "Bearer reader-token"}, http.StatusForbidden, "Scope admin is required", `scope="admin"`},
+		{"admin route with admin key", dto.ScopeAdmin, map[string]string{APIKeyHeader: "admin-key"}, http.StatusOK, "", ""},
+		// Области не наследуются: admin не дает чтения заказов
+		{"read route with admin key", dto.ScopeOrdersRead, map[string]string{APIKeyHeader: "admin-key"}, http.StatusForbidden, "Scope orders:read is required", `error="insufficient_scope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal *dto.Principal
+			handler := middleware.Require(tt.scope, func(w http.ResponseWriter, r *http.Request) {
+				principal = principalFrom(r)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/order/known", nil)
+			for key, value := range tt.headers {
+				request.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				if principal == nil || !principal.HasScope(tt.scope) {
+					t.Errorf("principal in context = %+v", principal)
+				}
+				return
+			}
+			if principal != nil {
+				t.Error("handler was called for a rejected request")
+			}
+
+			if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var response dto.ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("error body is not JSON: %v", err)
+			}
+			if response.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", response.Error, tt.wantError)
+			}
+			if challenge := recorder.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, tt.wantChallenge) {
+				t.Errorf("WWW-Authenticate = %q, want %q", challenge, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	called := false
+	handler := NewAuthMiddleware(nil).Require(dto.ScopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		called = principalFrom(r) == nil
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/consumer", nil))
+
+	if !called {
+		t.Error("request was not passed through with authentication disabled")
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// BrokerController публикует сообщения во встроенный брокер.
+// Используется при локальном запуске с брокером в памяти.
+type BrokerController struct {
+	publisher interfaces.MessagePublisher
+	topic     string
+}
+
+func NewBrokerController(publisher interfaces.MessagePublisher, topic string) *BrokerController {
+	return &BrokerController{
+		publisher: publisher,
+		topic:     topic,
+	}
+}
+
+// Publish публикует тело запроса как сообщение. Топик берется из параметра topic,
+// ключ - из параметра key, заголовки запроса вида X-Message-<Name> становятся
+// заголовками сообщения.
+func (c *BrokerController) Publish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	topic := r.URL.Query().Get("topic")
+	if topic == "" {
+		topic = c.topic
+	}
+
+	headers := make(map[string]string)
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	for name, values := range r.Header {
+		if key, ok := strings.CutPrefix(strings.ToLower(name), "x-message-"); ok && len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+
+	message := &dto.Message{
+		Topic:   topic,
+		Key:     []byte(r.URL.Query().Get("key")),
+		Value:   body,
+		Headers: headers,
+	}
+	if err := c.publisher.Publish(message); err != nil {
+		log.Printf("Failed to publish message: %v", err)
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/infrastructure/exporters"
+)
+
+// ExportController обрабатывает потоковую выгрузку заказов
+type ExportController struct {
+	orderExporter interfaces.OrderExporter
+	masker        interfaces.PIIMasker
+}
+
+// NewExportController создает контроллер выгрузки. Данные получателя маскируются
+// masker, если у клиента нет области orders:pii.
+func NewExportController(orderExporter interfaces.OrderExporter, masker interfaces.PIIMasker) *ExportController {
+	return &ExportController{
+		orderExporter: orderExporter,
+		masker:        masker,
+	}
+}
+
+// ExportOrders выгружает заказы потоком. Параметры: format (csv, ndjson, parquet),
+// from, to, customer_id, delivery_service.
+func (c *ExportController) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = exporters.FormatCSV
+	}
+	if !slices.Contains(exporters.Formats, format) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("format must be one of %s", strings.Join(exporters.Formats, ", ")))
+		return
+	}
+
+	filter := dto.ExportFilter{
+		CustomerID:      query.Get("customer_id"),
+		DeliveryService: query.Get("delivery_service"),
+	}
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			parsed, err := parseAnalyticsTime(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	// Выгрузка может идти дольше WriteTimeout сервера
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to extend export write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", exporters.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="orders.%s"`, format))
+
+	writer, err := exporters.NewOrderWriter(format, w)
+	if err != nil {
+		log.Printf("Failed to start export: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if !showPII(r) {
+		writer = exporters.NewMaskingWriter(writer, c.masker)
+	}
+
+	// Заголовки и часть данных могли уже уйти клиенту, поэтому при ошибке
+	// соединение обрывается, чтобы неполная выгрузка не выглядела успешной
+	exported, err := c.orderExporter.Export(filter, writer)
+	if err != nil {
+		log.Printf("Export of orders failed after %d orders: %v", exported, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	log.Printf("Exported %d orders as %s", exported, format)
+}
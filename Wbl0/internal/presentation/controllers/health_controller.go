@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// HealthController обрабатывает проверки здоровья и готовности сервиса
+type HealthController struct {
+	lagReporter interfaces.LagReporter
+	latency     interfaces.LatencyRecorder
+	maxLag      int64
+}
+
+// NewHealthController создает контроллер проверок. lagReporter может быть nil,
+// если потребитель не отслеживает отставание. latency - задержка сохранения заказов.
+// maxLag - отставание, после которого сервис считается неготовым; 0 отключает проверку.
+func NewHealthController(lagReporter interfaces.LagReporter, latency interfaces.LatencyRecorder, maxLag int64) *HealthController {
+	return &HealthController{
+		lagReporter: lagReporter,
+		latency:     latency,
+		maxLag:      maxLag,
+	}
+}
+
+// HealthCheck сообщает, что сервис жив, и показывает отставание потребителя
+// и задержку сохранения заказов
+func (c *HealthController) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	response, _ := c.status()
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ReadinessCheck отвечает 503, если отставание потребителя превышает допустимое
+// или его не удалось измерить
+func (c *HealthController) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	response, ready := c.status()
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, response)
+}
+
+func (c *HealthController) status() (dto.HealthResponse, bool) {
+	response := dto.HealthResponse{
+		Status:  "ok",
+		Service: "order-service",
+	}
+
+	latency := c.latency.Stats()
+	response.PersistLatency = &latency
+
+	if c.lagReporter == nil {
+		return response, true
+	}
+
+	lag := c.lagReporter.Lag()
+	response.Consumer = &lag
+
+	if c.maxLag <= 0 {
+		return response, true
+	}
+	// Без успешного замера отставание неизвестно, а предыдущий замер мог устареть
+	if lag.Error != "" || lag.UpdatedAt.IsZero() {
+		response.Status = "unknown"
+		return response, false
+	}
+	if lag.Total > c.maxLag {
+		response.Status = "lagging"
+		return response, false
+	}
+	return response, true
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/internal/application/interfaces"
+)
+
+// fakeLagReporter отдает заданный замер отставания
+type fakeLagReporter struct {
+	lag dto.ConsumerLag
+}
+
+func (r *fakeLagReporter) Lag() dto.ConsumerLag { return r.lag }
+
+// fakeLatency отдает пустую статистику задержки
+type fakeLatency struct {
+	interfaces.LatencyRecorder
+}
+
+func (fakeLatency) Stats() dto.LatencyStats { return dto.LatencyStats{} }
+
+func TestReadinessCheck(t *testing.T) {
+	measured := time.Now()
+
+	tests := []struct {
+		name       string
+		lag        dto.ConsumerLag
+		maxLag     int64
+		wantCode   int
+		wantStatus string
+	}{
+		{"within limit", dto.ConsumerLag{Total: 10, UpdatedAt: measured}, 100, http.StatusOK, "ok"},
+		{"lagging", dto.ConsumerLag{Total: 101, UpdatedAt: measured}, 100, http.StatusServiceUnavailable, "lagging"},
+		{"fetch failed", dto.ConsumerLag{Total: 10, UpdatedAt: measured, Error: "broker is unavailable"}, 100, http.StatusServiceUnavailable, "unknown"},
+		{"not measured yet", dto.ConsumerLag{}, 100, http.StatusServiceUnavailable, "unknown"},
+		{"check disabled", dto.ConsumerLag{Error: "broker is unavailable"}, 0, http.StatusOK, "ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewHealthController(&fakeLagReporter{lag: tt.lag}, fakeLatency{}, tt.maxLag)
+			recorder := httptest.NewRecorder()
+
+			controller.ReadinessCheck(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if recorder.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", recorder.Code, tt.wantCode)
+			}
+			var response dto.HealthResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", response.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHealthCheckReportsUnknownLag(t *testing.T) {
+	controller := NewHealthController(&fakeLagReporter{lag: dto.ConsumerLag{Error: "broker is unavailable"}}, fakeLatency{}, 100)
+	recorder := httptest.NewRecorder()
+
+	controller.HealthCheck(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	// Проверка живости не зависит от брокера
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status code = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,18 +10,28 @@ import (
 
 	"WbServis/Wbl0/internal/application/dto"
 	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
 )
 
 type OrderController struct {
 	orderService interfaces.OrderService
+	masker       interfaces.PIIMasker
 }
 
-func NewOrderController(orderService interfaces.OrderService) *OrderController {
+// NewOrderController создает контроллер заказов. Данные получателя в ответах
+// маскируются masker, если у клиента нет области orders:pii.
+func NewOrderController(orderService interfaces.OrderService, masker interfaces.PIIMasker) *OrderController {
 	return &OrderController{
 		orderService: orderService,
+		masker:       masker,
 	}
 }
 
+// showPII сообщает, можно ли отдать клиенту данные получателя без маскирования
+func showPII(r *http.Request) bool {
+	return principalFrom(r).HasScope(dto.ScopeOrdersPII)
+}
+
 func (c *OrderController) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/order/")
 	if path == "" {
@@ -29,12 +40,20 @@ func (c *OrderController) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	order, err := c.orderService.GetOrderByID(path)
+	if errors.Is(err, interfaces.ErrOrderNotFound) {
+		http.Error(w, fmt.Sprintf("Order not found: %s", path), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to get order %s: %v", path, err)
-		http.Error(w, fmt.Sprintf("Order not found: %s", path), http.StatusNotFound)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	if !showPII(r) {
+		order = c.masker.MaskOrder(order)
+	}
+
 	response := dto.OrderResponse{
 		Order: order,
 	}
@@ -51,14 +70,121 @@ func (c *OrderController) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Order %s retrieved successfully", path)
 }
 
-func (c *OrderController) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+// minSearchQueryLength - минимальная длина поискового запроса: триграммы
+// короче двух символов совпадают почти со всем
+const minSearchQueryLength = 2
+
+// SearchOrders ищет заказы по имени, адресу, телефону, email получателя
+// и названию или бренду товаров. Параметры: q, limit, offset.
+func (c *OrderController) SearchOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(query)) < minSearchQueryLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("query must be at least %d characters", minSearchQueryLength))
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := c.orderService.SearchOrders(query, limit, offset)
+	if err != nil {
+		log.Printf("Failed to search orders: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !showPII(r) {
+		for i := range response.Results {
+			response.Results[i].Order = c.masker.MaskOrder(response.Results[i].Order)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// FindOrders возвращает обработчик поиска заказов по значению поля из пути запроса,
+// например /orders/by-track/{track}
+func (c *OrderController) FindOrders(field dto.LookupField, prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		value := strings.TrimPrefix(r.URL.Path, prefix)
+		if value == "" || strings.Contains(value, "/") {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is required", field))
+			return
+		}
+
+		orders, err := c.orderService.FindOrders(field, value)
+		if err != nil {
+			log.Printf("Failed to find orders by %s %s: %v", field, value, err)
+			writeError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if len(orders) == 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no orders with %s %s", field, value))
+			return
+		}
+
+		if !showPII(r) {
+			masked := make([]*entities.Order, len(orders))
+			for i, order := range orders {
+				masked[i] = c.masker.MaskOrder(order)
+			}
+			orders = masked
+		}
+
+		writeJSON(w, http.StatusOK, dto.OrdersResponse{Orders: orders})
+	}
+}
+
+// GetCustomerOrders возвращает заказы покупателя со сводкой: /customers/{customer_id}/orders.
+// Параметры: limit, offset.
+func (c *OrderController) GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/customers/")
+	customerID, ok := strings.CutSuffix(path, "/orders")
+	if !ok || customerID == "" || strings.Contains(customerID, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := c.orderService.GetCustomerOrders(customerID, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get orders of customer %s: %v", customerID, err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if response.Stats.OrderCount == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no orders for customer %s", customerID))
+		return
+	}
 
-	response := map[string]string{
-		"status":  "ok",
-		"service": "order-service",
+	if !showPII(r) {
+		for i := range response.Orders {
+			response.Orders[i].DeliveryCity = c.masker.MaskField("city", response.Orders[i].DeliveryCity)
+		}
 	}
 
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/internal/domain/entities"
+)
+
+// fakeOrderService отдает заказы из памяти. Неиспользуемые методы
+// интерфейса не реализованы и паникуют при вызове.
+type fakeOrderService struct {
+	interfaces.OrderService
+	orders map[string]*entities.Order
+	err    error
+}
+
+func (s *fakeOrderService) GetOrderByID(orderUID string) (*entities.Order, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	order, ok := s.orders[orderUID]
+	if !ok {
+		return nil, interfaces.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// identityMasker не маскирует данные
+type identityMasker struct{}
+
+func (identityMasker) MaskOrder(order *entities.Order) *entities.Order { return order }
+func (identityMasker) MaskField(field, value string) string            { return value }
+func (identityMasker) MaskText(text string) string                     { return text }
+
+func TestGetOrderByIDStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *fakeOrderService
+		want    int
+	}{
+		{"found", &fakeOrderService{orders: map[string]*entities.Order{"known": {OrderUID: "known"}}}, http.StatusOK},
+		{"unknown", &fakeOrderService{}, http.StatusNotFound},
+		{"database error", &fakeOrderService{err: errors.New("connection refused")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := NewOrderController(tt.service, identityMasker{})
+			recorder := httptest.NewRecorder()
+
+			controller.GetOrderByID(recorder, httptest.NewRequest(http.MethodGet, "/order/known", nil))
+
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
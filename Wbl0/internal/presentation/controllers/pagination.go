@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Границы размера страницы для списочных запросов
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination читает параметры limit и offset запроса
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageLimit, 0
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		limit = parsed
+	}
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"WbServis/Wbl0/internal/application/dto"
+)
+
+// writeJSON отправляет ответ в формате JSON с указанным статусом
+func writeJSON(w http.ResponseWriter, status int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// writeError отправляет ошибку в формате dto.ErrorResponse
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, dto.ErrorResponse{Error: message})
+}
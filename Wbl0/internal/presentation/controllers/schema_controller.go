@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"WbServis/Wbl0/internal/application/interfaces"
+	"WbServis/Wbl0/pkg/schemas"
+)
+
+// SchemaController публикует схемы сообщений
+type SchemaController struct {
+	validator interfaces.MessageValidator
+}
+
+func NewSchemaController(validator interfaces.MessageValidator) *SchemaController {
+	return &SchemaController{
+		validator: validator,
+	}
+}
+
+// GetOrderSchema возвращает JSON Schema сообщения о заказе.
+// Версия задается параметром ?version=, по умолчанию - последняя.
+func (c *SchemaController) GetOrderSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	schema, err := c.validator.Schema(r.URL.Query().Get("version"))
+	if err != nil {
+		if errors.Is(err, schemas.ErrUnknownVersion) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Failed to get order schema: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(schema)
+}
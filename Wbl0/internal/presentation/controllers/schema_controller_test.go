@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"WbServis/Wbl0/internal/application/dto"
+	"WbServis/Wbl0/pkg/schemas"
+)
+
+func TestGetOrderSchema(t *testing.T) {
+	validator, err := schemas.NewOrderValidator(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := NewSchemaController(validator)
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		wantCode int
+	}{
+		{"latest", http.MethodGet, "/schema/order", http.StatusOK},
+		{"version", http.MethodGet, "/schema/order?version=1", http.StatusOK},
+		{"prefixed version", http.MethodGet, "/schema/order?version=v1", http.StatusOK},
+		{"unknown version", http.MethodGet, "/schema/order?version=2", http.StatusNotFound},
+		{"post", http.MethodPost, "/schema/order", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			controller.GetOrderSchema(recorder, httptest.NewRequest(tt.method, tt.url, nil))
+
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				var response dto.ErrorResponse
+				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil || response.Error == "" {
+					t.Errorf("error body = %q, %v", recorder.Body.String(), err)
+				}
+				return
+			}
+
+			if got := recorder.Header().Get("Content-Type"); got != "application/schema+json" {
+				t.Errorf("Content-Type = %q, want application/schema+json", got)
+			}
+			var schema struct {
+				Title       string `json:"title"`
+				Description string `json:"description"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&schema); err != nil {
+				t.Fatalf("schema is not JSON: %v", err)
+			}
+			if schema.Title != "Order" {
+				t.Errorf("schema title = %q, want Order", schema.Title)
+			}
+		})
+	}
+}
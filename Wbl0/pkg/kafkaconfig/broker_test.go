@@ -0,0 +1,371 @@
+package kafkaconfig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// testCA - центр сертификации, выпускающий сертификаты брокеру и клиенту
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue выпускает сертификат для 127.0.0.1 с назначением usage
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeKeyPair сохраняет сертификат и ключ в PEM-файлы
+func writeKeyPair(t *testing.T, certificate tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(certificate.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// rejectReporter пишет ошибки тестового брокера в лог вместо провала теста:
+// оборванное TLS-рукопожатие на стороне брокера - ожидаемый результат
+type rejectReporter struct {
+	*testing.T
+}
+
+func (r *rejectReporter) Errorf(format string, args ...interface{}) {
+	r.Logf("broker: "+format, args...)
+}
+
+func (r *rejectReporter) Error(args ...interface{}) {
+	r.Log(append([]interface{}{"broker:"}, args...)...)
+}
+
+// newMockBroker запускает тестовый брокер sarama на listener, отвечающий на запрос
+// метаданных, и дополнительные обработчики handlers
+func newMockBroker(t *testing.T, reporter sarama.TestReporter, listener net.Listener, handlers map[string]sarama.MockResponse) *sarama.MockBroker {
+	t.Helper()
+
+	broker := sarama.NewMockBrokerListener(reporter, 1, listener)
+	t.Cleanup(broker.Close)
+
+	responses := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("orders", 0, broker.BrokerID()),
+	}
+	for name, response := range handlers {
+		responses[name] = response
+	}
+	broker.SetHandlerByMap(responses)
+	return broker
+}
+
+// connect подключается к брокерам с конфигурацией cfg и запрашивает метаданные
+func connect(cfg Config, brokers []string) error {
+	config, err := NewSaramaConfig(cfg)
+	if err != nil {
+		return err
+	}
+	config.Net.DialTimeout = 5 * time.Second
+	config.Metadata.Retry.Max = 0
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.Topics()
+	return err
+}
+
+func TestTLSWithMockBroker(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	serverCert := ca.issue(t, "broker", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := writeKeyPair(t, ca.issue(t, "order-service", x509.ExtKeyUsageClientAuth))
+	otherCert, otherKey := writeKeyPair(t, otherCA.issue(t, "order-service", x509.ExtKeyUsageClientAuth))
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	tests := []struct {
+		name       string
+		clientAuth tls.ClientAuthType
+		tls        TLSConfig
+		wantErr    bool
+	}{
+		{
+			name: "server certificate",
+			tls:  TLSConfig{Enabled: true, CAFile: ca.file},
+		},
+		{
+			name:    "untrusted server certificate",
+			tls:     TLSConfig{Enabled: true, CAFile: otherCA.file},
+			wantErr: true,
+		},
+		{
+			name: "untrusted certificate with insecure skip verify",
+			tls:  TLSConfig{Enabled: true, CAFile: otherCA.file, InsecureSkipVerify: true},
+		},
+		{
+			name:       "mutual tls",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			tls:        TLSConfig{Enabled: true, CAFile: ca.file, CertFile: clientCert, KeyFile: clientKey},
+		},
+		{
+			name:       "mutual tls without client certificate",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			tls:        TLSConfig{Enabled: true, CAFile: ca.file},
+			wantErr:    true,
+		},
+		{
+			name:       "mutual tls with untrusted client certificate",
+			clientAuth: tls.RequireAndVerifyClientCert,
+			tls:        TLSConfig{Enabled: true, CAFile: ca.file, CertFile: otherCert, KeyFile: otherKey},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientAuth:   tt.clientAuth,
+				ClientCAs:    clientCAs,
+				MinVersion:   tls.VersionTLS12,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reporter sarama.TestReporter = t
+			if tt.wantErr {
+				reporter = &rejectReporter{T: t}
+			}
+			broker := newMockBroker(t, reporter, listener, nil)
+
+			err = connect(Config{Version: "2.1.0", TLS: tt.tls}, []string{broker.Addr()})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("connect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSASLPlainWithMockBroker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := newMockBroker(t, t, listener, map[string]sarama.MockResponse{
+		"SaslHandshakeRequest":    sarama.NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{sarama.SASLTypePlaintext}),
+		"SaslAuthenticateRequest": sarama.NewMockSaslAuthenticateResponse(t),
+	})
+
+	cfg := Config{
+		Version: "2.1.0",
+		SASL:    SASLConfig{Mechanism: MechanismPlain, Username: "order-service", Password: "secret"},
+	}
+	if err := connect(cfg, []string{broker.Addr()}); err != nil {
+		t.Fatalf("connect() error = %v", err)
+	}
+
+	// PLAIN передает authzid, имя и пароль, разделенные нулевым байтом
+	var authenticated bool
+	for _, exchange := range broker.History() {
+		if request, ok := exchange.Request.(*sarama.SaslAuthenticateRequest); ok {
+			authenticated = true
+			if want := []byte("\x00order-service\x00secret"); !bytes.Equal(request.SaslAuthBytes, want) {
+				t.Errorf("SASL/PLAIN payload = %q, want %q", request.SaslAuthBytes, want)
+			}
+		}
+	}
+	if !authenticated {
+		t.Error("client did not authenticate")
+	}
+}
+
+// TestBrokerIntegration проверяет подключение к настоящим брокерам в режимах TLS,
+// SASL/PLAIN и SCRAM. Адреса брокеров задаются переменными TEST_KAFKA_*_BROKERS,
+// режимы без адреса пропускаются.
+func TestBrokerIntegration(t *testing.T) {
+	username := os.Getenv("TEST_KAFKA_USERNAME")
+	password := os.Getenv("TEST_KAFKA_PASSWORD")
+	// Переменные TLS применяются ко всем режимам: SASL обычно включают поверх TLS
+	tlsConfig := TLSConfig{
+		Enabled:  os.Getenv("TEST_KAFKA_TLS_CA_FILE") != "",
+		CAFile:   os.Getenv("TEST_KAFKA_TLS_CA_FILE"),
+		CertFile: os.Getenv("TEST_KAFKA_TLS_CERT_FILE"),
+		KeyFile:  os.Getenv("TEST_KAFKA_TLS_KEY_FILE"),
+	}
+
+	modes := []struct {
+		name      string
+		env       string
+		mechanism string
+	}{
+		{"tls", "TEST_KAFKA_TLS_BROKERS", ""},
+		{"sasl plain", "TEST_KAFKA_SASL_PLAIN_BROKERS", MechanismPlain},
+		{"scram sha 256", "TEST_KAFKA_SCRAM_SHA256_BROKERS", MechanismSCRAMSHA256},
+		{"scram sha 512", "TEST_KAFKA_SCRAM_SHA512_BROKERS", MechanismSCRAMSHA512},
+	}
+
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			brokers := SplitList(os.Getenv(mode.env))
+			if len(brokers) == 0 {
+				t.Skipf("%s is not set", mode.env)
+			}
+
+			cfg := Config{
+				ClientID: "kafkaconfig-test",
+				Version:  os.Getenv("TEST_KAFKA_VERSION"),
+				TLS:      tlsConfig,
+				SASL:     SASLConfig{Mechanism: mode.mechanism, Username: username, Password: password},
+			}
+			roundTrip(t, cfg, brokers)
+
+			if mode.mechanism != "" {
+				cfg.SASL.Password = password + "-wrong"
+				if err := connect(cfg, brokers); err == nil {
+					t.Error("broker accepted a wrong password")
+				}
+			}
+		})
+	}
+}
+
+// roundTrip отправляет сообщение в топик TEST_KAFKA_TOPIC и читает его обратно
+func roundTrip(t *testing.T, cfg Config, brokers []string) {
+	t.Helper()
+
+	topic := os.Getenv("TEST_KAFKA_TOPIC")
+	if topic == "" {
+		topic = "kafkaconfig-test"
+	}
+
+	config, err := NewSaramaConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Producer.Return.Successes = true
+	config.Net.DialTimeout = 10 * time.Second
+
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	value := fmt.Sprintf("kafkaconfig-%d", time.Now().UnixNano())
+	partition, offset, err := producer.SendMessage(&sarama.ProducerMessage{Topic: topic, Value: sarama.StringEncoder(value)})
+	if err != nil {
+		t.Fatalf("failed to produce: %v", err)
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	partitionConsumer, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer partitionConsumer.Close()
+
+	select {
+	case message := <-partitionConsumer.Messages():
+		if string(message.Value) != value {
+			t.Errorf("consumed %q, want %q", message.Value, value)
+		}
+	case err := <-partitionConsumer.Errors():
+		t.Fatalf("failed to consume: %v", err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("message was not consumed")
+	}
+}
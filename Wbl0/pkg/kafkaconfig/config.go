@@ -0,0 +1,147 @@
+package kafkaconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// Механизмы SASL
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	MechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// Config содержит общие для потребителя и продюсеров параметры подключения к Kafka
+type Config struct {
+	// ClientID передается брокеру и виден в его логах и квотах
+	ClientID string
+	// Version - версия протокола Kafka, например 3.6.0. Пустая - версия sarama по умолчанию.
+	Version string
+	TLS     TLSConfig
+	SASL    SASLConfig
+}
+
+// TLSConfig задает шифрование соединения с брокерами
+type TLSConfig struct {
+	Enabled bool
+	// CAFile - PEM-бандл доверенных центров сертификации. Пустой - системные.
+	CAFile string
+	// CertFile и KeyFile - клиентский сертификат для взаимной аутентификации
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify отключает проверку сертификата брокера. Только для разработки.
+	InsecureSkipVerify bool
+}
+
+// SASLConfig задает аутентификацию SASL. Пустой Mechanism отключает SASL.
+type SASLConfig struct {
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// NewSaramaConfig создает конфигурацию sarama с параметрами безопасности и версии
+func NewSaramaConfig(cfg Config) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	if err := cfg.Apply(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Apply переносит параметры в конфигурацию sarama
+func (c Config) Apply(config *sarama.Config) error {
+	if c.ClientID != "" {
+		config.ClientID = c.ClientID
+	}
+
+	if c.Version != "" {
+		version, err := sarama.ParseKafkaVersion(c.Version)
+		if err != nil {
+			return fmt.Errorf("invalid kafka version %q: %w", c.Version, err)
+		}
+		config.Version = version
+	}
+
+	if c.TLS.Enabled {
+		tlsConfig, err := c.TLS.build()
+		if err != nil {
+			return err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if c.SASL.Mechanism != "" {
+		if err := c.SASL.apply(config); err != nil {
+			return err
+		}
+	}
+
+	return config.Validate()
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, fmt.Errorf("both client certificate and key files are required")
+		}
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func (s SASLConfig) apply(config *sarama.Config) error {
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = true
+	config.Net.SASL.User = s.Username
+	config.Net.SASL.Password = s.Password
+
+	switch strings.ToUpper(s.Mechanism) {
+	case MechanismPlain:
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case MechanismSCRAMSHA256:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: sha256Generator}
+		}
+	case MechanismSCRAMSHA512:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: sha512Generator}
+		}
+	default:
+		return fmt.Errorf("unknown SASL mechanism %q, expected %s, %s or %s",
+			s.Mechanism, MechanismPlain, MechanismSCRAMSHA256, MechanismSCRAMSHA512)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package kafkaconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// writeCertificate создает самоподписанный сертификат и ключ в PEM-файлах
+func writeCertificate(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "order-service"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "client.crt")
+	keyFile = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Config
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{},
+			want: Config{ClientID: "order-service"},
+		},
+		{
+			name: "tls and sasl",
+			env: map[string]string{
+				"KAFKA_CLIENT_ID":                "producer",
+				"KAFKA_VERSION":                  "3.6.0",
+				"KAFKA_TLS_ENABLED":              "true",
+				"KAFKA_TLS_CA_FILE":              "/certs/ca.pem",
+				"KAFKA_TLS_CERT_FILE":            "/certs/client.pem",
+				"KAFKA_TLS_KEY_FILE":             "/certs/client.key",
+				"KAFKA_TLS_INSECURE_SKIP_VERIFY": "1",
+				"KAFKA_SASL_MECHANISM":           MechanismSCRAMSHA512,
+				"KAFKA_SASL_USERNAME":            "user",
+				"KAFKA_SASL_PASSWORD":            "secret",
+			},
+			want: Config{
+				ClientID: "producer",
+				Version:  "3.6.0",
+				TLS: TLSConfig{
+					Enabled:            true,
+					CAFile:             "/certs/ca.pem",
+					CertFile:           "/certs/client.pem",
+					KeyFile:            "/certs/client.key",
+					InsecureSkipVerify: true,
+				},
+				SASL: SASLConfig{Mechanism: MechanismSCRAMSHA512, Username: "user", Password: "secret"},
+			},
+		},
+		{
+			// Нераспознанное значение булевой переменной выключает параметр
+			name: "invalid bool",
+			env:  map[string]string{"KAFKA_TLS_ENABLED": "yes"},
+			want: Config{ClientID: "order-service"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{
+				"KAFKA_CLIENT_ID", "KAFKA_VERSION", "KAFKA_TLS_ENABLED", "KAFKA_TLS_CA_FILE",
+				"KAFKA_TLS_CERT_FILE", "KAFKA_TLS_KEY_FILE", "KAFKA_TLS_INSECURE_SKIP_VERIFY",
+				"KAFKA_SASL_MECHANISM", "KAFKA_SASL_USERNAME", "KAFKA_SASL_PASSWORD",
+			} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			if got := FromEnv(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSaramaConfig(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name      string
+		config    Config
+		wantErr   string
+		mechanism sarama.SASLMechanism
+		check     func(t *testing.T, config *sarama.Config)
+	}{
+		{
+			name:   "plaintext",
+			config: Config{ClientID: "order-service", Version: "3.6.0"},
+			check: func(t *testing.T, config *sarama.Config) {
+				if config.ClientID != "order-service" || config.Version != sarama.V3_6_0_0 {
+					t.Errorf("client id = %s, version = %s", config.ClientID, config.Version)
+				}
+				if config.Net.TLS.Enable || config.Net.SASL.Enable {
+					t.Error("tls or sasl enabled without configuration")
+				}
+			},
+		},
+		{
+			name:    "invalid version",
+			config:  Config{Version: "banana"},
+			wantErr: "invalid kafka version",
+		},
+		{
+			name: "tls with ca and client certificate",
+			config: Config{TLS: TLSConfig{
+				Enabled:  true,
+				CAFile:   certFile,
+				CertFile: certFile,
+				KeyFile:  keyFile,
+			}},
+			check: func(t *testing.T, config *sarama.Config) {
+				tlsConfig := config.Net.TLS.Config
+				if !config.Net.TLS.Enable || tlsConfig == nil {
+					t.Fatal("tls is not enabled")
+				}
+				if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 {
+					t.Errorf("root CAs = %v, certificates = %d", tlsConfig.RootCAs, len(tlsConfig.Certificates))
+				}
+				if tlsConfig.InsecureSkipVerify {
+					t.Error("broker certificate verification is disabled")
+				}
+			},
+		},
+		{
+			name:    "tls missing ca file",
+			config:  Config{TLS: TLSConfig{Enabled: true, CAFile: missing}},
+			wantErr: "failed to read CA file",
+		},
+		{
+			name:    "tls ca file without certificates",
+			config:  Config{TLS: TLSConfig{Enabled: true, CAFile: keyFile}},
+			wantErr: "no certificates found in CA file",
+		},
+		{
+			name:    "tls certificate without key",
+			config:  Config{TLS: TLSConfig{Enabled: true, CertFile: certFile}},
+			wantErr: "both client certificate and key files are required",
+		},
+		{
+			name:    "tls missing certificate file",
+			config:  Config{TLS: TLSConfig{Enabled: true, CertFile: missing, KeyFile: keyFile}},
+			wantErr: "failed to load client certificate",
+		},
+		{
+			name:    "tls missing key file",
+			config:  Config{TLS: TLSConfig{Enabled: true, CertFile: certFile, KeyFile: missing}},
+			wantErr: "failed to load client certificate",
+		},
+		{
+			name:      "sasl plain",
+			config:    Config{SASL: SASLConfig{Mechanism: "plain", Username: "user", Password: "secret"}},
+			mechanism: sarama.SASLTypePlaintext,
+		},
+		{
+			name:      "sasl scram-sha-256",
+			config:    Config{SASL: SASLConfig{Mechanism: MechanismSCRAMSHA256, Username: "user", Password: "secret"}},
+			mechanism: sarama.SASLTypeSCRAMSHA256,
+		},
+		{
+			name:      "sasl scram-sha-512 over tls",
+			config:    Config{TLS: TLSConfig{Enabled: true}, SASL: SASLConfig{Mechanism: MechanismSCRAMSHA512, Username: "user", Password: "secret"}},
+			mechanism: sarama.SASLTypeSCRAMSHA512,
+		},
+		{
+			name:    "sasl unknown mechanism",
+			config:  Config{SASL: SASLConfig{Mechanism: "GSSAPI", Username: "user", Password: "secret"}},
+			wantErr: "unknown SASL mechanism",
+		},
+		{
+			// Проверку учетных данных выполняет config.Validate()
+			name:    "sasl without username",
+			config:  Config{SASL: SASLConfig{Mechanism: MechanismPlain}},
+			wantErr: "SASL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewSaramaConfig(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewSaramaConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSaramaConfig() error = %v", err)
+			}
+
+			if tt.mechanism != "" {
+				sasl := config.Net.SASL
+				if !sasl.Enable || !sasl.Handshake || sasl.Mechanism != tt.mechanism {
+					t.Errorf("sasl = %v, handshake = %v, mechanism = %s", sasl.Enable, sasl.Handshake, sasl.Mechanism)
+				}
+				if sasl.User != "user" || sasl.Password != "secret" {
+					t.Errorf("sasl credentials = %s/%s", sasl.User, sasl.Password)
+				}
+				if config.Net.TLS.Enable != tt.config.TLS.Enabled {
+					t.Errorf("tls enabled = %v, want %v", config.Net.TLS.Enable, tt.config.TLS.Enabled)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, config)
+			}
+		})
+	}
+}
+
+func TestSCRAMClient(t *testing.T) {
+	for _, mechanism := range []string{MechanismSCRAMSHA256, MechanismSCRAMSHA512} {
+		config, err := NewSaramaConfig(Config{SASL: SASLConfig{Mechanism: mechanism, Username: "user", Password: "secret"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Клиент начинает обмен сообщением client-first с именем пользователя
+		client := config.Net.SASL.SCRAMClientGeneratorFunc()
+		if err := client.Begin("user", "secret", ""); err != nil {
+			t.Fatalf("%s: Begin() error = %v", mechanism, err)
+		}
+		first, err := client.Step("")
+		if err != nil {
+			t.Fatalf("%s: Step() error = %v", mechanism, err)
+		}
+		if !strings.HasPrefix(first, "n,,n=user,r=") {
+			t.Errorf("%s: client-first message = %q", mechanism, first)
+		}
+		if client.Done() {
+			t.Errorf("%s: conversation finished after the first message", mechanism)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"kafka:9092", []string{"kafka:9092"}},
+		{"kafka-1:9092, kafka-2:9092,", []string{"kafka-1:9092", "kafka-2:9092"}},
+		{" , ,", nil},
+	}
+
+	for _, tt := range tests {
+		if got := SplitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
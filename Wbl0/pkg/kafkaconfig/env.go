@@ -0,0 +1,53 @@
+package kafkaconfig
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FromEnv читает параметры подключения из переменных окружения KAFKA_CLIENT_ID,
+// KAFKA_VERSION, KAFKA_TLS_*, KAFKA_SASL_*. Используется сервисом и утилитами,
+// чтобы все клиенты подключались к кластеру одинаково.
+func FromEnv() Config {
+	return Config{
+		ClientID: getEnv("KAFKA_CLIENT_ID", "order-service"),
+		Version:  os.Getenv("KAFKA_VERSION"),
+		TLS: TLSConfig{
+			Enabled:            getEnvBool("KAFKA_TLS_ENABLED"),
+			CAFile:             os.Getenv("KAFKA_TLS_CA_FILE"),
+			CertFile:           os.Getenv("KAFKA_TLS_CERT_FILE"),
+			KeyFile:            os.Getenv("KAFKA_TLS_KEY_FILE"),
+			InsecureSkipVerify: getEnvBool("KAFKA_TLS_INSECURE_SKIP_VERIFY"),
+		},
+		SASL: SASLConfig{
+			Mechanism: os.Getenv("KAFKA_SASL_MECHANISM"),
+			Username:  os.Getenv("KAFKA_SASL_USERNAME"),
+			Password:  os.Getenv("KAFKA_SASL_PASSWORD"),
+		},
+	}
+}
+
+// SplitList разбирает список через запятую (брокеры, топики), пропуская пробелы
+// и пустые элементы: "kafka-1:9092, kafka-2:9092," -> [kafka-1:9092 kafka-2:9092]
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string) bool {
+	parsed, _ := strconv.ParseBool(os.Getenv(key))
+	return parsed
+}
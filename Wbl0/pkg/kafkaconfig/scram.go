@@ -0,0 +1,33 @@
+package kafkaconfig
+
+import (
+	"github.com/xdg-go/scram"
+)
+
+var (
+	sha256Generator = scram.SHA256
+	sha512Generator = scram.SHA512
+)
+
+// scramClient реализует sarama.SCRAMClient поверх xdg-go/scram
+type scramClient struct {
+	hash         scram.HashGeneratorFcn
+	conversation *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hash.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
@@ -0,0 +1,164 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Типы схем, поддерживаемые реестром
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+	SchemaTypeJSON     = "JSON"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// magicByte открывает каждое сообщение в wire-формате Confluent
+const magicByte = 0
+
+// ErrNotWireFormat возвращается для сообщения без заголовка wire-формата Confluent
+var ErrNotWireFormat = errors.New("message is not in confluent wire format")
+
+// Client - клиент реестра схем, совместимого с Confluent Schema Registry.
+// Схемы кэшируются по идентификатору: в реестре они неизменяемы.
+type Client struct {
+	baseURL string
+	http    *http.Client
+
+	mu      sync.RWMutex
+	schemas map[int]string
+}
+
+// NewClient создает клиент реестра схем по базовому URL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+		schemas: make(map[int]string),
+	}
+}
+
+type schemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type registerRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type registerResponse struct {
+	ID int `json:"id"`
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// SchemaByID возвращает текст схемы по ее идентификатору
+func (c *Client) SchemaByID(id int) (string, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var response schemaResponse
+	if err := c.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &response); err != nil {
+		return "", fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+
+	c.mu.Lock()
+	c.schemas[id] = response.Schema
+	c.mu.Unlock()
+
+	return response.Schema, nil
+}
+
+// Register регистрирует схему в субъекте и возвращает ее идентификатор.
+// Повторная регистрация той же схемы возвращает существующий идентификатор.
+func (c *Client) Register(subject, schema, schemaType string) (int, error) {
+	request := registerRequest{Schema: schema}
+	if schemaType != SchemaTypeAvro {
+		// Для AVRO поле опускается - так делает и клиент Confluent
+		request.SchemaType = schemaType
+	}
+
+	var response registerResponse
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := c.do(http.MethodPost, path, request, &response); err != nil {
+		return 0, fmt.Errorf("failed to register schema in subject %s: %w", subject, err)
+	}
+
+	c.mu.Lock()
+	c.schemas[response.ID] = schema
+	c.mu.Unlock()
+
+	return response.ID, nil
+}
+
+func (c *Client) do(method, path string, request, response interface{}) error {
+	var body bytes.Buffer
+	if request != nil {
+		if err := json.NewEncoder(&body).Encode(request); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResponse errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResponse); err == nil && errResponse.Message != "" {
+			return fmt.Errorf("registry error %d: %s", errResponse.ErrorCode, errResponse.Message)
+		}
+		return fmt.Errorf("registry returned status %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// EncodeWireFormat добавляет к сообщению заголовок wire-формата Confluent:
+// нулевой байт и идентификатор схемы (4 байта, big-endian)
+func EncodeWireFormat(schemaID int, payload []byte) []byte {
+	data := make([]byte, 5, 5+len(payload))
+	data[0] = magicByte
+	binary.BigEndian.PutUint32(data[1:5], uint32(schemaID))
+	return append(data, payload...)
+}
+
+// DecodeWireFormat отделяет идентификатор схемы от тела сообщения в wire-формате Confluent
+func DecodeWireFormat(data []byte) (int, []byte, error) {
+	if !IsWireFormat(data) {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// IsWireFormat сообщает, начинается ли сообщение с заголовка wire-формата Confluent
+func IsWireFormat(data []byte) bool {
+	return len(data) >= 5 && data[0] == magicByte
+}
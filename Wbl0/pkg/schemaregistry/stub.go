@@ -0,0 +1,120 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stub - реестр схем в памяти, реализующий подмножество REST API Confluent
+// Schema Registry: регистрацию схем, получение по идентификатору и последней
+// версии субъекта. Используется в тестах и локальном запуске без реестра.
+type Stub struct {
+	mu       sync.Mutex
+	schemas  []registerRequest
+	subjects map[string][]int
+}
+
+// NewStub создает пустой реестр схем в памяти
+func NewStub() *Stub {
+	return &Stub{
+		subjects: make(map[string][]int),
+	}
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "schemas" && parts[1] == "ids":
+		s.getByID(w, parts[2])
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "subjects" && parts[2] == "versions":
+		s.register(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 4 && parts[0] == "subjects" && parts[2] == "versions" && parts[3] == "latest":
+		s.latest(w, parts[1])
+	default:
+		writeStubError(w, http.StatusNotFound, 404, "HTTP 404 Not Found")
+	}
+}
+
+func (s *Stub) getByID(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil || id < 1 || id > len(s.schemas) {
+		writeStubError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+
+	writeStubJSON(w, schemaResponse(s.schemas[id-1]))
+}
+
+func (s *Stub) register(w http.ResponseWriter, r *http.Request, subject string) {
+	var request registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Schema == "" {
+		writeStubError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, schema := range s.schemas {
+		if schema == request {
+			s.addToSubject(subject, i+1)
+			writeStubJSON(w, registerResponse{ID: i + 1})
+			return
+		}
+	}
+
+	s.schemas = append(s.schemas, request)
+	id := len(s.schemas)
+	s.addToSubject(subject, id)
+	writeStubJSON(w, registerResponse{ID: id})
+}
+
+func (s *Stub) latest(w http.ResponseWriter, subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.subjects[subject]
+	if len(versions) == 0 {
+		writeStubError(w, http.StatusNotFound, 40401, "Subject not found")
+		return
+	}
+
+	id := versions[len(versions)-1]
+	schema := s.schemas[id-1]
+	writeStubJSON(w, map[string]interface{}{
+		"subject":    subject,
+		"version":    len(versions),
+		"id":         id,
+		"schema":     schema.Schema,
+		"schemaType": schema.SchemaType,
+	})
+}
+
+func (s *Stub) addToSubject(subject string, id int) {
+	for _, existing := range s.subjects[subject] {
+		if existing == id {
+			return
+		}
+	}
+	s.subjects[subject] = append(s.subjects[subject], id)
+}
+
+func writeStubJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	json.NewEncoder(w).Encode(response)
+}
+
+func writeStubError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{ErrorCode: code, Message: message})
+}
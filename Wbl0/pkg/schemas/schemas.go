@@ -0,0 +1,167 @@
+package schemas
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// VersionHeader - заголовок сообщения с версией схемы заказа
+const VersionHeader = "schema-version"
+
+// ErrUnknownVersion возвращается для версии схемы, которой нет среди опубликованных
+var ErrUnknownVersion = errors.New("unknown schema version")
+
+//go:embed order/*.json
+var orderSchemas embed.FS
+
+// OrderValidator проверяет сообщения о заказах по опубликованным версиям JSON Schema
+type OrderValidator struct {
+	strict   bool
+	latest   string
+	raw      map[string][]byte
+	compiled map[string]*jsonschema.Schema
+}
+
+// NewOrderValidator компилирует все версии схемы заказа.
+// В строгом режиме сообщение с расхождениями считается невалидным,
+// иначе расхождения только возвращаются для журналирования.
+func NewOrderValidator(strict bool) (*OrderValidator, error) {
+	entries, err := orderSchemas.ReadDir("order")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order schemas: %w", err)
+	}
+
+	v := &OrderValidator{
+		strict:   strict,
+		raw:      make(map[string][]byte),
+		compiled: make(map[string]*jsonschema.Schema),
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+
+	var versions []int
+	for _, entry := range entries {
+		name := entry.Name()
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "v"), ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema file name %s", name)
+		}
+
+		data, err := orderSchemas.ReadFile(path.Join("order", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", name, err)
+		}
+
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", name, err)
+		}
+		location := "order/" + name
+		if err := compiler.AddResource(location, doc); err != nil {
+			return nil, fmt.Errorf("failed to add schema %s: %w", name, err)
+		}
+		schema, err := compiler.Compile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema %s: %w", name, err)
+		}
+
+		version := strconv.Itoa(number)
+		v.raw[version] = data
+		v.compiled[version] = schema
+		versions = append(versions, number)
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no order schemas found")
+	}
+	sort.Ints(versions)
+	v.latest = strconv.Itoa(versions[len(versions)-1])
+
+	return v, nil
+}
+
+// Validate проверяет сообщение по указанной версии схемы (пустая версия - последняя).
+// Возвращает найденные расхождения; ошибка возвращается для неизвестной версии,
+// некорректного JSON или, в строгом режиме, при любых расхождениях.
+func (v *OrderValidator) Validate(version string, payload []byte) ([]string, error) {
+	version, err := v.resolve(version)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	err = v.compiled[version].Validate(instance)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	problems := collectProblems(*validationErr.DetailedOutput(), nil)
+
+	if v.strict {
+		return problems, fmt.Errorf("message does not match order schema v%s: %s", version, strings.Join(problems, "; "))
+	}
+	return problems, nil
+}
+
+// Schema возвращает исходный текст схемы указанной версии (пустая версия - последняя)
+func (v *OrderValidator) Schema(version string) ([]byte, error) {
+	version, err := v.resolve(version)
+	if err != nil {
+		return nil, err
+	}
+	return v.raw[version], nil
+}
+
+// LatestVersion возвращает номер последней опубликованной версии схемы
+func (v *OrderValidator) LatestVersion() string {
+	return v.latest
+}
+
+// collectProblems собирает расхождения из листьев дерева ошибок. Плоский BasicOutput
+// для ошибок за $ref (delivery, payment, items) отдает только сам переход по ссылке
+// без причины, поэтому обходится подробный вывод.
+func collectProblems(unit jsonschema.OutputUnit, problems []string) []string {
+	if len(unit.Errors) == 0 {
+		if unit.Error == nil {
+			return problems
+		}
+		location := unit.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return append(problems, location+": "+unit.Error.String())
+	}
+	for _, cause := range unit.Errors {
+		problems = collectProblems(cause, problems)
+	}
+	return problems
+}
+
+func (v *OrderValidator) resolve(version string) (string, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" {
+		return v.latest, nil
+	}
+	if _, ok := v.compiled[version]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+	}
+	return version, nil
+}
@@ -0,0 +1,210 @@
+package schemas
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validOrder возвращает сообщение о заказе, соответствующее схеме v1
+func validOrder() map[string]interface{} {
+	return map[string]interface{}{
+		"order_uid":    "b563feb7b2b84b6test",
+		"track_number": "WBILMTESTTRACK",
+		"entry":        "WBIL",
+		"delivery": map[string]interface{}{
+			"name":    "Test Testov",
+			"phone":   "+9720000000",
+			"zip":     "2639809",
+			"city":    "Kiryat Mozkin",
+			"address": "Ploshad Mira 15",
+			"region":  "Kraiot",
+			"email":   "test@gmail.com",
+		},
+		"payment": map[string]interface{}{
+			"transaction":   "b563feb7b2b84b6test",
+			"request_id":    "",
+			"currency":      "USD",
+			"provider":      "wbpay",
+			"amount":        1817,
+			"payment_dt":    1637907727,
+			"bank":          "alpha",
+			"delivery_cost": 1500,
+			"goods_total":   317,
+			"custom_fee":    0,
+		},
+		"items": []interface{}{map[string]interface{}{
+			"chrt_id":      9934930,
+			"track_number": "WBILMTESTTRACK",
+			"price":        453,
+			"rid":          "ab4219087a764ae0btest",
+			"name":         "Mascaras",
+			"sale":         30,
+			"size":         "0",
+			"total_price":  317,
+			"nm_id":        2389212,
+			"brand":        "Vivienne Sabo",
+			"status":       202,
+		}},
+		"locale":             "en",
+		"internal_signature": "",
+		"customer_id":        "test",
+		"delivery_service":   "meest",
+		"shardkey":           "9",
+		"sm_id":              99,
+		"date_created":       "2021-11-26T06:22:19Z",
+		"oof_shard":          "1",
+	}
+}
+
+func marshal(t *testing.T, order map[string]interface{}) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func newValidator(t *testing.T, strict bool) *OrderValidator {
+	t.Helper()
+
+	validator, err := NewOrderValidator(strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
+
+func TestValidateVersionSelection(t *testing.T) {
+	validator := newValidator(t, true)
+	if validator.LatestVersion() != "1" {
+		t.Fatalf("LatestVersion() = %q, want 1", validator.LatestVersion())
+	}
+	payload := marshal(t, validOrder())
+
+	tests := []struct {
+		version string
+		wantErr error
+	}{
+		{version: ""},
+		{version: "1"},
+		{version: "v1"},
+		{version: " 1 "},
+		{version: "2", wantErr: ErrUnknownVersion},
+		{version: "latest", wantErr: ErrUnknownVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			problems, err := validator.Validate(tt.version, payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate(%q) error = %v, want %v", tt.version, err, tt.wantErr)
+			}
+			if len(problems) != 0 {
+				t.Errorf("Validate(%q) problems = %v", tt.version, problems)
+			}
+		})
+	}
+}
+
+func TestValidateMismatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(order map[string]interface{})
+		// wantProblem - фрагмент, по которому находится расхождение
+		wantProblem string
+	}{
+		{
+			name:        "unknown field",
+			change:      func(order map[string]interface{}) { order["discount"] = 10 },
+			wantProblem: "discount",
+		},
+		{
+			name: "unknown nested field",
+			change: func(order map[string]interface{}) {
+				order["delivery"].(map[string]interface{})["floor"] = "3"
+			},
+			wantProblem: "/delivery",
+		},
+		{
+			name:        "missing field",
+			change:      func(order map[string]interface{}) { delete(order, "track_number") },
+			wantProblem: "track_number",
+		},
+		{
+			name: "missing item field",
+			change: func(order map[string]interface{}) {
+				delete(order["items"].([]interface{})[0].(map[string]interface{}), "rid")
+			},
+			wantProblem: "/items/0",
+		},
+		{
+			name:        "wrong type",
+			change:      func(order map[string]interface{}) { order["sm_id"] = "99" },
+			wantProblem: "/sm_id",
+		},
+		{
+			name:        "invalid date",
+			change:      func(order map[string]interface{}) { order["date_created"] = "yesterday" },
+			wantProblem: "/date_created",
+		},
+	}
+
+	for _, tt := range tests {
+		order := validOrder()
+		tt.change(order)
+		payload := marshal(t, order)
+
+		t.Run(tt.name+" lenient", func(t *testing.T) {
+			// Без строгого режима расхождения только возвращаются для журнала
+			problems, err := newValidator(t, false).Validate("", payload)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !strings.Contains(strings.Join(problems, "; "), tt.wantProblem) {
+				t.Errorf("problems = %v, want %q", problems, tt.wantProblem)
+			}
+		})
+
+		t.Run(tt.name+" strict", func(t *testing.T) {
+			problems, err := newValidator(t, true).Validate("1", payload)
+			if err == nil {
+				t.Fatal("message with mismatches was accepted")
+			}
+			if !strings.Contains(err.Error(), tt.wantProblem) || len(problems) == 0 {
+				t.Errorf("Validate() = %v, %v, want %q", problems, err, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		if _, err := newValidator(t, strict).Validate("", []byte(`{"order_uid": `)); err == nil {
+			t.Errorf("invalid JSON accepted with strict=%v", strict)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	validator := newValidator(t, false)
+
+	latest, err := validator.Schema("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := validator.Schema("v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(latest) != string(v1) || !json.Valid(v1) {
+		t.Error("Schema() did not return the published v1 schema")
+	}
+
+	if _, err := validator.Schema("2"); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Schema(2) error = %v, want ErrUnknownVersion", err)
+	}
+}